@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/controls"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/ledger"
+
+	"gorm.io/gorm"
+)
+
+type balanceOutput struct {
+	Address string `json:"address"`
+	Balance string `json:"balance"`
+	Frozen  bool   `json:"frozen"`
+}
+
+type transferOutput struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Amount  string `json:"amount"`
+	Fee     string `json:"fee"`
+	Net     string `json:"net"`
+	Balance string `json:"balance"`
+}
+
+type mintOutput struct {
+	To      string `json:"to"`
+	Amount  string `json:"amount"`
+	Balance string `json:"balance"`
+}
+
+// balance prints the balance of an address and whether it is frozen.
+func balance(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("balance")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: tokenctl balance [flags] <address>")
+		flags.PrintDefaults()
+	}
+	format := outputFlag(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = checkOutput(*format)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected an address")
+	}
+	addr, err := parseAddress(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var amount decimal.Decimal
+	var frozen bool
+	err = withDb(ctx, func(tx *gorm.DB) error {
+		amount, err = ledger.New(tx).Balance(ctx, addr)
+		if err != nil {
+			return err
+		}
+		freeze, err := controls.GetFrozen(tx, addr)
+		frozen = freeze != nil
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	res := balanceOutput{Address: addr.Hex(), Balance: amount.String(), Frozen: frozen}
+	return write(out, *format, res, table{
+		header: []string{"ADDRESS", "BALANCE", "FROZEN"},
+		rows:   [][]string{{res.Address, res.Balance, fmt.Sprint(res.Frozen)}},
+	})
+}
+
+// transfer moves tokens between two addresses with a ledger configured like the server's.
+func transfer(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("transfer")
+	from := flags.String("from", "", "address debited with the amount")
+	to := flags.String("to", "", "address credited with the amount minus the fee")
+	amount := flags.String("amount", "", "amount to transfer")
+	format := outputFlag(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = checkOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	fromAddr, err := parseAddress(*from)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	toAddr, err := parseAddress(*to)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+	value, err := decimal.NewFromString(*amount)
+	if err != nil {
+		return fmt.Errorf("invalid -amount: %w", err)
+	}
+
+	ctx = audit.WithSystemActor(ctx, operator())
+	var receipt ledger.Receipt
+	err = withLedger(ctx, func(l *ledger.Ledger) error {
+		receipt, err = l.Transfer(ctx, fromAddr, toAddr, value)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	res := transferOutput{
+		From:    fromAddr.Hex(),
+		To:      toAddr.Hex(),
+		Amount:  receipt.Amount.String(),
+		Fee:     receipt.Fee.String(),
+		Net:     receipt.Net.String(),
+		Balance: receipt.Balance.String(),
+	}
+	return write(out, *format, res, table{
+		header: []string{"FROM", "TO", "AMOUNT", "FEE", "NET", "BALANCE"},
+		rows:   [][]string{{res.From, res.To, res.Amount, res.Fee, res.Net, res.Balance}},
+	})
+}
+
+// mint creates new tokens credited to an address.
+func mint(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("mint")
+	to := flags.String("to", "", "address credited with the minted tokens")
+	amount := flags.String("amount", "", "amount to mint")
+	format := outputFlag(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = checkOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	toAddr, err := parseAddress(*to)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+	value, err := decimal.NewFromString(*amount)
+	if err != nil {
+		return fmt.Errorf("invalid -amount: %w", err)
+	}
+
+	ctx = audit.WithSystemActor(ctx, operator())
+	var balance decimal.Decimal
+	err = withLedger(ctx, func(l *ledger.Ledger) error {
+		balance, err = l.Mint(ctx, toAddr, value)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	res := mintOutput{To: toAddr.Hex(), Amount: value.String(), Balance: balance.String()}
+	return write(out, *format, res, table{
+		header: []string{"TO", "AMOUNT", "BALANCE"},
+		rows:   [][]string{{res.To, res.Amount, res.Balance}},
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/controls"
+	"token-transfer-api/internal/db"
+
+	"gorm.io/gorm"
+)
+
+type freezeOutput struct {
+	Address  string     `json:"address"`
+	Frozen   bool       `json:"frozen"`
+	Reason   string     `json:"reason,omitempty"`
+	FrozenBy string     `json:"frozen_by,omitempty"`
+	FrozenAt *time.Time `json:"frozen_at,omitempty"`
+}
+
+// freeze stops an address from sending and receiving tokens.
+func freeze(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("freeze")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: tokenctl freeze -reason <reason> [flags] <address>")
+		flags.PrintDefaults()
+	}
+	reason := flags.String("reason", "", "why the account is frozen, recorded in the audit log")
+	format := outputFlag(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = checkOutput(*format)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected an address")
+	}
+	addr, err := parseAddress(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx = audit.WithSystemActor(ctx, operator())
+	var frozen *db.FrozenAccount
+	err = withDb(ctx, func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			frozen, err = controls.Freeze(ctx, tx, addr, *reason, operator())
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	res := freezeOutput{
+		Address:  addr.Hex(),
+		Frozen:   true,
+		Reason:   frozen.Reason,
+		FrozenBy: frozen.FrozenBy,
+		FrozenAt: &frozen.FrozenAt,
+	}
+	return write(out, *format, res, table{
+		header: []string{"ADDRESS", "REASON", "FROZEN BY", "FROZEN AT"},
+		rows:   [][]string{{res.Address, res.Reason, res.FrozenBy, res.FrozenAt.Format(time.RFC3339)}},
+	})
+}
+
+// unfreeze allows a frozen address to transfer tokens again.
+func unfreeze(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("unfreeze")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: tokenctl unfreeze [flags] <address>")
+		flags.PrintDefaults()
+	}
+	format := outputFlag(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = checkOutput(*format)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected an address")
+	}
+	addr, err := parseAddress(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	ctx = audit.WithSystemActor(ctx, operator())
+	err = withDb(ctx, func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			return controls.Unfreeze(ctx, tx, addr, operator())
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	res := freezeOutput{Address: addr.Hex(), Frozen: false}
+	return write(out, *format, res, table{
+		header: []string{"ADDRESS", "FROZEN"},
+		rows:   [][]string{{res.Address, "false"}},
+	})
+}
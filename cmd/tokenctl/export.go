@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"token-transfer-api/internal/export"
+
+	"gorm.io/gorm"
+)
+
+// exportRows writes the accounts or the transfer history as CSV or JSON Lines.
+func exportRows(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("export")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: tokenctl export [flags] accounts|transfers")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", string(export.CSV), "output format, csv or jsonl")
+	addr := flags.String("address", "", "only export the account of, or the transfers from or to, this address")
+	from := flags.String("from", "", "only export the transfers created at or after this RFC 3339 time")
+	to := flags.String("to", "", "only export the transfers created before this RFC 3339 time")
+	output := flags.String("file", "", "file to write to instead of the standard output")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected accounts or transfers")
+	}
+
+	var rows func(tx *gorm.DB, w io.Writer, format export.Format, filter export.Filter) (int, error)
+	switch flags.Arg(0) {
+	case "accounts":
+		rows = export.Accounts
+	case "transfers":
+		rows = export.Transfers
+	default:
+		return fmt.Errorf("unknown export %q, expected accounts or transfers", flags.Arg(0))
+	}
+
+	exportFormat, err := export.ParseFormat(*format)
+	if err != nil {
+		return err
+	}
+	var filter export.Filter
+	if *addr != "" {
+		a, err := parseAddress(*addr)
+		if err != nil {
+			return err
+		}
+		filter.Address = &a
+	}
+	filter.From, err = parseTimeFlag("from", *from)
+	if err != nil {
+		return err
+	}
+	filter.To, err = parseTimeFlag("to", *to)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return withDb(ctx, func(tx *gorm.DB) error {
+			_, err := rows(tx, out, exportFormat, filter)
+			return err
+		})
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	var n int
+	err = withDb(ctx, func(tx *gorm.DB) error {
+		n, err = rows(tx, file, exportFormat, filter)
+		return err
+	})
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	fmt.Fprintf(out, "exported %d rows to %s\n", n, *output)
+	return nil
+}
+
+func parseTimeFlag(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -%s: %w", name, err)
+	}
+	return &t, nil
+}
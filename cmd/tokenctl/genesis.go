@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"token-transfer-api/internal/genesis"
+)
+
+type genesisOutput struct {
+	Name        string `json:"name"`
+	Symbol      string `json:"symbol"`
+	Decimals    uint8  `json:"decimals"`
+	Allocations int    `json:"allocations"`
+	Total       string `json:"total"`
+	Hash        string `json:"hash"`
+}
+
+// checkGenesis validates a genesis file and prints its hash, without connecting to the database.
+func checkGenesis(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("genesis")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: tokenctl genesis <file>")
+		flags.PrintDefaults()
+	}
+	format := outputFlag(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = checkOutput(*format)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected a genesis file")
+	}
+
+	g, err := genesis.Load(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	res := genesisOutput{
+		Name:        g.Token.Name,
+		Symbol:      g.Token.Symbol,
+		Decimals:    g.Token.Decimals,
+		Allocations: len(g.Balances),
+		Total:       g.Total.String(),
+		Hash:        g.Hash.Hex(),
+	}
+	return write(out, *format, res, table{
+		header: []string{"NAME", "SYMBOL", "DECIMALS", "ALLOCATIONS", "TOTAL", "HASH"},
+		rows: [][]string{{
+			res.Name,
+			res.Symbol,
+			strconv.Itoa(int(res.Decimals)),
+			strconv.Itoa(res.Allocations),
+			res.Total,
+			res.Hash,
+		}},
+	})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/importer"
+
+	"gorm.io/gorm"
+)
+
+// importBalances sets balances from a CSV file of address,amount records.
+func importBalances(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("import")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: tokenctl import [flags] <file.csv|->")
+		flags.PrintDefaults()
+	}
+	dryRun := flags.Bool("dry-run", false, "print the changes against the current balances without applying them")
+	batchSize := flags.Int("batch-size", importer.DefaultBatchSize, "number of rows imported per transaction")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("expected a CSV file")
+	}
+
+	in := os.Stdin
+	if flags.Arg(0) != "-" {
+		in, err = os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+	}
+
+	rows, err := importer.Parse(in)
+	if err != nil {
+		return fmt.Errorf("invalid import file:\n%w", err)
+	}
+
+	ctx = audit.WithSystemActor(ctx, operator())
+	var result *importer.Result
+	err = withDb(ctx, func(tx *gorm.DB) error {
+		result, err = importer.Run(ctx, tx, rows, importer.Options{DryRun: *dryRun, BatchSize: *batchSize})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ADDRESS\tCURRENT\tNEW\tCHANGE")
+		for _, change := range result.Changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", change.Address.Hex(), change.Current, change.New, signed(change.Delta()))
+		}
+		err = w.Flush()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "dry run: %d rows would change %d balances, minting %s and burning %s\n",
+			result.Rows, len(result.Changes), result.Minted, result.Burned)
+		return nil
+	}
+
+	fmt.Fprintf(out, "imported %d rows, changed %d balances, minted %s and burned %s\n",
+		result.Rows, len(result.Changes), result.Minted, result.Burned)
+	return nil
+}
+
+// signed formats d with an explicit sign.
+func signed(d decimal.Decimal) string {
+	if d.GreaterThan(decimal.Zero) {
+		return "+" + d.String()
+	}
+	return d.String()
+}
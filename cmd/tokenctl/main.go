@@ -0,0 +1,158 @@
+// Command tokenctl operates the token ledger from a shell.
+//
+// It connects to the database configured with DATABASE_URL, like the server.
+// Changes are recorded in the audit log as made by "tokenctl:<user>". Most commands
+// print a table, or JSON with -o json.
+//
+// Usage:
+//
+//	tokenctl <command> [flags]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"os/user"
+	"syscall"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/config"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/ledger"
+
+	"gorm.io/gorm"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string, out io.Writer) error
+}
+
+var commands = []command{
+	{name: "migrate", summary: "create or update the schema and seed the genesis", run: migrate},
+	{name: "balance", summary: "print the balance of an address", run: balance},
+	{name: "transfer", summary: "transfer tokens between addresses", run: transfer},
+	{name: "mint", summary: "mint tokens to an address", run: mint},
+	{name: "freeze", summary: "freeze an address", run: freeze},
+	{name: "unfreeze", summary: "unfreeze an address", run: unfreeze},
+	{name: "verify", summary: "verify the hash chain of the ledger", run: verify},
+	{name: "rebuild-balances", summary: "rebuild account balances by replaying the ledger", run: rebuildBalances},
+	{name: "genesis", summary: "validate a genesis file and print its hash", run: checkGenesis},
+	{name: "import", summary: "set balances from a CSV file of addresses and amounts", run: importBalances},
+	{name: "export", summary: "export accounts or transfers as CSV or JSON Lines", run: exportRows},
+	{name: "snapshot", summary: "snapshot all balances or print a Merkle proof", run: snapshotBalances},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: tokenctl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-18s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nrun 'tokenctl <command> -h' for the flags of a command")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == os.Args[1] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	os.Exit(run(cmd, os.Args[2:]))
+}
+
+func run(cmd *command, args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	err := cmd.run(ctx, args, os.Stdout)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(os.Stderr, "tokenctl %s: %v\n", cmd.name, err)
+		return 1
+	}
+	return 0
+}
+
+// newFlagSet returns the flag set of the named command. Parse errors are returned
+// rather than exiting, so commands can clean up.
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("tokenctl "+name, flag.ContinueOnError)
+}
+
+// operator identifies the user of tokenctl in the audit log and as the operator of controls,
+// e.g. "tokenctl:alice".
+func operator() string {
+	u, err := user.Current()
+	if err != nil {
+		return "tokenctl"
+	}
+	return "tokenctl:" + u.Username
+}
+
+// parseAddress parses a hex address argument.
+func parseAddress(s string) (address.Address, error) {
+	var addr address.Address
+	err := addr.UnmarshalGQL(s)
+	if err != nil {
+		return address.Address{}, err
+	}
+	return addr, nil
+}
+
+// withDb connects to the database, runs fn and closes the connection.
+// Commands parse their flags first, so that usage errors do not need a database.
+func withDb(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	return connect(ctx, cfg, fn)
+}
+
+// withLedger connects to the database and runs fn with a ledger configured like the
+// server's, so operator transfers charge the same fees and are retried and bounded
+// by the same timeouts.
+func withLedger(ctx context.Context, fn func(l *ledger.Ledger) error) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	return connect(ctx, cfg, func(tx *gorm.DB) error {
+		return fn(ledger.FromConfig(tx, cfg))
+	})
+}
+
+// connect connects to the database configured by cfg, runs fn and closes the connection.
+func connect(ctx context.Context, cfg config.Config, fn func(tx *gorm.DB) error) error {
+	dbConnection, err := db.ConnectDb(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	err = fn(dbConnection.WithContext(ctx))
+	closeErr := db.CloseDb(dbConnection)
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
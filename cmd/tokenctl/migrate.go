@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"token-transfer-api/internal/config"
+	"token-transfer-api/internal/genesis"
+
+	"gorm.io/gorm"
+)
+
+// migrate creates or updates the database schema and seeds the genesis configured
+// with GENESIS_FILE, like the server does on startup.
+func migrate(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("migrate")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	seed := genesis.Default()
+	if cfg.GenesisFile != "" {
+		seed, err = genesis.Load(cfg.GenesisFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	// connecting applies the migrations
+	err = connect(ctx, cfg, func(tx *gorm.DB) error {
+		return genesis.Apply(ctx, tx, seed)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "schema is up to date, genesis %s\n", seed.Hash.Hex())
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats selected with the -o flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// table is the tabular form of a command's result.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// outputFlag adds the -o flag selecting the output format to flags.
+func outputFlag(flags *flag.FlagSet) *string {
+	return flags.String("o", outputTable, "output format, table or json")
+}
+
+// checkOutput validates the value of the -o flag. Commands check it before
+// connecting to the database, so an invalid format does not leave changes unreported.
+func checkOutput(format string) error {
+	if format != outputTable && format != outputJSON {
+		return fmt.Errorf("invalid output format %q, expected table or json", format)
+	}
+	return nil
+}
+
+// write prints v as indented JSON or t as an aligned table.
+func write(out io.Writer, format string, v any, t table) error {
+	if format == outputJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestWrite_Table(t *testing.T) {
+	var out bytes.Buffer
+
+	err := write(&out, outputTable, nil, table{
+		header: []string{"ADDRESS", "BALANCE"},
+		rows:   [][]string{{"0x01", "10"}, {"0x0002", "200"}},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "ADDRESS  BALANCE\n0x01     10\n0x0002   200\n", out.String())
+}
+
+func TestWrite_JSON(t *testing.T) {
+	var out bytes.Buffer
+
+	err := write(&out, outputJSON, balanceOutput{Address: "0x01", Balance: "10"}, table{})
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"address": "0x01", "balance": "10", "frozen": false}`, out.String())
+}
+
+func TestCheckOutput_Invalid(t *testing.T) {
+	assert.NoError(t, checkOutput(outputTable))
+	assert.NoError(t, checkOutput(outputJSON))
+	assert.Error(t, checkOutput("yaml"))
+}
+
+func TestParseAddress_Invalid(t *testing.T) {
+	_, err := parseAddress("0x123")
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/rebuild"
+
+	"gorm.io/gorm"
+)
+
+// rebuildBalances replays the ledger into a side table or, with -swap, replaces the accounts table.
+func rebuildBalances(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("rebuild-balances")
+	asOf := flags.String("as-of", "", "replay the entries created at or before this RFC 3339 time")
+	ledgerID := flags.Uint64("ledger-id", 0, "replay the entries up to and including this ledger entry")
+	sideTable := flags.String("side-table", rebuild.DefaultSideTable, "table receiving the rebuilt balances, it must not exist")
+	swap := flags.Bool("swap", false, "replace the accounts table, requires replaying the whole ledger")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	opts := rebuild.Options{LedgerID: *ledgerID, SideTable: *sideTable, Swap: *swap}
+	if *asOf != "" {
+		at, err := time.Parse(time.RFC3339, *asOf)
+		if err != nil {
+			return fmt.Errorf("invalid -as-of: %w", err)
+		}
+		opts.AsOf = &at
+	}
+
+	ctx = audit.WithSystemActor(ctx, operator())
+	var result *rebuild.Result
+	err = withDb(ctx, func(tx *gorm.DB) error {
+		result, err = rebuild.Run(ctx, tx, opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "replayed %d entries up to entry %d\n", result.Entries, result.LastEntryID)
+	fmt.Fprintf(out, "rebuilt %d accounts holding a supply of %s into %s\n", result.Accounts, result.Supply, result.Table)
+	fmt.Fprintf(out, "%d balances differ from the previous accounts table\n", result.Differences)
+	if result.Replaced != "" {
+		fmt.Fprintf(out, "previous accounts table kept as %s\n", result.Replaced)
+	}
+	return nil
+}
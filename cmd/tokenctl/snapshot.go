@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/snapshot"
+
+	"gorm.io/gorm"
+)
+
+type snapshotOutput struct {
+	ID            uint64    `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	CreatedBy     string    `json:"created_by"`
+	LedgerEntryID uint64    `json:"ledger_entry_id"`
+	Root          string    `json:"root"`
+	Accounts      int       `json:"accounts"`
+	Total         string    `json:"total"`
+}
+
+type proofOutput struct {
+	SnapshotID uint64   `json:"snapshot_id"`
+	Address    string   `json:"address"`
+	Amount     string   `json:"amount"`
+	Leaf       string   `json:"leaf"`
+	Proof      []string `json:"proof"`
+	Root       string   `json:"root"`
+}
+
+// snapshotBalances snapshots all balances or, with -id and -address, prints the
+// Merkle proof of an address in an existing snapshot.
+func snapshotBalances(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("snapshot")
+	id := flags.Uint64("id", 0, "snapshot to print the proof from, requires -address")
+	addr := flags.String("address", "", "address to print the Merkle proof of, requires -id")
+	format := outputFlag(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = checkOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	if *id != 0 || *addr != "" {
+		if *id == 0 || *addr == "" {
+			return fmt.Errorf("-id and -address must be given together")
+		}
+		return printProof(ctx, *id, *addr, *format, out)
+	}
+
+	ctx = audit.WithSystemActor(ctx, operator())
+	var s *db.Snapshot
+	err = withDb(ctx, func(tx *gorm.DB) error {
+		s, err = snapshot.Create(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	res := snapshotOutput{
+		ID:            s.ID,
+		CreatedAt:     s.CreatedAt,
+		CreatedBy:     s.CreatedBy,
+		LedgerEntryID: s.LedgerEntryID,
+		Root:          s.Root,
+		Accounts:      s.Accounts,
+		Total:         s.Total.String(),
+	}
+	return write(out, *format, res, table{
+		header: []string{"ID", "LEDGER ENTRY", "ACCOUNTS", "TOTAL", "ROOT"},
+		rows: [][]string{{
+			strconv.FormatUint(res.ID, 10),
+			strconv.FormatUint(res.LedgerEntryID, 10),
+			strconv.Itoa(res.Accounts),
+			res.Total,
+			res.Root,
+		}},
+	})
+}
+
+func printProof(ctx context.Context, id uint64, hex, format string, out io.Writer) error {
+	addr, err := parseAddress(hex)
+	if err != nil {
+		return err
+	}
+
+	var proof *snapshot.Proof
+	err = withDb(ctx, func(tx *gorm.DB) error {
+		proof, err = snapshot.MerkleProof(tx, id, addr)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if proof == nil {
+		return fmt.Errorf("%s has no balance in snapshot %d", addr.Hex(), id)
+	}
+
+	res := proofOutput{
+		SnapshotID: id,
+		Address:    addr.Hex(),
+		Amount:     proof.Amount.String(),
+		Leaf:       proof.Leaf.Hex(),
+		Proof:      make([]string, 0, len(proof.Hashes)),
+		Root:       proof.Snapshot.Root,
+	}
+	t := table{
+		header: []string{"FIELD", "VALUE"},
+		rows: [][]string{
+			{"amount", res.Amount},
+			{"leaf", res.Leaf},
+			{"root", res.Root},
+		},
+	}
+	for _, hash := range proof.Hashes {
+		res.Proof = append(res.Proof, hash.Hex())
+		t.rows = append(t.rows, []string{"proof", hash.Hex()})
+	}
+	return write(out, format, res, t)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"token-transfer-api/internal/ledger"
+
+	"gorm.io/gorm"
+)
+
+type verifyOutput struct {
+	Checked  int    `json:"checked"`
+	Valid    bool   `json:"valid"`
+	BrokenAt uint64 `json:"broken_at,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	LastHash string `json:"last_hash"`
+}
+
+// verify walks the ledger hash chain and reports the first broken link.
+// It fails if the chain is broken, so it can be used in scripts.
+func verify(ctx context.Context, args []string, out io.Writer) error {
+	flags := newFlagSet("verify")
+	from := flags.Uint64("from", 0, "id of the first entry to verify, 0 starts at the first entry")
+	to := flags.Uint64("to", 0, "id of the last entry to verify, 0 ends at the last entry")
+	format := outputFlag(flags)
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	err = checkOutput(*format)
+	if err != nil {
+		return err
+	}
+
+	var verification ledger.Verification
+	err = withDb(ctx, func(tx *gorm.DB) error {
+		verification, err = ledger.Verify(tx, *from, *to)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	res := verifyOutput{
+		Checked:  verification.Checked,
+		Valid:    verification.Valid,
+		BrokenAt: verification.BrokenAt,
+		Reason:   verification.Reason,
+		LastHash: verification.LastHash,
+	}
+	t := table{
+		header: []string{"CHECKED", "VALID", "LAST HASH"},
+		rows:   [][]string{{strconv.Itoa(res.Checked), strconv.FormatBool(res.Valid), res.LastHash}},
+	}
+	if !res.Valid {
+		t.header = append(t.header, "BROKEN AT", "REASON")
+		t.rows[0] = append(t.rows[0], strconv.FormatUint(res.BrokenAt, 10), res.Reason)
+	}
+	err = write(out, *format, res, t)
+	if err != nil {
+		return err
+	}
+
+	if !verification.Valid {
+		return fmt.Errorf("broken link at entry %d: %s", verification.BrokenAt, verification.Reason)
+	}
+	return nil
+}
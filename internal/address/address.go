@@ -33,7 +33,7 @@ func (a *Address) Scan(src any) error {
 	return nil
 }
 
-func (a *Address) Value() (driver.Value, error) {
+func (a Address) Value() (driver.Value, error) {
 	return a.Hex(), nil
 }
 
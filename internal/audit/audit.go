@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eaudit"
+
+	"gorm.io/gorm"
+)
+
+// Anonymous is the actor of events caused by unauthenticated requests.
+const Anonymous = "anonymous"
+
+// Event describes an audited state change.
+type Event struct {
+	// Operation names the change, it is overridden by the operation stored in
+	// the context, so that e.g. the transfers of a release are recorded as "release".
+	Operation string
+	// Subject identifies what the change was performed on, e.g. an address.
+	Subject string
+	// Details holds operation specific data, it is stored as JSON.
+	Details map[string]any
+	// Balances lists the balances changed by the operation.
+	Balances []BalanceChange
+}
+
+// BalanceChange is the balance of an account before and after an audited change.
+type BalanceChange struct {
+	Address address.Address
+	Before  decimal.Decimal
+	After   decimal.Decimal
+}
+
+// Record appends an event to the audit log. It is written inside tx,
+// so the event is persisted if and only if the audited change is.
+// The actor and request metadata are taken from ctx.
+func Record(ctx context.Context, tx *gorm.DB, event Event) error {
+	data, err := json.Marshal(event.Details)
+	if err != nil {
+		return eaudit.AuditRecordError
+	}
+
+	meta := FromContext(ctx)
+	operation := event.Operation
+	if meta.Operation != "" {
+		operation = meta.Operation
+	}
+
+	balances := make([]db.AuditBalanceChange, 0, len(event.Balances))
+	for _, change := range event.Balances {
+		balances = append(balances, db.AuditBalanceChange{
+			Address: change.Address,
+			Before:  change.Before,
+			After:   change.After,
+		})
+	}
+
+	err = tx.Create(&db.AuditEvent{
+		Actor:     Actor(ctx),
+		RequestID: meta.RequestID,
+		ClientIP:  meta.ClientIP,
+		Operation: operation,
+		Subject:   event.Subject,
+		Details:   string(data),
+		Balances:  balances,
+	}).Error
+	if err != nil {
+		return eaudit.AuditRecordError
+	}
+	return nil
+}
+
+// Actor returns who the events recorded with ctx are attributed to:
+// the authenticated operator, the system component set with WithSystemActor
+// or Anonymous.
+func Actor(ctx context.Context) string {
+	operator, ok := auth.Operator(ctx)
+	if ok {
+		return operator
+	}
+
+	meta := FromContext(ctx)
+	if meta.SystemActor != "" {
+		return meta.SystemActor
+	}
+	return Anonymous
+}
+
+// Filter restricts the listed events, zero fields match all events.
+type Filter struct {
+	Actor     string
+	Operation string
+	// Address matches events with the address as subject or with a balance change of the address.
+	Address *address.Address
+	From    *time.Time
+	To      *time.Time
+}
+
+// List returns up to limit events matching filter with an id greater than after,
+// ordered by id, together with their balance changes. Ids follow the order the events
+// were recorded in, CreatedAt is taken from the clock of the server recording them
+// and may not, so after must be the id of the last event of the previous page.
+func List(tx *gorm.DB, filter Filter, after uint64, limit int) ([]db.AuditEvent, error) {
+	query := tx.Preload("Balances", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("id")
+	}).Where("id > ?", after)
+
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Operation != "" {
+		query = query.Where("operation = ?", filter.Operation)
+	}
+	if filter.Address != nil {
+		query = query.Where(
+			"subject = ? OR id IN (SELECT audit_event_id FROM audit_balance_changes WHERE address = ?)",
+			filter.Address.Hex(), filter.Address.Hex(),
+		)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var events []db.AuditEvent
+	err := query.Order("id").Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, eaudit.AuditRetrievalError
+	}
+	return events, nil
+}
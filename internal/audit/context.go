@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request id. A request id sent by the client
+// is kept, otherwise one is generated. It is echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client provided request ids.
+const maxRequestIDLength = 128
+
+type contextKey struct{}
+
+// Metadata describes the origin of the audited changes made with a context.
+type Metadata struct {
+	RequestID string
+	ClientIP  string
+	// Operation overrides the operation name of recorded events.
+	Operation string
+	// SystemActor names the component acting on its own, e.g. the scheduler.
+	SystemActor string
+}
+
+// FromContext returns the metadata stored in ctx.
+func FromContext(ctx context.Context) Metadata {
+	meta, _ := ctx.Value(contextKey{}).(Metadata)
+	return meta
+}
+
+// WithMetadata returns a copy of ctx carrying meta.
+func WithMetadata(ctx context.Context, meta Metadata) context.Context {
+	return context.WithValue(ctx, contextKey{}, meta)
+}
+
+// WithOperation returns a copy of ctx whose recorded events are named operation.
+func WithOperation(ctx context.Context, operation string) context.Context {
+	meta := FromContext(ctx)
+	meta.Operation = operation
+	return WithMetadata(ctx, meta)
+}
+
+// WithSystemActor returns a copy of ctx whose recorded events are attributed to
+// the system component actor, unless an operator is authenticated.
+func WithSystemActor(ctx context.Context, actor string) context.Context {
+	meta := FromContext(ctx)
+	meta.SystemActor = actor
+	return WithMetadata(ctx, meta)
+}
+
+// Middleware stores the request id and client IP in the request context.
+// The client IP is taken from the X-Forwarded-For header if trustProxy is set,
+// which must only be enabled behind a proxy overwriting the header.
+func Middleware(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := r.Header.Get(RequestIDHeader)
+			if requestID == "" || len(requestID) > maxRequestIDLength {
+				requestID = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			meta := FromContext(r.Context())
+			meta.RequestID = requestID
+			meta.ClientIP = clientIP(r, trustProxy)
+			next.ServeHTTP(w, r.WithContext(WithMetadata(r.Context(), meta)))
+		})
+	}
+}
+
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		forwarded := r.Header.Get("X-Forwarded-For")
+		if forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/auth"
+)
+
+func testMiddleware(t *testing.T, trustProxy bool, headers map[string]string) (Metadata, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	var meta Metadata
+	handler := Middleware(trustProxy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		meta = FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return meta, rec
+}
+
+func TestMiddleware_GeneratesRequestID(t *testing.T) {
+	meta, rec := testMiddleware(t, false, nil)
+
+	assert.NotEmpty(t, meta.RequestID)
+	assert.Equal(t, meta.RequestID, rec.Header().Get(RequestIDHeader))
+	assert.Equal(t, "192.0.2.1", meta.ClientIP)
+}
+
+func TestMiddleware_KeepsRequestID(t *testing.T) {
+	meta, rec := testMiddleware(t, false, map[string]string{RequestIDHeader: "request-1"})
+
+	assert.Equal(t, "request-1", meta.RequestID)
+	assert.Equal(t, "request-1", rec.Header().Get(RequestIDHeader))
+}
+
+func TestMiddleware_IgnoresForwardedForByDefault(t *testing.T) {
+	meta, _ := testMiddleware(t, false, map[string]string{"X-Forwarded-For": "198.51.100.7"})
+
+	assert.Equal(t, "192.0.2.1", meta.ClientIP)
+}
+
+func TestMiddleware_TrustsForwardedFor(t *testing.T) {
+	meta, _ := testMiddleware(t, true, map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.1"})
+
+	assert.Equal(t, "198.51.100.7", meta.ClientIP)
+}
+
+func TestActor(t *testing.T) {
+	ctx := context.Background()
+	system := WithSystemActor(ctx, "scheduler")
+
+	assert.Equal(t, Anonymous, Actor(ctx))
+	assert.Equal(t, "scheduler", Actor(system))
+	assert.Equal(t, "alice", Actor(auth.WithOperator(system, "alice")))
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"token-transfer-api/internal/errors/eauth"
+)
+
+type contextKey struct{}
+
+// Tokens maps admin bearer tokens to the operator they identify.
+type Tokens map[string]string
+
+// ParseTokens parses comma separated operator:token pairs, e.g. "alice:s3cret,bob:t0ken".
+func ParseTokens(s string) (Tokens, error) {
+	tokens := Tokens{}
+	if s == "" {
+		return tokens, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		operator, token, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || operator == "" || token == "" {
+			return nil, eauth.InvalidAdminTokensError
+		}
+		tokens[token] = operator
+	}
+	return tokens, nil
+}
+
+// Lookup returns the operator identified by token.
+// Tokens are compared in constant time.
+func (t Tokens) Lookup(token string) (string, bool) {
+	for candidate, operator := range t {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			return operator, true
+		}
+	}
+	return "", false
+}
+
+// Middleware authenticates requests carrying an "Authorization: Bearer <token>" header
+// and stores the identified operator in the request context. Requests without the header
+// are passed through anonymously, requests with an unknown token are rejected.
+func Middleware(tokens Tokens) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok {
+				http.Error(w, eauth.InvalidTokenError.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			operator, ok := tokens.Lookup(token)
+			if !ok {
+				http.Error(w, eauth.InvalidTokenError.Error(), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithOperator(r.Context(), operator)))
+		})
+	}
+}
+
+// WithOperator returns a copy of ctx authenticated as operator.
+func WithOperator(ctx context.Context, operator string) context.Context {
+	return context.WithValue(ctx, contextKey{}, operator)
+}
+
+// Operator returns the operator ctx is authenticated as.
+func Operator(ctx context.Context) (string, bool) {
+	operator, ok := ctx.Value(contextKey{}).(string)
+	return operator, ok
+}
+
+// RequireOperator returns the operator ctx is authenticated as
+// or eauth.UnauthorizedError for anonymous requests.
+func RequireOperator(ctx context.Context) (string, error) {
+	operator, ok := Operator(ctx)
+	if !ok {
+		return "", eauth.UnauthorizedError
+	}
+	return operator, nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"token-transfer-api/internal/errors/eauth"
+)
+
+func testMiddleware(t *testing.T, authorization string, expectedStatus int, expectedOperator string) {
+	t.Helper()
+
+	tokens, err := ParseTokens("alice:secret-a, bob:secret-b")
+	require.NoError(t, err)
+
+	var operator string
+	handler := Middleware(tokens)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		operator, _ = Operator(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, expectedStatus, rec.Code)
+	assert.Equal(t, expectedOperator, operator)
+}
+
+func TestMiddleware_Anonymous(t *testing.T) {
+	testMiddleware(t, "", http.StatusOK, "")
+}
+
+func TestMiddleware_ValidToken(t *testing.T) {
+	testMiddleware(t, "Bearer secret-b", http.StatusOK, "bob")
+}
+
+func TestMiddleware_InvalidToken(t *testing.T) {
+	testMiddleware(t, "Bearer secret-c", http.StatusUnauthorized, "")
+}
+
+func TestMiddleware_NotBearer(t *testing.T) {
+	testMiddleware(t, "Basic secret-a", http.StatusUnauthorized, "")
+}
+
+func TestParseTokens_Invalid(t *testing.T) {
+	_, err := ParseTokens("alice")
+	assert.ErrorIs(t, err, eauth.InvalidAdminTokensError)
+}
@@ -0,0 +1,331 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/errors/econfig"
+	"token-transfer-api/internal/errors/efees"
+	"token-transfer-api/internal/fees"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	DefaultSchedulerPollInterval  = 5 * time.Second
+	DefaultSchedulerBatchSize     = 100
+	DefaultReconcileInterval      = time.Hour
+	DefaultCheckpointInterval     = 10 * time.Minute
+	DefaultTransferRetryAttempts  = 3
+	DefaultTransferRetryBackoff   = 10 * time.Millisecond
+	DefaultHotAccountShards       = 8
+	DefaultLockTimeout            = 5 * time.Second
+	DefaultStatementTimeout       = 30 * time.Second
+	DefaultDatabaseConnectTimeout = time.Minute
+	DefaultDatabaseConnectBackoff = 500 * time.Millisecond
+	DefaultReadYourWritesWindow   = 5 * time.Second
+)
+
+// Ledger stores selected with LEDGER_STORE.
+const (
+	// StoreDatabase keeps the ledger in the PostgreSQL or SQLite database configured with DATABASE_URL.
+	StoreDatabase = "database"
+	// StoreMemory keeps the ledger in memory, it is lost on restart. Only transfers
+	// and balances are available, the other features need a database.
+	StoreMemory = "memory"
+)
+
+// Config holds the runtime settings of the application.
+// All values are read from environment variables, see Load.
+type Config struct {
+	// SchedulerPollInterval is how often the background worker looks for due scheduled transfers.
+	SchedulerPollInterval time.Duration
+	// SchedulerBatchSize is the maximum number of scheduled transfers executed per poll.
+	SchedulerBatchSize int
+	// ReconcileInterval is how often balances are reconciled with the ledger, zero disables it.
+	ReconcileInterval time.Duration
+	// CheckpointInterval is how often balance checkpoints for historical queries are created, zero disables it.
+	CheckpointInterval time.Duration
+	// TransferFees is the fee schedule charged on transfers, nil if transfers are free.
+	TransferFees fees.Schedule
+	// FeeCollector is the account credited with transfer fees.
+	FeeCollector address.Address
+	// AdminTokens identifies the operators allowed to perform administrative operations.
+	AdminTokens auth.Tokens
+	// GenesisFile is the JSON or YAML genesis file seeding the initial balances,
+	// empty to seed the default account.
+	GenesisFile string
+	// LedgerStore selects where the ledger is kept, StoreDatabase or StoreMemory.
+	LedgerStore string
+	// ConditionalUpdates moves balances with conditional updates instead of
+	// locking and reading the accounts first, see ledger.Ledger.
+	ConditionalUpdates bool
+	// TransferRetryAttempts is the maximum number of attempts of transfers aborted by
+	// a concurrent transaction, e.g. on a deadlock, one disables retries.
+	TransferRetryAttempts int
+	// TransferRetryBackoff is the maximum delay before the first retry, it doubles with every retry.
+	TransferRetryBackoff time.Duration
+	// HotAccounts are the accounts whose balance is split over HotAccountShards shards
+	// so that concurrent transfers do not serialize on them, e.g. a faucet.
+	HotAccounts []address.Address
+	// HotAccountShards is the number of shards of each hot account.
+	HotAccountShards int
+	// LockTimeout bounds how long a statement of a ledger transaction waits for a lock, zero disables it.
+	LockTimeout time.Duration
+	// StatementTimeout bounds how long a statement of a ledger transaction runs, zero disables it.
+	StatementTimeout time.Duration
+	// TrustProxyHeaders takes the client IP recorded in the audit log from the
+	// X-Forwarded-For header. Only enable it behind a proxy setting the header.
+	TrustProxyHeaders bool
+	// ReadYourWritesWindow is how long the reads of a client following its mutation are
+	// served by the primary instead of the replicas, zero disables it.
+	ReadYourWritesWindow time.Duration
+	// Database configures how the database and its replicas are connected: how long
+	// connecting is retried on startup and the connection pool.
+	Database db.Options
+}
+
+// Load reads the configuration from the environment, falling back to
+// defaults for unset variables.
+func Load() (Config, error) {
+	var cfg Config
+	var err error
+
+	cfg.SchedulerPollInterval, err = duration("SCHEDULER_POLL_INTERVAL", DefaultSchedulerPollInterval)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.SchedulerBatchSize, err = integer("SCHEDULER_BATCH_SIZE", DefaultSchedulerBatchSize)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.ReconcileInterval, err = duration("RECONCILE_INTERVAL", DefaultReconcileInterval)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.CheckpointInterval, err = duration("CHECKPOINT_INTERVAL", DefaultCheckpointInterval)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.TransferFees, err = feeSchedule("TRANSFER_FEE")
+	if err != nil {
+		return Config{}, err
+	}
+
+	if cfg.TransferFees != nil {
+		cfg.FeeCollector, err = feeCollector("FEE_COLLECTOR_ADDRESS")
+		if err != nil {
+			return Config{}, err
+		}
+	}
+
+	cfg.AdminTokens, err = auth.ParseTokens(os.Getenv("ADMIN_TOKENS"))
+	if err != nil {
+		return Config{}, econfig.InvalidValueError{Key: "ADMIN_TOKENS", Value: "<redacted>", Err: err}
+	}
+
+	cfg.TrustProxyHeaders, err = boolean("TRUST_PROXY_HEADERS", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.GenesisFile = os.Getenv("GENESIS_FILE")
+
+	cfg.LedgerStore, err = ledgerStore("LEDGER_STORE")
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.ConditionalUpdates, err = boolean("LEDGER_CONDITIONAL_UPDATES", false)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.TransferRetryAttempts, err = integer("TRANSFER_RETRY_ATTEMPTS", DefaultTransferRetryAttempts)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.TransferRetryBackoff, err = duration("TRANSFER_RETRY_BACKOFF", DefaultTransferRetryBackoff)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.HotAccounts, err = addresses("HOT_ACCOUNTS")
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.HotAccountShards, err = integer("HOT_ACCOUNT_SHARDS", DefaultHotAccountShards)
+	if err != nil {
+		return Config{}, err
+	}
+	if cfg.HotAccountShards < 1 {
+		return Config{}, econfig.InvalidValueError{Key: "HOT_ACCOUNT_SHARDS", Value: os.Getenv("HOT_ACCOUNT_SHARDS"), Err: errors.New("must be at least 1")}
+	}
+
+	cfg.LockTimeout, err = duration("LOCK_TIMEOUT", DefaultLockTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.StatementTimeout, err = duration("STATEMENT_TIMEOUT", DefaultStatementTimeout)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.ReadYourWritesWindow, err = duration("READ_YOUR_WRITES_WINDOW", DefaultReadYourWritesWindow)
+	if err != nil {
+		return Config{}, err
+	}
+
+	cfg.Database, err = dbOptions()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return cfg, nil
+}
+
+func dbOptions() (db.Options, error) {
+	var opts db.Options
+	var err error
+
+	opts.RetryTimeout, err = duration("DATABASE_CONNECT_TIMEOUT", DefaultDatabaseConnectTimeout)
+	if err != nil {
+		return db.Options{}, err
+	}
+
+	opts.RetryBackoff, err = duration("DATABASE_CONNECT_BACKOFF", DefaultDatabaseConnectBackoff)
+	if err != nil {
+		return db.Options{}, err
+	}
+	if opts.RetryBackoff <= 0 {
+		return db.Options{}, econfig.InvalidValueError{Key: "DATABASE_CONNECT_BACKOFF", Value: os.Getenv("DATABASE_CONNECT_BACKOFF"), Err: errors.New("must be positive")}
+	}
+
+	opts.Pool.MaxOpenConns, err = integer("DATABASE_MAX_OPEN_CONNS", 0)
+	if err != nil {
+		return db.Options{}, err
+	}
+
+	opts.Pool.MaxIdleConns, err = integer("DATABASE_MAX_IDLE_CONNS", 0)
+	if err != nil {
+		return db.Options{}, err
+	}
+
+	opts.Pool.ConnMaxLifetime, err = duration("DATABASE_CONN_MAX_LIFETIME", 0)
+	if err != nil {
+		return db.Options{}, err
+	}
+
+	opts.Pool.ConnMaxIdleTime, err = duration("DATABASE_CONN_MAX_IDLE_TIME", 0)
+	if err != nil {
+		return db.Options{}, err
+	}
+
+	return opts, nil
+}
+
+func duration(key string, def time.Duration) (time.Duration, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, econfig.InvalidValueError{Key: key, Value: s, Err: err}
+	}
+	return d, nil
+}
+
+func integer(key string, def int) (int, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, econfig.InvalidValueError{Key: key, Value: s, Err: err}
+	}
+	return n, nil
+}
+
+func boolean(key string, def bool) (bool, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return def, nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, econfig.InvalidValueError{Key: key, Value: s, Err: err}
+	}
+	return b, nil
+}
+
+func ledgerStore(key string) (string, error) {
+	s := os.Getenv(key)
+	switch s {
+	case "":
+		return StoreDatabase, nil
+	case StoreDatabase, StoreMemory:
+		return s, nil
+	}
+	return "", econfig.InvalidValueError{Key: key, Value: s, Err: errors.New("expected database or memory")}
+}
+
+// addresses parses a comma separated list of hex addresses.
+func addresses(key string) ([]address.Address, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return nil, nil
+	}
+
+	var parsed []address.Address
+	for _, hex := range strings.Split(s, ",") {
+		hex = strings.TrimSpace(hex)
+		if !common.IsHexAddress(hex) {
+			return nil, econfig.InvalidValueError{Key: key, Value: s, Err: fmt.Errorf("%q is not a hex address", hex)}
+		}
+		parsed = append(parsed, address.HexToAddress(hex))
+	}
+	return parsed, nil
+}
+
+func feeSchedule(key string) (fees.Schedule, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return nil, nil
+	}
+
+	schedule, err := fees.Parse([]byte(s))
+	if err != nil {
+		return nil, econfig.InvalidValueError{Key: key, Value: s, Err: err}
+	}
+	return schedule, nil
+}
+
+func feeCollector(key string) (address.Address, error) {
+	s := os.Getenv(key)
+	if s == "" {
+		return address.Address{}, efees.CollectorNotConfiguredError
+	}
+
+	if !common.IsHexAddress(s) {
+		return address.Address{}, econfig.InvalidValueError{Key: key, Value: s, Err: errors.New("not a hex address")}
+	}
+	return address.HexToAddress(s), nil
+}
@@ -0,0 +1,182 @@
+package controls
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/errors/econtrols"
+	"token-transfer-api/internal/errors/eresolvers"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Freeze stops addr from sending and receiving tokens. Freezing a frozen
+// account updates the reason. The change is recorded in the audit log.
+//
+// The account is locked first: transfers check for freezes once they hold their
+// accounts, so transfers of addr in flight commit before Freeze returns and later
+// ones are rejected. Only a transfer creating the account of addr is not waited for.
+func Freeze(ctx context.Context, tx *gorm.DB, addr address.Address, reason, operator string) (*db.FrozenAccount, error) {
+	if strings.TrimSpace(reason) == "" {
+		return nil, econtrols.EmptyReasonError
+	}
+
+	var locked []db.Account
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("address = ?", addr.Hex()).Limit(1).Find(&locked).Error
+	if err != nil {
+		return nil, econtrols.FreezeUpdateError
+	}
+
+	frozen := db.FrozenAccount{
+		Address:  addr,
+		Reason:   reason,
+		FrozenBy: operator,
+		FrozenAt: time.Now().UTC(),
+	}
+	err = tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "address"}},
+		DoUpdates: clause.AssignmentColumns([]string{"reason", "frozen_by", "frozen_at"}),
+	}).Create(&frozen).Error
+	if err != nil {
+		return nil, econtrols.FreezeUpdateError
+	}
+
+	err = audit.Record(ctx, tx, audit.Event{
+		Operation: "freezeAccount",
+		Subject:   addr.Hex(),
+		Details:   map[string]any{"reason": reason},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &frozen, nil
+}
+
+// Unfreeze allows a frozen address to transfer tokens again.
+// The change is recorded in the audit log.
+func Unfreeze(ctx context.Context, tx *gorm.DB, addr address.Address, operator string) error {
+	res := tx.Where("address = ?", addr.Hex()).Delete(&db.FrozenAccount{})
+	if res.Error != nil {
+		return econtrols.FreezeUpdateError
+	}
+	if res.RowsAffected == 0 {
+		return econtrols.NotFrozenError{Address: addr}
+	}
+
+	return audit.Record(ctx, tx, audit.Event{Operation: "unfreezeAccount", Subject: addr.Hex()})
+}
+
+// GetFrozen returns the freeze of addr or nil if the account is not frozen.
+func GetFrozen(tx *gorm.DB, addr address.Address) (*db.FrozenAccount, error) {
+	var frozen []db.FrozenAccount
+	err := tx.Where("address = ?", addr.Hex()).Limit(1).Find(&frozen).Error
+	if err != nil {
+		return nil, econtrols.FreezeRetrievalError
+	}
+
+	if len(frozen) == 0 {
+		return nil, nil
+	}
+	return &frozen[0], nil
+}
+
+// ListFrozen returns all frozen accounts.
+func ListFrozen(tx *gorm.DB) ([]db.FrozenAccount, error) {
+	var frozen []db.FrozenAccount
+	err := tx.Order("frozen_at").Find(&frozen).Error
+	if err != nil {
+		return nil, econtrols.FreezeRetrievalError
+	}
+	return frozen, nil
+}
+
+// SetPaused pauses or resumes all transfers. Reads keep being served while paused.
+// The change is recorded in the audit log.
+//
+// The pause row is locked first, which waits for the transfers in flight holding it
+// FOR SHARE, see CheckTransfer, so no transfer commits after pausing returns.
+func SetPaused(ctx context.Context, tx *gorm.DB, paused bool, reason, operator string) (*db.Pause, error) {
+	if paused && strings.TrimSpace(reason) == "" {
+		return nil, econtrols.EmptyReasonError
+	}
+
+	var locked []db.Pause
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", db.PauseID).Find(&locked).Error
+	if err != nil {
+		return nil, econtrols.PauseUpdateError
+	}
+
+	pause := db.Pause{
+		ID:        db.PauseID,
+		Paused:    paused,
+		Reason:    reason,
+		UpdatedBy: operator,
+		UpdatedAt: time.Now().UTC(),
+	}
+	err = tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"paused", "reason", "updated_by", "updated_at"}),
+	}).Create(&pause).Error
+	if err != nil {
+		return nil, econtrols.PauseUpdateError
+	}
+
+	operation := "unpause"
+	if paused {
+		operation = "pause"
+	}
+	err = audit.Record(ctx, tx, audit.Event{Operation: operation, Details: map[string]any{"reason": reason}})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pause, nil
+}
+
+// GetPause returns the current pause state.
+func GetPause(tx *gorm.DB) (*db.Pause, error) {
+	pause := db.Pause{}
+	err := tx.Where("id = ?", db.PauseID).Take(&pause).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &db.Pause{ID: db.PauseID}, nil
+		}
+		return nil, econtrols.PauseRetrievalError
+	}
+	return &pause, nil
+}
+
+// CheckTransfer returns an error if transfers are paused or any of the addresses is frozen.
+// Transfers call it once their accounts are locked, so that Freeze waits for them. The
+// pause row is held FOR SHARE until the end of the transaction, so that SetPaused does.
+func CheckTransfer(tx *gorm.DB, addresses ...address.Address) error {
+	pause, err := GetPause(tx.Clauses(clause.Locking{Strength: "SHARE"}))
+	if err != nil {
+		return err
+	}
+	if pause.Paused {
+		return eresolvers.TransfersPausedError
+	}
+
+	hexes := make([]string, 0, len(addresses))
+	for _, addr := range addresses {
+		hexes = append(hexes, addr.Hex())
+	}
+
+	var frozen []db.FrozenAccount
+	err = tx.Where("address IN ?", hexes).Order("address").Find(&frozen).Error
+	if err != nil {
+		return econtrols.FreezeRetrievalError
+	}
+	if len(frozen) > 0 {
+		return eresolvers.AccountFrozenError{Address: frozen[0].Address}
+	}
+
+	return nil
+}
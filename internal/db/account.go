@@ -3,6 +3,8 @@ package db
 import (
 	"token-transfer-api/internal/address"
 	"token-transfer-api/internal/decimal"
+
+	"gorm.io/gorm"
 )
 
 // Account represents a user's cryptocurrency account in the database.
@@ -11,3 +13,28 @@ type Account struct {
 	Address address.Address `gorm:"primaryKey;type:string;size:42"`
 	Amount  decimal.Decimal `gorm:"type:numeric(78,0)"`
 }
+
+// AccountShard is a sub-balance of a hot account. The balance of a hot account is
+// split over several shards so that concurrent debits lock different rows, its
+// balance is the amount of the account plus the amounts of its shards, see Balances.
+type AccountShard struct {
+	Address address.Address `gorm:"primaryKey;type:string;size:42"`
+	Shard   int             `gorm:"primaryKey;autoIncrement:false"`
+	Amount  decimal.Decimal `gorm:"type:numeric(78,0)"`
+}
+
+// Balances returns a query of the accounts with their balance including the shards
+// of hot accounts. It is aliased accounts and can be used like the accounts table.
+func Balances(tx *gorm.DB) *gorm.DB {
+	return tx.Table("(?) AS accounts", gorm.Expr(BalancesSQL(tx)))
+}
+
+// BalancesSQL returns the SQL of Balances for raw queries. SQLite does not shard
+// accounts, its writers are serialized anyway.
+func BalancesSQL(tx *gorm.DB) string {
+	if IsSQLite(tx) {
+		return "SELECT address, amount FROM accounts"
+	}
+	return `SELECT a.address, a.amount + COALESCE((SELECT SUM(s.amount) FROM account_shards s WHERE s.address = a.address), 0) AS amount
+FROM accounts a`
+}
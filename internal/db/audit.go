@@ -0,0 +1,32 @@
+package db
+
+import (
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+)
+
+// AuditEvent is an entry of the append-only log of administrative and financial actions.
+// Rows are never updated or deleted, which is enforced by a trigger, see Migrate.
+type AuditEvent struct {
+	ID        uint64    `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"index"`
+	Actor     string    `gorm:"index"`
+	RequestID string
+	ClientIP  string
+	Operation string `gorm:"index"`
+	// Subject identifies what the action was performed on, e.g. an address.
+	Subject string `gorm:"index"`
+	// Details is a JSON object with operation specific data.
+	Details  string
+	Balances []AuditBalanceChange
+}
+
+// AuditBalanceChange is the balance of an account before and after an audited action.
+type AuditBalanceChange struct {
+	ID           uint64          `gorm:"primaryKey"`
+	AuditEventID uint64          `gorm:"index"`
+	Address      address.Address `gorm:"type:string;size:42;index"`
+	Before       decimal.Decimal `gorm:"type:numeric(78,0)"`
+	After        decimal.Decimal `gorm:"type:numeric(78,0)"`
+}
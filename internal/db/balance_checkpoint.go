@@ -0,0 +1,16 @@
+package db
+
+import (
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+)
+
+// BalanceCheckpoint is the balance of Address after the ledger entry LedgerEntryID.
+// Checkpoints are incremental: a checkpoint only stores the addresses whose balance
+// changed since the previous checkpoint, so the balance of an address at a checkpoint
+// is its row with the greatest LedgerEntryID not after it.
+type BalanceCheckpoint struct {
+	LedgerEntryID uint64          `gorm:"primaryKey;autoIncrement:false;index:idx_balance_checkpoints_address_entry,priority:2"`
+	Address       address.Address `gorm:"primaryKey;type:string;size:42;index:idx_balance_checkpoints_address_entry,priority:1"`
+	Amount        decimal.Decimal `gorm:"type:numeric(78,0)"`
+}
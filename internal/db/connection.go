@@ -1,24 +1,101 @@
 package db
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 	"log"
 	"os"
+	"strings"
 	"time"
-	"token-transfer-api/internal/address"
-	"token-transfer-api/internal/decimal"
 )
 
 const useLogger = false
 
+// sqlitePrefix marks a DATABASE_URL naming a SQLite database file, e.g. "sqlite:ledger.db".
+const sqlitePrefix = "sqlite:"
+
+// sqliteOptions begin every transaction with BEGIN IMMEDIATE, which takes the write
+// lock of the database upfront. SQLite has no SELECT ... FOR UPDATE, so this is how
+// transfers are serialized. Waiting writers retry for busy_timeout milliseconds.
+const sqliteOptions = "_txlock=immediate&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+
 // ConnectDb returns pointer to gorm.DB which can be used to
 // interact with the database. Applies migrations.
-func ConnectDb() (*gorm.DB, error) {
-	dsn := os.Getenv("DATABASE_URL")
+//
+// DATABASE_URL is a PostgreSQL connection string, or the path of a SQLite
+// database file prefixed with "sqlite:". Connecting and migrating are retried
+// as configured by opts, so that the database may still be starting.
+func ConnectDb(ctx context.Context, opts Options) (*gorm.DB, error) {
+	return connect(ctx, "database", opts, func() (*gorm.DB, error) {
+		db, err := open(os.Getenv("DATABASE_URL"))
+		if err != nil {
+			return nil, err
+		}
+
+		err = Migrate(ctx, db)
+		if err != nil {
+			sqlDB, err2 := db.DB()
+			if err2 != nil {
+				return nil, fmt.Errorf("migration failed (%w), and failed to get underlying *sql.DB (%w)", err, err2)
+			}
+
+			err2 = sqlDB.Close()
+			if err2 != nil {
+				return nil, fmt.Errorf("migration failed (%w), and failed to close database connection (%w)", err, err2)
+			}
+
+			return nil, err
+		}
+
+		return db, nil
+	})
+}
+
+// connect calls attempt until it succeeds or opts.RetryTimeout has elapsed, waiting
+// opts.RetryBackoff before the first retry and doubling the delay up to maxRetryBackoff.
+// The pool of the connection is configured with opts.Pool.
+func connect(ctx context.Context, name string, opts Options, attempt func() (*gorm.DB, error)) (*gorm.DB, error) {
+	deadline := time.Now().Add(opts.RetryTimeout)
+	backoff := opts.RetryBackoff
+	for {
+		db, err := attempt()
+		if err == nil {
+			err = opts.Pool.apply(db)
+			if err != nil {
+				return nil, errors.Join(err, CloseDb(db))
+			}
+			return db, nil
+		}
+
+		remaining := time.Until(deadline)
+		if backoff <= 0 || remaining <= 0 {
+			if opts.RetryTimeout > 0 {
+				return nil, fmt.Errorf("connecting to the %s did not succeed within %s: %w", name, opts.RetryTimeout, err)
+			}
+			return nil, err
+		}
+
+		delay := min(backoff, maxRetryBackoff, remaining)
+		log.Printf("connecting to the %s failed, retrying in %s: %v", name, delay, err)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
 
+// open connects to the database of dsn, see ConnectDb, without applying migrations.
+func open(dsn string) (*gorm.DB, error) {
 	var newLogger logger.Interface
 	if useLogger {
 		newLogger = logger.New(
@@ -34,27 +111,161 @@ func ConnectDb() (*gorm.DB, error) {
 		newLogger = logger.Discard
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: newLogger})
+	return gorm.Open(dialector(dsn), &gorm.Config{Logger: newLogger})
+}
+
+// dialector returns the dialector of a PostgreSQL connection string or "sqlite:" path.
+func dialector(dsn string) gorm.Dialector {
+	if path, ok := strings.CutPrefix(dsn, sqlitePrefix); ok {
+		separator := "?"
+		if strings.Contains(path, "?") {
+			separator = "&"
+		}
+		return sqlite.Open(path + separator + sqliteOptions)
+	}
+	return postgres.Open(dsn)
+}
+
+// models lists the tables managed by AutoMigrate.
+var models = []interface{}{
+	&Account{},
+	&AccountShard{},
+	&ScheduledTransfer{},
+	&ScheduledTransferRun{},
+	&Vesting{},
+	&Stream{},
+	&FrozenAccount{},
+	&Pause{},
+	&AuditEvent{},
+	&AuditBalanceChange{},
+	&LedgerEntry{},
+	&BalanceCheckpoint{},
+	&Snapshot{},
+	&SnapshotBalance{},
+	&Genesis{},
+}
+
+// appendOnlyTables are protected from updates and deletes by a trigger.
+var appendOnlyTables = []string{"audit_events", "audit_balance_changes"}
+
+// Migrate creates or updates the database schema, its statements are cancelled with ctx.
+func Migrate(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+	err := db.AutoMigrate(models...)
+	if err != nil {
+		return err
+	}
+
+	err = protectAppendOnly(db)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	err = db.AutoMigrate(&Account{})
+	// transfers lock the pause row, so it must exist before the first pause
+	err = db.Clauses(clause.OnConflict{DoNothing: true}).Create(&Pause{ID: PauseID}).Error
 	if err != nil {
-		sqlDB, err2 := db.DB()
-		if err2 != nil {
-			return nil, fmt.Errorf("migration failed (%w), and failed to get underlying *sql.DB (%w)", err, err2)
+		return err
+	}
+
+	return openLedger(ctx, db)
+}
+
+// protectAppendOnly creates the triggers rejecting updates and deletes of appendOnlyTables.
+func protectAppendOnly(db *gorm.DB) error {
+	if IsSQLite(db) {
+		for _, table := range appendOnlyTables {
+			err := db.Exec(fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_append_only_update BEFORE UPDATE ON %[1]s
+BEGIN SELECT RAISE(ABORT, '%[1]s is append-only'); END`, table)).Error
+			if err != nil {
+				return err
+			}
+			err = db.Exec(fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %[1]s_append_only_delete BEFORE DELETE ON %[1]s
+BEGIN SELECT RAISE(ABORT, '%[1]s is append-only'); END`, table)).Error
+			if err != nil {
+				return err
+			}
 		}
+		return nil
+	}
 
-		err2 = sqlDB.Close()
-		if err2 != nil {
-			return nil, fmt.Errorf("migration failed (%w), and failed to close database connection (%w)", err, err2)
+	err := db.Exec(`CREATE OR REPLACE FUNCTION reject_modification() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql`).Error
+	if err != nil {
+		return err
+	}
+
+	for _, table := range appendOnlyTables {
+		err = db.Exec(fmt.Sprintf(
+			`CREATE OR REPLACE TRIGGER %[1]s_append_only BEFORE UPDATE OR DELETE ON %[1]s
+FOR EACH ROW EXECUTE FUNCTION reject_modification()`, table)).Error
+		if err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+// IsSQLite reports whether db is a SQLite database.
+func IsSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
 
-		return nil, err
+// AdvisoryLock takes the transaction scoped advisory lock identified by key, which
+// is released when tx ends. SQLite transactions hold the write lock of the whole
+// database, so no further lock is needed there.
+func AdvisoryLock(tx *gorm.DB, key int64) error {
+	if IsSQLite(tx) {
+		return nil
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(?)", key).Error
+}
+
+// TryAdvisoryLock is like AdvisoryLock, but returns false instead of waiting if
+// the lock is held by another transaction.
+func TryAdvisoryLock(tx *gorm.DB, key int64) (bool, error) {
+	if IsSQLite(tx) {
+		return true, nil
 	}
 
-	return db, nil
+	var locked bool
+	err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", key).Scan(&locked).Error
+	return locked, err
+}
+
+// openLedger records the balances of databases created before the ledger existed
+// as mints, so that the ledger accounts for every token.
+func openLedger(ctx context.Context, db *gorm.DB) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := AdvisoryLock(tx, ledgerLockKey)
+		if err != nil {
+			return err
+		}
+
+		var entries int64
+		err = tx.Model(&LedgerEntry{}).Count(&entries).Error
+		if err != nil || entries > 0 {
+			return err
+		}
+
+		var accounts []Account
+		err = tx.Where("amount > 0").Order("address").Find(&accounts).Error
+		if err != nil || len(accounts) == 0 {
+			return err
+		}
+
+		mints := make([]*LedgerEntry, 0, len(accounts))
+		for _, account := range accounts {
+			mints = append(mints, &LedgerEntry{
+				Kind:      LedgerEntryMint,
+				ToAddress: account.Address,
+				Amount:    account.Amount,
+			})
+		}
+		return AppendLedgerEntries(tx, mints...)
+	})
 }
 
 func CloseDb(db *gorm.DB) error {
@@ -69,20 +280,6 @@ func CloseDb(db *gorm.DB) error {
 	return nil
 }
 
+// DefaultCurrencyAmount is minted to DefaultAccountHex if no genesis file is configured.
 const DefaultCurrencyAmount int64 = 1_000_000
 const DefaultAccountHex = "0x0000000000000000000000000000000000000000"
-
-// CreateDefaultAccount creates the default account if it does not exist.
-func CreateDefaultAccount(db *gorm.DB) error {
-	defaultAccount := Account{
-		Address: address.HexToAddress(DefaultAccountHex),
-		Amount:  decimal.NewFromInt64(DefaultCurrencyAmount),
-	}
-	err := db.Where("address = ?", defaultAccount.Address).
-		FirstOrCreate(&defaultAccount).Error
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var errNotReady = errors.New("the database system is starting up")
+
+// openTemp opens an empty SQLite database in a temporary directory.
+func openTemp(t *testing.T) (*gorm.DB, error) {
+	return open(sqlitePrefix + filepath.Join(t.TempDir(), "test.db"))
+}
+
+func TestConnect_RetriesUntilReady(t *testing.T) {
+	attempts := 0
+	opts := Options{RetryTimeout: time.Second, RetryBackoff: time.Millisecond}
+
+	conn, err := connect(context.Background(), "database", opts, func() (*gorm.DB, error) {
+		attempts++
+		if attempts < 3 {
+			return nil, errNotReady
+		}
+		return openTemp(t)
+	})
+
+	require.NoError(t, err)
+	assert.NoError(t, CloseDb(conn))
+	assert.Equal(t, 3, attempts)
+}
+
+func TestConnect_GivesUpAfterTimeout(t *testing.T) {
+	attempts := 0
+	opts := Options{RetryTimeout: 20 * time.Millisecond, RetryBackoff: time.Millisecond}
+
+	_, err := connect(context.Background(), "database", opts, func() (*gorm.DB, error) {
+		attempts++
+		return nil, errNotReady
+	})
+
+	assert.ErrorIs(t, err, errNotReady)
+	assert.Greater(t, attempts, 1)
+}
+
+func TestConnect_WithoutRetries(t *testing.T) {
+	attempts := 0
+
+	_, err := connect(context.Background(), "database", Options{}, func() (*gorm.DB, error) {
+		attempts++
+		return nil, errNotReady
+	})
+
+	assert.ErrorIs(t, err, errNotReady)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestConnect_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	opts := Options{RetryTimeout: time.Minute, RetryBackoff: time.Minute}
+
+	_, err := connect(ctx, "database", opts, func() (*gorm.DB, error) {
+		cancel()
+		return nil, errNotReady
+	})
+
+	assert.ErrorIs(t, err, errNotReady)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestConnect_ConfiguresPool(t *testing.T) {
+	opts := Options{Pool: Pool{MaxOpenConns: 4, MaxIdleConns: 2}}
+
+	conn, err := connect(context.Background(), "database", opts, func() (*gorm.DB, error) {
+		return openTemp(t)
+	})
+
+	require.NoError(t, err)
+	sqlDB, err := conn.DB()
+	require.NoError(t, err)
+	assert.Equal(t, 4, sqlDB.Stats().MaxOpenConnections)
+	assert.NoError(t, CloseDb(conn))
+}
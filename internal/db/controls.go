@@ -0,0 +1,30 @@
+package db
+
+import (
+	"time"
+	"token-transfer-api/internal/address"
+)
+
+// FrozenAccount marks an address that can neither send nor receive tokens.
+type FrozenAccount struct {
+	Address  address.Address `gorm:"primaryKey;type:string;size:42"`
+	Reason   string
+	FrozenBy string
+	FrozenAt time.Time
+}
+
+// PauseID is the primary key of the only Pause row.
+const PauseID = 1
+
+// Pause is the global switch stopping all transfers. The table holds a single row.
+type Pause struct {
+	ID        uint `gorm:"primaryKey"`
+	Paused    bool
+	Reason    string
+	UpdatedBy string
+	UpdatedAt time.Time
+}
+
+func (Pause) TableName() string {
+	return "pause"
+}
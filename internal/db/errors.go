@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Reasons of ConflictReason.
+const (
+	ConflictSerialization = "serialization_failure"
+	ConflictDeadlock      = "deadlock"
+	ConflictLockTimeout   = "lock_timeout"
+)
+
+// conflictCodes maps the PostgreSQL error codes of statements aborted because of a
+// concurrent transaction to their reason.
+var conflictCodes = map[string]string{
+	"40001": ConflictSerialization,
+	"40P01": ConflictDeadlock,
+	"55P03": ConflictLockTimeout,
+}
+
+// TimeoutStatement is the timeout of statements running longer than the statement_timeout.
+const TimeoutStatement = "statement_timeout"
+
+// queryCanceled is the PostgreSQL error code of statements cancelled by the
+// statement_timeout or a cancel request.
+const queryCanceled = "57014"
+
+// IsStatementTimeout reports whether err is the error of a statement cancelled by
+// the statement_timeout of its transaction, see Timeouts.
+func IsStatementTimeout(err error) bool {
+	var pgErr *pgconn.PgError
+	// requests cancelled because their context is done fail with the context error instead
+	return errors.As(err, &pgErr) && pgErr.Code == queryCanceled &&
+		!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// ConflictReason returns why the database aborted the statement failing with err
+// because of a concurrent transaction, or "" if it did not. The aborted transaction
+// can be retried.
+func ConflictReason(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return conflictCodes[pgErr.Code]
+	}
+	return ""
+}
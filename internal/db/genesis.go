@@ -0,0 +1,23 @@
+package db
+
+import (
+	"time"
+)
+
+// GenesisID is the primary key of the only Genesis row.
+const GenesisID = 1
+
+// Genesis records the genesis file the database was seeded from. The table holds a single row.
+type Genesis struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	// Hash is the keccak256 hash of the canonical form of the genesis file.
+	Hash     string `gorm:"size:66"`
+	Name     string
+	Symbol   string
+	Decimals uint8
+}
+
+func (Genesis) TableName() string {
+	return "genesis"
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"encoding/binary"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gorm.io/gorm"
+)
+
+type LedgerEntryKind string
+
+const (
+	// LedgerEntryMint creates tokens, it credits ToAddress and has no sender.
+	LedgerEntryMint LedgerEntryKind = "mint"
+	// LedgerEntryTransfer moves tokens from FromAddress to ToAddress.
+	LedgerEntryTransfer LedgerEntryKind = "transfer"
+	// LedgerEntryFee moves the fee of a transfer from FromAddress to the fee collector.
+	LedgerEntryFee LedgerEntryKind = "fee"
+	// LedgerEntryBurn destroys tokens, it debits FromAddress and has no receiver.
+	LedgerEntryBurn LedgerEntryKind = "burn"
+)
+
+// GenesisHash is the previous hash of the first ledger entry.
+var GenesisHash = common.Hash{}.Hex()
+
+// appendBatchSize is the number of entries inserted per statement.
+const appendBatchSize = 1000
+
+// ledgerLockKey identifies the advisory lock serializing appends to the ledger.
+const ledgerLockKey = 0x6c6564676572 // "ledger"
+
+// LedgerEntry is a balance movement. Entries form a hash chain: every entry stores
+// the hash of the previous one and its own hash covers its contents and PrevHash,
+// so editing, removing or reordering entries after the fact is detectable.
+type LedgerEntry struct {
+	ID          uint64          `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt   time.Time       `gorm:"index"`
+	Kind        LedgerEntryKind `gorm:"size:16"`
+	FromAddress address.Address `gorm:"type:string;size:42;index"`
+	ToAddress   address.Address `gorm:"type:string;size:42;index"`
+	Amount      decimal.Decimal `gorm:"type:numeric(78,0)"`
+	PrevHash    string          `gorm:"size:66"`
+	Hash        string          `gorm:"size:66;uniqueIndex"`
+}
+
+// ComputeHash returns the keccak256 hash of the entry contents and PrevHash.
+func (e *LedgerEntry) ComputeHash() string {
+	id := binary.BigEndian.AppendUint64(nil, e.ID)
+	createdAt := binary.BigEndian.AppendUint64(nil, uint64(e.CreatedAt.UnixMicro()))
+
+	return common.BytesToHash(crypto.Keccak256(
+		common.FromHex(e.PrevHash),
+		id,
+		createdAt,
+		[]byte(e.Kind),
+		[]byte{0},
+		e.FromAddress[:],
+		e.ToAddress[:],
+		[]byte(e.Amount.String()),
+	)).Hex()
+}
+
+// AppendLedgerEntries links the entries to the end of the chain and inserts them.
+// Appends are serialized with a transaction scoped advisory lock, see AdvisoryLock, which is held
+// until tx ends. Every transaction appending to the ledger waits for it, whichever accounts it
+// locks, so appending should be the last statement before committing.
+func AppendLedgerEntries(tx *gorm.DB, entries ...*LedgerEntry) error {
+	err := AdvisoryLock(tx, ledgerLockKey)
+	if err != nil {
+		return err
+	}
+
+	var last []LedgerEntry
+	err = tx.Order("id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return err
+	}
+
+	prevHash, nextID := GenesisHash, uint64(1)
+	if len(last) > 0 {
+		prevHash, nextID = last[0].Hash, last[0].ID+1
+	}
+
+	// timestamps are stored with microsecond precision, truncate them
+	// so the hash of a stored entry can be recomputed
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for _, entry := range entries {
+		entry.ID = nextID
+		entry.CreatedAt = now
+		entry.PrevHash = prevHash
+		entry.Hash = entry.ComputeHash()
+		prevHash, nextID = entry.Hash, nextID+1
+	}
+
+	return tx.CreateInBatches(entries, appendBatchSize).Error
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxRetryBackoff bounds the delay between connection attempts, see Options.
+const maxRetryBackoff = 10 * time.Second
+
+// Options configure how ConnectDb and ConnectReplicas connect.
+type Options struct {
+	// Pool configures the connection pool.
+	Pool Pool
+	// RetryTimeout is how long failed connection attempts and migrations are
+	// retried. Zero disables retries.
+	RetryTimeout time.Duration
+	// RetryBackoff is the delay before the first retry, it doubles with every retry
+	// up to 10s. Zero disables retries.
+	RetryBackoff time.Duration
+}
+
+// Pool configures the database/sql connection pool. Zero values keep its defaults.
+type Pool struct {
+	// MaxOpenConns bounds the number of open connections, zero leaves it unbounded.
+	MaxOpenConns int
+	// MaxIdleConns bounds the number of idle connections kept open. Zero keeps the
+	// default of 2, a negative value closes connections once they are idle.
+	MaxIdleConns int
+	// ConnMaxLifetime is how long a connection may be reused, zero reuses it forever.
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime is how long a connection may be idle, zero keeps it forever.
+	ConnMaxIdleTime time.Duration
+}
+
+// apply configures the pool of db.
+func (p Pool) apply(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	sqlDB.SetMaxOpenConns(p.MaxOpenConns)
+	if p.MaxIdleConns != 0 {
+		sqlDB.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(p.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	return nil
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// ReadYourWritesHeader pins a request to the primary when set to true or to an unexpired
+// read-your-writes token. Responses to writing requests return the token in it, see
+// ReadYourWrites.
+const ReadYourWritesHeader = "X-Read-Your-Writes"
+
+// ReadYourWritesCookie carries the read-your-writes token for clients keeping cookies.
+const ReadYourWritesCookie = "read_your_writes"
+
+type primaryKey struct{}
+
+type writesKey struct{}
+
+// ConnectReplicas returns the read replicas of DATABASE_REPLICA_URLS, a comma
+// separated list of connection strings in the format of DATABASE_URL, or nil if
+// there are none. Reads are spread randomly over the replicas, transactions run
+// on the first one. The replicas are read-only, migrations are left to the primary.
+// Connecting is retried and the pools are configured as set by opts.
+func ConnectReplicas(ctx context.Context, opts Options) (*gorm.DB, error) {
+	var dsns []string
+	for _, dsn := range strings.Split(os.Getenv("DATABASE_REPLICA_URLS"), ",") {
+		dsn = strings.TrimSpace(dsn)
+		if dsn != "" {
+			dsns = append(dsns, dsn)
+		}
+	}
+	if len(dsns) == 0 {
+		return nil, nil
+	}
+
+	return connect(ctx, "replicas", opts, func() (*gorm.DB, error) {
+		replicas, err := open(dsns[0])
+		if err != nil {
+			return nil, err
+		}
+
+		dialectors := make([]gorm.Dialector, 0, len(dsns))
+		for _, dsn := range dsns {
+			dialectors = append(dialectors, dialector(dsn))
+		}
+		resolver := dbresolver.Register(dbresolver.Config{Replicas: dialectors, Policy: dbresolver.RandomPolicy{}}).
+			SetMaxOpenConns(opts.Pool.MaxOpenConns).
+			SetConnMaxLifetime(opts.Pool.ConnMaxLifetime).
+			SetConnMaxIdleTime(opts.Pool.ConnMaxIdleTime)
+		if opts.Pool.MaxIdleConns != 0 {
+			resolver.SetMaxIdleConns(opts.Pool.MaxIdleConns)
+		}
+		err = replicas.Use(resolver)
+		if err != nil {
+			err2 := CloseDb(replicas)
+			if err2 != nil {
+				return nil, fmt.Errorf("registering the replicas failed (%w), and failed to close database connection (%w)", err, err2)
+			}
+			return nil, err
+		}
+		return replicas, nil
+	})
+}
+
+// WithPrimary returns a copy of ctx whose reads are served by the primary, see Reader.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// Reader returns the connection serving the reads made with ctx: replicas, unless
+// it is nil or ctx is pinned to the primary with WithPrimary.
+func Reader(ctx context.Context, primary, replicas *gorm.DB) *gorm.DB {
+	pinned, _ := ctx.Value(primaryKey{}).(bool)
+	if replicas == nil || pinned {
+		return primary.WithContext(ctx)
+	}
+	return replicas.WithContext(ctx)
+}
+
+// Wrote marks the request of ctx as having written to the primary, so that ReadYourWrites
+// issues a read-your-writes token with its response. Outside of ReadYourWrites it does nothing.
+func Wrote(ctx context.Context) {
+	wrote, _ := ctx.Value(writesKey{}).(*atomic.Bool)
+	if wrote != nil {
+		wrote.Store(true)
+	}
+}
+
+// ReadYourWrites pins a request to the primary if ReadYourWritesHeader is set to true or
+// the header or ReadYourWritesCookie hold an unexpired read-your-writes token. Replicas lag
+// behind the primary, so the reads following a write are served by the primary for window:
+// the responses of requests marked with Wrote return a token in both, which expires after
+// window. A zero window issues no tokens.
+func ReadYourWrites(window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if pinned(r, time.Now()) {
+				r = r.WithContext(WithPrimary(r.Context()))
+			}
+			if window <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tw := &tokenWriter{ResponseWriter: w, window: window}
+			r = r.WithContext(context.WithValue(r.Context(), writesKey{}, &tw.wrote))
+			next.ServeHTTP(tw, r)
+		})
+	}
+}
+
+// pinned reports whether r asks to be served by the primary at now.
+func pinned(r *http.Request, now time.Time) bool {
+	header := r.Header.Get(ReadYourWritesHeader)
+	if pin, err := strconv.ParseBool(header); err == nil {
+		return pin
+	}
+	if unexpired(header, now) {
+		return true
+	}
+
+	cookie, err := r.Cookie(ReadYourWritesCookie)
+	return err == nil && unexpired(cookie.Value, now)
+}
+
+// unexpired reports whether token is a read-your-writes token expiring after now.
+// Tokens are the Unix time in milliseconds they expire at.
+func unexpired(token string, now time.Time) bool {
+	until, err := strconv.ParseInt(token, 10, 64)
+	return err == nil && until > now.UnixMilli()
+}
+
+// tokenWriter adds a read-your-writes token to the response if the request wrote to
+// the primary by the time the response header is written.
+type tokenWriter struct {
+	http.ResponseWriter
+	window      time.Duration
+	wrote       atomic.Bool
+	wroteHeader bool
+}
+
+func (w *tokenWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if w.wrote.Load() {
+			until := time.Now().Add(w.window)
+			token := strconv.FormatInt(until.UnixMilli(), 10)
+			w.Header().Set(ReadYourWritesHeader, token)
+			http.SetCookie(w, &http.Cookie{
+				Name:     ReadYourWritesCookie,
+				Value:    token,
+				Path:     "/",
+				Expires:  until,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *tokenWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped writer for http.ResponseController.
+func (w *tokenWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
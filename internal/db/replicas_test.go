@@ -0,0 +1,63 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// servePinned serves req with ReadYourWrites, marking it as writing if write is set,
+// and returns the response and whether the request was pinned to the primary.
+func servePinned(t *testing.T, req *http.Request, write bool) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	var primary bool
+	handler := ReadYourWrites(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		primary, _ = r.Context().Value(primaryKey{}).(bool)
+		if write {
+			Wrote(r.Context())
+		}
+		_, err := w.Write([]byte("ok"))
+		require.NoError(t, err)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, primary
+}
+
+func TestReadYourWrites_PinsAfterWrite(t *testing.T) {
+	write, writePinned := servePinned(t, httptest.NewRequest(http.MethodPost, "/query", nil), true)
+	token := write.Header().Get(ReadYourWritesHeader)
+	cookies := write.Result().Cookies()
+
+	withHeader := httptest.NewRequest(http.MethodPost, "/query", nil)
+	withHeader.Header.Set(ReadYourWritesHeader, token)
+	_, headerPinned := servePinned(t, withHeader, false)
+
+	withCookie := httptest.NewRequest(http.MethodPost, "/query", nil)
+	require.Len(t, cookies, 1)
+	withCookie.AddCookie(cookies[0])
+	read, cookiePinned := servePinned(t, withCookie, false)
+
+	_, otherPinned := servePinned(t, httptest.NewRequest(http.MethodPost, "/query", nil), false)
+
+	assert.False(t, writePinned)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, ReadYourWritesCookie, cookies[0].Name)
+	assert.True(t, headerPinned)
+	assert.True(t, cookiePinned)
+	assert.Empty(t, read.Header().Get(ReadYourWritesHeader))
+	assert.False(t, otherPinned)
+}
+
+func TestReadYourWrites_ExpiredToken(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/query", nil)
+	req.Header.Set(ReadYourWritesHeader, strconv.FormatInt(time.Now().Add(-time.Second).UnixMilli(), 10))
+
+	_, pinned := servePinned(t, req, false)
+
+	assert.False(t, pinned)
+}
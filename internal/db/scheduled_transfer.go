@@ -0,0 +1,43 @@
+package db
+
+import (
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+)
+
+type ScheduledTransferStatus string
+
+const (
+	ScheduledTransferActive    ScheduledTransferStatus = "ACTIVE"
+	ScheduledTransferCompleted ScheduledTransferStatus = "COMPLETED"
+	ScheduledTransferCancelled ScheduledTransferStatus = "CANCELLED"
+)
+
+// ScheduledTransfer is a transfer that should be executed by the background
+// worker at NextRunAt. Recurring transfers have a cron Recurrence and are
+// rescheduled after every run, one-off transfers are completed after their only run.
+type ScheduledTransfer struct {
+	ID          uint64          `gorm:"primaryKey"`
+	FromAddress address.Address `gorm:"type:string;size:42;index"`
+	ToAddress   address.Address `gorm:"type:string;size:42"`
+	Amount      decimal.Decimal `gorm:"type:numeric(78,0)"`
+	ExecuteAt   time.Time
+	Recurrence  string
+	NextRunAt   time.Time               `gorm:"index"`
+	Status      ScheduledTransferStatus `gorm:"size:16;index"`
+	CreatedAt   time.Time
+	Runs        []ScheduledTransferRun `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// ScheduledTransferRun records the outcome of a single execution of a ScheduledTransfer.
+type ScheduledTransferRun struct {
+	ID                  uint64 `gorm:"primaryKey"`
+	ScheduledTransferID uint64 `gorm:"index"`
+	ScheduledFor        time.Time
+	ExecutedAt          time.Time
+	Succeeded           bool
+	Error               string
+	// Balance is the balance of the sender after a successful run.
+	Balance *decimal.Decimal `gorm:"type:numeric(78,0)"`
+}
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+)
+
+// Snapshot is a consistent view of all non-zero balances, committed to by the
+// Merkle root of its balances.
+type Snapshot struct {
+	ID        uint64 `gorm:"primaryKey"`
+	CreatedAt time.Time
+	CreatedBy string
+	// LedgerEntryID is the last ledger entry included in the snapshot.
+	LedgerEntryID uint64
+	Root          string `gorm:"size:66"`
+	Accounts      int
+	Total         decimal.Decimal `gorm:"type:numeric(78,0)"`
+}
+
+// SnapshotBalance is a balance included in a snapshot.
+type SnapshotBalance struct {
+	SnapshotID uint64          `gorm:"primaryKey;autoIncrement:false"`
+	Address    address.Address `gorm:"primaryKey;type:string;size:42"`
+	Amount     decimal.Decimal `gorm:"type:numeric(78,0)"`
+}
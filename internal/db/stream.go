@@ -0,0 +1,34 @@
+package db
+
+import (
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+)
+
+type StreamStatus string
+
+const (
+	StreamActive    StreamStatus = "ACTIVE"
+	StreamCancelled StreamStatus = "CANCELLED"
+	// StreamCompleted streams have stopped and their whole deposit was withdrawn.
+	StreamCompleted StreamStatus = "COMPLETED"
+)
+
+// Stream is a payment from Sender to Recipient accruing RatePerSecond tokens
+// every second between Start and Stop. The whole Deposit is held by the stream
+// escrow account from creation, the streamed part can be withdrawn by the recipient
+// at any time and cancelling the stream refunds the not yet streamed part to the sender.
+type Stream struct {
+	ID            uint64          `gorm:"primaryKey"`
+	Sender        address.Address `gorm:"type:string;size:42;index"`
+	Recipient     address.Address `gorm:"type:string;size:42;index"`
+	RatePerSecond decimal.Decimal `gorm:"type:numeric(78,0)"`
+	Deposit       decimal.Decimal `gorm:"type:numeric(78,0)"`
+	Withdrawn     decimal.Decimal `gorm:"type:numeric(78,0)"`
+	Start         time.Time
+	Stop          time.Time
+	Status        StreamStatus `gorm:"size:16;index"`
+	CancelledAt   *time.Time
+	CreatedAt     time.Time
+}
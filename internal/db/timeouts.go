@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Timeouts bound how long the statements of a transaction may wait for locks and
+// run, so that a stuck lock cannot hold a request forever. Zero values disable them.
+type Timeouts struct {
+	// Lock is the lock_timeout of the transaction.
+	Lock time.Duration
+	// Statement is the statement_timeout of the transaction.
+	Statement time.Duration
+}
+
+// Set applies the timeouts to the transaction with SET LOCAL, they end with it.
+// SQLite has no such settings, its transactions wait for the write lock up to the
+// busy timeout of the connection.
+func (t Timeouts) Set(tx *gorm.DB) error {
+	if IsSQLite(tx) {
+		return nil
+	}
+
+	if t.Lock > 0 {
+		err := tx.Exec(fmt.Sprintf("SET LOCAL lock_timeout = %d", milliseconds(t.Lock))).Error
+		if err != nil {
+			return err
+		}
+	}
+	if t.Statement > 0 {
+		err := tx.Exec(fmt.Sprintf("SET LOCAL statement_timeout = %d", milliseconds(t.Statement))).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// milliseconds returns d in whole milliseconds, rounded up. Postgres takes the timeouts
+// in milliseconds and zero disables them, so a sub-millisecond timeout must not round to zero.
+func milliseconds(d time.Duration) int64 {
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// Begin begins a transaction bound to ctx, whose queries are cancelled with it,
+// and applies the timeouts to it.
+func Begin(ctx context.Context, conn *gorm.DB, timeouts Timeouts) (*gorm.DB, error) {
+	tx := conn.WithContext(ctx).Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	err := timeouts.Set(tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
@@ -0,0 +1,14 @@
+package db
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMilliseconds_RoundsUp(t *testing.T) {
+	assert.Equal(t, int64(1), milliseconds(500*time.Microsecond))
+	assert.Equal(t, int64(1), milliseconds(time.Millisecond))
+	assert.Equal(t, int64(2), milliseconds(1500*time.Microsecond))
+	assert.Equal(t, int64(5000), milliseconds(5*time.Second))
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+)
+
+// Vesting is a schedule releasing Total tokens to Beneficiary.
+// Nothing is vested before the cliff (Start + CliffSeconds), afterwards tokens vest
+// linearly until Start + DurationSeconds when the whole Total is vested.
+// Tokens are held by the vesting escrow account until they are released.
+type Vesting struct {
+	ID              uint64          `gorm:"primaryKey"`
+	Beneficiary     address.Address `gorm:"type:string;size:42;index"`
+	Total           decimal.Decimal `gorm:"type:numeric(78,0)"`
+	Released        decimal.Decimal `gorm:"type:numeric(78,0)"`
+	Start           time.Time
+	CliffSeconds    int64
+	DurationSeconds int64
+	CreatedAt       time.Time
+}
@@ -1,12 +1,17 @@
 package decimal
 
 import (
+	"database/sql/driver"
 	"fmt"
 	dec "github.com/shopspring/decimal"
 	"io"
+	"math/big"
 	"reflect"
 	"strconv"
 	"token-transfer-api/internal/errors/egeneric"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
 )
 
 type Decimal dec.Decimal
@@ -22,6 +27,16 @@ func (Decimal) GormDataType() string {
 	return fmt.Sprintf("numeric(%d,%d)", NumericPrecision, NumericScale)
 }
 
+// GormDBDataType stores decimals as text in SQLite, whose numeric columns hold at
+// most 64-bit integers or doubles. The canonical strings written by Value are scanned
+// back exactly. Other databases use the column type of the field.
+func (Decimal) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if db.Dialector.Name() == "sqlite" {
+		return "text"
+	}
+	return ""
+}
+
 func (d Decimal) String() string {
 	return (dec.Decimal(d)).String()
 }
@@ -32,6 +47,18 @@ func (d Decimal) Int64() int64 {
 	return (dec.Decimal(d)).BigInt().Int64()
 }
 
+// BigInt returns the integer part of the decimal.
+func (d Decimal) BigInt() *big.Int {
+	return (dec.Decimal(d)).BigInt()
+}
+
+// Float64 returns the nearest float64 to the decimal, e.g. for reporting metrics.
+// It must not be used for arithmetic.
+func (d Decimal) Float64() float64 {
+	f, _ := (dec.Decimal(d)).Float64()
+	return f
+}
+
 func NewFromString(s string) (Decimal, error) {
 	d, err := dec.NewFromString(s)
 	if err != nil {
@@ -93,6 +120,18 @@ func (d Decimal) Sub(o Decimal) Decimal {
 	return Decimal((dec.Decimal(d)).Sub(dec.Decimal(o)))
 }
 
+func (d Decimal) Mul(o Decimal) Decimal {
+	return Decimal((dec.Decimal(d)).Mul(dec.Decimal(o)))
+}
+
+// QuoInt returns the integer part of d / o, truncated towards zero.
+// Unlike a regular division it never rounds, so it is exact for integer arithmetic.
+// Panics if o is zero.
+func (d Decimal) QuoInt(o Decimal) Decimal {
+	q, _ := (dec.Decimal(d)).QuoRem(dec.Decimal(o), 0)
+	return Decimal(q)
+}
+
 // MarshalGQL implements the graphql.Marshaler interface (used by gqlgen).
 // It writes the string representation of the Decimal to the GraphQL response.
 func (d Decimal) MarshalGQL(w io.Writer) {
@@ -132,9 +171,28 @@ func (d *Decimal) UnmarshalGQL(v interface{}) error {
 	return nil
 }
 
+// MarshalJSON implements the json.Marshaler interface.
+// The Decimal is written as a quoted string, so it does not lose precision.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return dec.Decimal(d).MarshalJSON()
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// It accepts both quoted and unquoted numbers.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	temp := new(dec.Decimal)
+	err := temp.UnmarshalJSON(data)
+	if err != nil {
+		return err
+	}
+
+	*d = Decimal(*temp)
+	return nil
+}
+
 // Value implements the database/sql.Valuer interface for database storage.
 // It delegates to the underlying shopspring/decimal.Decimal's Value method.
-func (d Decimal) Value() (interface{}, error) {
+func (d Decimal) Value() (driver.Value, error) {
 	return dec.Decimal(d).Value()
 }
 
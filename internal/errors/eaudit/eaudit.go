@@ -0,0 +1,26 @@
+package eaudit
+
+import (
+	"errors"
+	"fmt"
+)
+
+var AuditRecordError = errors.New("failed to record audit event")
+var AuditRetrievalError = errors.New("failed to retrieve audit events")
+
+type InvalidPageSizeError struct {
+	First int
+	Max   int
+}
+
+func (e InvalidPageSizeError) Error() string {
+	return fmt.Sprintf("invalid page size %d: must be between 1 and %d", e.First, e.Max)
+}
+
+type InvalidCursorError struct {
+	Cursor string
+}
+
+func (e InvalidCursorError) Error() string {
+	return fmt.Sprintf("invalid cursor: %s", e.Cursor)
+}
@@ -0,0 +1,7 @@
+package eauth
+
+import "errors"
+
+var UnauthorizedError = errors.New("operation requires an admin token")
+var InvalidTokenError = errors.New("invalid admin token")
+var InvalidAdminTokensError = errors.New("admin tokens must be comma separated operator:token pairs")
@@ -0,0 +1,17 @@
+package econfig
+
+import "fmt"
+
+type InvalidValueError struct {
+	Key   string
+	Value string
+	Err   error
+}
+
+func (e InvalidValueError) Error() string {
+	return fmt.Sprintf("invalid value %q for %s: %v", e.Value, e.Key, e.Err)
+}
+
+func (e InvalidValueError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,21 @@
+package econtrols
+
+import (
+	"errors"
+	"fmt"
+	"token-transfer-api/internal/address"
+)
+
+var FreezeUpdateError = errors.New("failed to update frozen accounts")
+var FreezeRetrievalError = errors.New("failed to retrieve frozen accounts")
+var PauseUpdateError = errors.New("failed to update pause state")
+var PauseRetrievalError = errors.New("failed to retrieve pause state")
+var EmptyReasonError = errors.New("reason must not be empty")
+
+type NotFrozenError struct {
+	Address address.Address
+}
+
+func (e NotFrozenError) Error() string {
+	return fmt.Sprintf("account is not frozen: %s", e.Address.Hex())
+}
@@ -0,0 +1,26 @@
+package eexport
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ExportError = errors.New("failed to export rows")
+var TimeRangeNotSupportedError = errors.New("accounts cannot be filtered by time, use balancesAt for historical balances")
+
+type InvalidFormatError struct {
+	Format string
+}
+
+func (e InvalidFormatError) Error() string {
+	return fmt.Sprintf("invalid export format %q: must be csv or jsonl", e.Format)
+}
+
+type InvalidParameterError struct {
+	Name  string
+	Value string
+}
+
+func (e InvalidParameterError) Error() string {
+	return fmt.Sprintf("invalid value %q for parameter %s", e.Value, e.Name)
+}
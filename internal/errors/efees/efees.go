@@ -0,0 +1,32 @@
+package efees
+
+import (
+	"errors"
+	"fmt"
+	"token-transfer-api/internal/decimal"
+)
+
+var CollectorNotConfiguredError = errors.New("fee collector address must be set when transfer fees are configured")
+var NegativeFeeError = errors.New("fee amounts must be non-negative integers")
+var InvalidBasisPointsError = errors.New("basis points must be between 0 and 10000")
+var MinExceedsMaxError = errors.New("minimum fee must not exceed the maximum fee")
+var EmptyTiersError = errors.New("tiered fee needs at least one tier")
+var UnboundedTierError = errors.New("only the last fee tier may omit up_to")
+var UnsortedTiersError = errors.New("fee tiers must be sorted by up_to")
+
+type UnknownFeeTypeError struct {
+	Type string
+}
+
+func (e UnknownFeeTypeError) Error() string {
+	return fmt.Sprintf("unknown fee type: %q", e.Type)
+}
+
+type FeeExceedsAmountError struct {
+	Amount decimal.Decimal
+	Fee    decimal.Decimal
+}
+
+func (e FeeExceedsAmountError) Error() string {
+	return fmt.Sprintf("fee %s exceeds transfer amount %s", e.Fee, e.Amount)
+}
@@ -0,0 +1,87 @@
+package egenesis
+
+import (
+	"errors"
+	"fmt"
+)
+
+var GenesisApplyError = errors.New("failed to apply genesis")
+var GenesisRetrievalError = errors.New("failed to retrieve genesis")
+var EmptyGenesisError = errors.New("genesis has no allocations")
+var NotSeededError = errors.New("database has not been seeded from a genesis")
+
+type GenesisReadError struct {
+	Path string
+	Err  error
+}
+
+func (e GenesisReadError) Error() string {
+	return fmt.Sprintf("failed to read genesis file %s: %v", e.Path, e.Err)
+}
+
+func (e GenesisReadError) Unwrap() error {
+	return e.Err
+}
+
+type InvalidTokenError struct {
+	Field  string
+	Reason string
+}
+
+func (e InvalidTokenError) Error() string {
+	return fmt.Sprintf("invalid token %s: %s", e.Field, e.Reason)
+}
+
+type InvalidAddressError struct {
+	Index   int
+	Address string
+}
+
+func (e InvalidAddressError) Error() string {
+	return fmt.Sprintf("allocation %d: invalid address %q", e.Index, e.Address)
+}
+
+type ChecksumMismatchError struct {
+	Index    int
+	Address  string
+	Expected string
+}
+
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("allocation %d: address %s is not checksummed, expected %s", e.Index, e.Address, e.Expected)
+}
+
+type DuplicateAddressError struct {
+	Index   int
+	Address string
+}
+
+func (e DuplicateAddressError) Error() string {
+	return fmt.Sprintf("allocation %d: duplicate address %s", e.Index, e.Address)
+}
+
+type InvalidAmountError struct {
+	Index  int
+	Amount string
+}
+
+func (e InvalidAmountError) Error() string {
+	return fmt.Sprintf("allocation %d: invalid amount %q, must be a non-negative integer", e.Index, e.Amount)
+}
+
+type TotalOverflowError struct {
+	Total string
+}
+
+func (e TotalOverflowError) Error() string {
+	return fmt.Sprintf("total allocation %s exceeds the maximum uint256", e.Total)
+}
+
+type GenesisMismatchError struct {
+	Stored string
+	Given  string
+}
+
+func (e GenesisMismatchError) Error() string {
+	return fmt.Sprintf("database was seeded from genesis %s, refusing to start with genesis %s", e.Stored, e.Given)
+}
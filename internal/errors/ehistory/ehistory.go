@@ -0,0 +1,18 @@
+package ehistory
+
+import (
+	"errors"
+	"fmt"
+)
+
+var CheckpointError = errors.New("failed to create balance checkpoint")
+var HistoryRetrievalError = errors.New("failed to retrieve balance history")
+var ConflictingPositionError = errors.New("at and ledger id are mutually exclusive")
+
+type PositionNotFoundError struct {
+	LedgerID uint64
+}
+
+func (e PositionNotFoundError) Error() string {
+	return fmt.Sprintf("ledger entry not found: %d", e.LedgerID)
+}
@@ -0,0 +1,55 @@
+package eimport
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ImportError = errors.New("failed to import balances")
+var EmptyImportError = errors.New("there are no balances to import")
+
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+type ColumnCountError struct {
+	Columns int
+}
+
+func (e ColumnCountError) Error() string {
+	return fmt.Sprintf("expected 2 columns (address, amount), got %d", e.Columns)
+}
+
+type InvalidAddressError struct {
+	Address string
+}
+
+func (e InvalidAddressError) Error() string {
+	return fmt.Sprintf("invalid address %q", e.Address)
+}
+
+type InvalidAmountError struct {
+	Amount string
+}
+
+func (e InvalidAmountError) Error() string {
+	return fmt.Sprintf("invalid amount %q, must be a non-negative integer", e.Amount)
+}
+
+type DuplicateAddressError struct {
+	Address   string
+	FirstLine int
+}
+
+func (e DuplicateAddressError) Error() string {
+	return fmt.Sprintf("duplicate address %s, first listed on line %d", e.Address, e.FirstLine)
+}
@@ -0,0 +1,48 @@
+package eledger
+
+import (
+	"errors"
+	"fmt"
+)
+
+var LedgerAppendError = errors.New("failed to append ledger entry")
+var LedgerRetrievalError = errors.New("failed to retrieve ledger entries")
+
+type InvalidRangeError struct {
+	From uint64
+	To   uint64
+}
+
+func (e InvalidRangeError) Error() string {
+	return fmt.Sprintf("invalid ledger range: from %d is after to %d", e.From, e.To)
+}
+
+// ConflictError is returned if the database aborted the transaction because of a
+// concurrent transaction, e.g. on a deadlock. The transaction can be retried.
+type ConflictError struct {
+	Reason string
+	Err    error
+}
+
+func (e ConflictError) Error() string {
+	return fmt.Sprintf("transaction aborted by a concurrent transaction: %s", e.Reason)
+}
+
+func (e ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// TimeoutError is returned if a statement of the transaction timed out waiting for a
+// lock or running, see db.Timeouts. Timeout is "lock_timeout" or "statement_timeout".
+type TimeoutError struct {
+	Timeout string
+	Err     error
+}
+
+func (e TimeoutError) Error() string {
+	return fmt.Sprintf("transaction timed out: %s exceeded", e.Timeout)
+}
+
+func (e TimeoutError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,17 @@
+package emerkle
+
+import (
+	"errors"
+	"fmt"
+	"token-transfer-api/internal/decimal"
+)
+
+var EmptyTreeError = errors.New("merkle tree must have at least one leaf")
+
+type InvalidAmountError struct {
+	Amount decimal.Decimal
+}
+
+func (e InvalidAmountError) Error() string {
+	return fmt.Sprintf("amount is not a uint256: %s", e.Amount)
+}
@@ -0,0 +1,54 @@
+package erebuild
+
+import (
+	"errors"
+	"fmt"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+)
+
+var RebuildError = errors.New("failed to rebuild balances")
+var BoundedSwapError = errors.New("only a replay of the whole ledger can replace the accounts table")
+var ConflictingBoundsError = errors.New("as-of and ledger id are mutually exclusive")
+
+type NoEntriesBeforeError struct {
+	AsOf string
+}
+
+func (e NoEntriesBeforeError) Error() string {
+	return fmt.Sprintf("no ledger entries before %s", e.AsOf)
+}
+
+type InvalidTableError struct {
+	Table string
+}
+
+func (e InvalidTableError) Error() string {
+	return fmt.Sprintf("invalid side table name: %s", e.Table)
+}
+
+type TableExistsError struct {
+	Table string
+}
+
+func (e TableExistsError) Error() string {
+	return fmt.Sprintf("table already exists: %s", e.Table)
+}
+
+type BrokenLedgerError struct {
+	BrokenAt uint64
+	Reason   string
+}
+
+func (e BrokenLedgerError) Error() string {
+	return fmt.Sprintf("ledger is broken at entry %d: %s", e.BrokenAt, e.Reason)
+}
+
+type NegativeBalanceError struct {
+	Address address.Address
+	Amount  decimal.Decimal
+}
+
+func (e NegativeBalanceError) Error() string {
+	return fmt.Sprintf("ledger replay results in negative balance %s of %s", e.Amount, e.Address.Hex())
+}
@@ -0,0 +1,29 @@
+package ereconcile
+
+import (
+	"errors"
+	"fmt"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+)
+
+var ReconciliationError = errors.New("failed to reconcile balances")
+
+type BalanceMismatchError struct {
+	Address  address.Address
+	Stored   decimal.Decimal
+	Expected decimal.Decimal
+}
+
+func (e BalanceMismatchError) Error() string {
+	return fmt.Sprintf("balance of %s is %s, ledger expects %s", e.Address.Hex(), e.Stored, e.Expected)
+}
+
+type SupplyMismatchError struct {
+	Supply decimal.Decimal
+	Total  decimal.Decimal
+}
+
+func (e SupplyMismatchError) Error() string {
+	return fmt.Sprintf("sum of balances %s differs from total supply %s", e.Total, e.Supply)
+}
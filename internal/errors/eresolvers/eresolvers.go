@@ -43,3 +43,29 @@ type AddressAmountUpdateError struct {
 func (e AddressAmountUpdateError) Error() string {
 	return fmt.Sprintf("address amount update error: %s", e.Address.Hex())
 }
+
+type InvalidIDError struct {
+	ID string
+}
+
+func (e InvalidIDError) Error() string {
+	return fmt.Sprintf("invalid id: %s", e.ID)
+}
+
+var TransfersPausedError = errors.New("transfers are paused")
+
+type AccountFrozenError struct {
+	Address address.Address
+}
+
+func (e AccountFrozenError) Error() string {
+	return fmt.Sprintf("account is frozen: %s", e.Address.Hex())
+}
+
+type DatabaseRequiredError struct {
+	Field string
+}
+
+func (e DatabaseRequiredError) Error() string {
+	return fmt.Sprintf("%s requires a database, the ledger is kept in memory", e.Field)
+}
@@ -0,0 +1,39 @@
+package escheduler
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ScheduleCreationError = errors.New("failed to create scheduled transfer")
+var ScheduleRetrievalError = errors.New("failed to retrieve scheduled transfer")
+var ScheduleUpdateError = errors.New("failed to update scheduled transfer")
+
+type InvalidRecurrenceError struct {
+	Recurrence string
+	Err        error
+}
+
+func (e InvalidRecurrenceError) Error() string {
+	return fmt.Sprintf("invalid recurrence %q: %v", e.Recurrence, e.Err)
+}
+
+func (e InvalidRecurrenceError) Unwrap() error {
+	return e.Err
+}
+
+type ScheduleNotFoundError struct {
+	ID uint64
+}
+
+func (e ScheduleNotFoundError) Error() string {
+	return fmt.Sprintf("scheduled transfer not found: %d", e.ID)
+}
+
+type ScheduleNotActiveError struct {
+	ID uint64
+}
+
+func (e ScheduleNotActiveError) Error() string {
+	return fmt.Sprintf("scheduled transfer is not active: %d", e.ID)
+}
@@ -0,0 +1,18 @@
+package esnapshot
+
+import (
+	"errors"
+	"fmt"
+)
+
+var SnapshotCreationError = errors.New("failed to create snapshot")
+var SnapshotRetrievalError = errors.New("failed to retrieve snapshot")
+var EmptySnapshotError = errors.New("there are no balances to snapshot")
+
+type SnapshotNotFoundError struct {
+	ID uint64
+}
+
+func (e SnapshotNotFoundError) Error() string {
+	return fmt.Sprintf("snapshot not found: %d", e.ID)
+}
@@ -0,0 +1,39 @@
+package estream
+
+import (
+	"errors"
+	"fmt"
+	"token-transfer-api/internal/decimal"
+)
+
+var NonPositiveRateError = errors.New("stream rate must be positive")
+var InvalidTimeRangeError = errors.New("stream stop must be after start")
+var SameSenderAndRecipientError = errors.New("stream sender and recipient must differ")
+var StreamCreationError = errors.New("failed to create stream")
+var StreamRetrievalError = errors.New("failed to retrieve stream")
+var StreamUpdateError = errors.New("failed to update stream")
+var NothingToWithdrawError = errors.New("nothing to withdraw from stream")
+
+type StreamNotFoundError struct {
+	ID uint64
+}
+
+func (e StreamNotFoundError) Error() string {
+	return fmt.Sprintf("stream not found: %d", e.ID)
+}
+
+type StreamNotActiveError struct {
+	ID uint64
+}
+
+func (e StreamNotActiveError) Error() string {
+	return fmt.Sprintf("stream is not active: %d", e.ID)
+}
+
+type AmountExceedsWithdrawableError struct {
+	Withdrawable decimal.Decimal
+}
+
+func (e AmountExceedsWithdrawableError) Error() string {
+	return fmt.Sprintf("amount exceeds withdrawable balance of %s", e.Withdrawable)
+}
@@ -0,0 +1,22 @@
+package evesting
+
+import (
+	"errors"
+	"fmt"
+)
+
+var NonPositiveTotalError = errors.New("vesting total must be positive")
+var NonPositiveDurationError = errors.New("vesting duration must be positive")
+var InvalidCliffError = errors.New("vesting cliff must be between 0 and duration")
+var NothingToReleaseError = errors.New("no tokens are due for release")
+var VestingCreationError = errors.New("failed to create vesting")
+var VestingRetrievalError = errors.New("failed to retrieve vesting")
+var VestingUpdateError = errors.New("failed to update vesting")
+
+type VestingNotFoundError struct {
+	ID uint64
+}
+
+func (e VestingNotFoundError) Error() string {
+	return fmt.Sprintf("vesting not found: %d", e.ID)
+}
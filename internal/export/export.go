@@ -0,0 +1,226 @@
+// Package export streams accounts and the transfer history as CSV or JSON Lines.
+package export
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/errors/eexport"
+
+	"gorm.io/gorm"
+)
+
+// Format is the output format of an export.
+type Format string
+
+const (
+	CSV       Format = "csv"
+	JSONLines Format = "jsonl"
+)
+
+// ParseFormat parses an export format, the empty string selects CSV.
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "", CSV:
+		return CSV, nil
+	case JSONLines:
+		return JSONLines, nil
+	default:
+		return "", eexport.InvalidFormatError{Format: s}
+	}
+}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	if f == JSONLines {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
+
+// Filter restricts the exported rows.
+type Filter struct {
+	// Address keeps the account of the address or the entries sent or received by it.
+	Address *address.Address
+	// From and To keep the entries created in [From, To), they cannot be used for accounts.
+	From *time.Time
+	To   *time.Time
+}
+
+// Account is an exported account.
+type Account struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+// Entry is an exported ledger entry.
+type Entry struct {
+	ID          uint64    `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Kind        string    `json:"kind"`
+	FromAddress string    `json:"from_address"`
+	ToAddress   string    `json:"to_address"`
+	Amount      string    `json:"amount"`
+	Hash        string    `json:"hash"`
+}
+
+var (
+	accountHeader = []string{"address", "amount"}
+	entryHeader   = []string{"id", "created_at", "kind", "from_address", "to_address", "amount", "hash"}
+)
+
+// Accounts writes the accounts ordered by address and returns the number of rows written.
+func Accounts(tx *gorm.DB, w io.Writer, format Format, filter Filter) (int, error) {
+	err := checkAccountFilter(filter)
+	if err != nil {
+		return 0, err
+	}
+
+	query := db.Balances(tx).Order("address")
+	if filter.Address != nil {
+		query = query.Where("address = ?", filter.Address.Hex())
+	}
+
+	return stream(query, newEncoder(w, format, accountHeader), func(rows *sql.Rows) (any, []string, error) {
+		var account db.Account
+		err := tx.ScanRows(rows, &account)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		record := Account{Address: account.Address.Hex(), Amount: account.Amount.String()}
+		return record, []string{record.Address, record.Amount}, nil
+	})
+}
+
+func checkAccountFilter(filter Filter) error {
+	if filter.From != nil || filter.To != nil {
+		return eexport.TimeRangeNotSupportedError
+	}
+	return nil
+}
+
+// Transfers writes the ledger entries ordered by id and returns the number of rows written.
+func Transfers(tx *gorm.DB, w io.Writer, format Format, filter Filter) (int, error) {
+	query := tx.Model(&db.LedgerEntry{}).Order("id")
+	if filter.Address != nil {
+		query = query.Where("from_address = ? OR to_address = ?", filter.Address.Hex(), filter.Address.Hex())
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	return stream(query, newEncoder(w, format, entryHeader), func(rows *sql.Rows) (any, []string, error) {
+		var entry db.LedgerEntry
+		err := tx.ScanRows(rows, &entry)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		record := Entry{
+			ID:          entry.ID,
+			CreatedAt:   entry.CreatedAt.UTC(),
+			Kind:        string(entry.Kind),
+			FromAddress: entry.FromAddress.Hex(),
+			ToAddress:   entry.ToAddress.Hex(),
+			Amount:      entry.Amount.String(),
+			Hash:        entry.Hash,
+		}
+		return record, []string{
+			strconv.FormatUint(record.ID, 10),
+			record.CreatedAt.Format(time.RFC3339Nano),
+			record.Kind,
+			record.FromAddress,
+			record.ToAddress,
+			record.Amount,
+			record.Hash,
+		}, nil
+	})
+}
+
+// stream runs query and encodes its rows one at a time. The rows are read from the
+// connection while they are written, so memory use does not grow with the export.
+func stream(query *gorm.DB, enc *encoder, scan func(rows *sql.Rows) (any, []string, error)) (int, error) {
+	rows, err := query.Rows()
+	if err != nil {
+		return 0, eexport.ExportError
+	}
+	defer rows.Close()
+
+	err = enc.header()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for rows.Next() {
+		record, fields, err := scan(rows)
+		if err != nil {
+			return n, eexport.ExportError
+		}
+
+		err = enc.encode(record, fields)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	if rows.Err() != nil {
+		return n, eexport.ExportError
+	}
+
+	return n, enc.flush()
+}
+
+// encoder writes records as CSV rows or JSON lines.
+type encoder struct {
+	format  Format
+	columns []string
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	json    *json.Encoder
+}
+
+func newEncoder(w io.Writer, format Format, columns []string) *encoder {
+	buf := bufio.NewWriter(w)
+	return &encoder{
+		format:  format,
+		columns: columns,
+		buf:     buf,
+		csv:     csv.NewWriter(buf),
+		json:    json.NewEncoder(buf),
+	}
+}
+
+// header writes the CSV header, JSON lines have none.
+func (e *encoder) header() error {
+	if e.format != CSV {
+		return nil
+	}
+	return e.csv.Write(e.columns)
+}
+
+func (e *encoder) encode(record any, fields []string) error {
+	if e.format == CSV {
+		return e.csv.Write(fields)
+	}
+	return e.json.Encode(record)
+}
+
+func (e *encoder) flush() error {
+	e.csv.Flush()
+	err := e.csv.Error()
+	if err != nil {
+		return err
+	}
+	return e.buf.Flush()
+}
@@ -0,0 +1,102 @@
+package export
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/errors/eexport"
+)
+
+func testEncode(t *testing.T, format Format) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	enc := newEncoder(&buf, format, accountHeader)
+	require.NoError(t, enc.header())
+	record := Account{Address: "0x1234567890123456789012345678901234567890", Amount: "100"}
+	require.NoError(t, enc.encode(record, []string{record.Address, record.Amount}))
+	require.NoError(t, enc.flush())
+	return buf.String()
+}
+
+func testRequest(t *testing.T, target string, operator bool) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if operator {
+		req = req.WithContext(auth.WithOperator(req.Context(), "alice"))
+	}
+	rec := httptest.NewRecorder()
+	// requests are rejected before the database is used
+	Handler(nil, nil).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestParseFormat_Default(t *testing.T) {
+	format, err := ParseFormat("")
+
+	require.NoError(t, err)
+	assert.Equal(t, CSV, format)
+}
+
+func TestParseFormat_Invalid(t *testing.T) {
+	_, err := ParseFormat("xml")
+
+	assert.ErrorIs(t, err, eexport.InvalidFormatError{Format: "xml"})
+}
+
+func TestEncoder_CSV(t *testing.T) {
+	out := testEncode(t, CSV)
+
+	assert.Equal(t, "address,amount\n0x1234567890123456789012345678901234567890,100\n", out)
+}
+
+func TestEncoder_JSONLines(t *testing.T) {
+	out := testEncode(t, JSONLines)
+
+	assert.Equal(t, `{"address":"0x1234567890123456789012345678901234567890","amount":"100"}`+"\n", out)
+}
+
+func TestParseQuery_Filter(t *testing.T) {
+	format, filter, err := parseQuery(map[string][]string{
+		"format":  {"jsonl"},
+		"address": {"0x1234567890123456789012345678901234567890"},
+		"from":    {"2025-01-01T00:00:00Z"},
+		"to":      {"2025-02-01T00:00:00Z"},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, JSONLines, format)
+	assert.Equal(t, "0x1234567890123456789012345678901234567890", filter.Address.Hex())
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), *filter.From)
+	assert.Equal(t, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), *filter.To)
+}
+
+func TestParseQuery_InvalidTime(t *testing.T) {
+	_, _, err := parseQuery(map[string][]string{"from": {"yesterday"}})
+
+	assert.ErrorIs(t, err, eexport.InvalidParameterError{Name: "from", Value: "yesterday"})
+}
+
+func TestHandler_RequiresOperator(t *testing.T) {
+	rec := testRequest(t, "/export/accounts", false)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestHandler_InvalidFormat(t *testing.T) {
+	rec := testRequest(t, "/export/transfers?format=xml", true)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestHandler_AccountsTimeRange(t *testing.T) {
+	rec := testRequest(t, "/export/accounts?from=2025-01-01T00:00:00Z", true)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
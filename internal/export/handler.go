@@ -0,0 +1,123 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/errors/eexport"
+
+	"gorm.io/gorm"
+)
+
+// Handler serves the operator-only exports:
+//
+//	GET /export/accounts?format=csv|jsonl&address=0x...
+//	GET /export/transfers?format=csv|jsonl&address=0x...&from=<RFC 3339>&to=<RFC 3339>
+//
+// It must be wrapped by auth.Middleware. The exports are read from replicas if it is
+// not nil, unless the request is pinned to the primary, see db.ReadYourWrites.
+func Handler(dbConnection, replicas *gorm.DB) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /export/accounts", exportHandler(dbConnection, replicas, "accounts", checkAccountFilter, Accounts))
+	mux.Handle("GET /export/transfers", exportHandler(dbConnection, replicas, "transfers", nil, Transfers))
+	return mux
+}
+
+type exportFunc func(tx *gorm.DB, w io.Writer, format Format, filter Filter) (int, error)
+
+// exportHandler serves an export. check validates the filter before the database is queried.
+func exportHandler(dbConnection, replicas *gorm.DB, name string, check func(Filter) error, export exportFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := auth.RequireOperator(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		format, filter, err := parseQuery(r.URL.Query())
+		if err == nil && check != nil {
+			err = check(filter)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", format.ContentType())
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+name+"."+string(format)+"\"")
+		out := &trackingWriter{w: w}
+		_, err = export(db.Reader(r.Context(), dbConnection, replicas), out, format, filter)
+		if err != nil {
+			if !out.written {
+				w.Header().Del("Content-Disposition")
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			// The status has been sent, abort the response so that the client
+			// does not mistake a truncated export for a complete one.
+			if !errors.Is(r.Context().Err(), context.Canceled) {
+				log.Printf("export of %s failed: %v", name, err)
+			}
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+// parseQuery parses the format and filter of an export request.
+func parseQuery(query url.Values) (Format, Filter, error) {
+	format, err := ParseFormat(query.Get("format"))
+	if err != nil {
+		return "", Filter{}, err
+	}
+
+	var filter Filter
+	if s := query.Get("address"); s != "" {
+		var addr address.Address
+		err := addr.UnmarshalGQL(s)
+		if err != nil {
+			return "", Filter{}, eexport.InvalidParameterError{Name: "address", Value: s}
+		}
+		filter.Address = &addr
+	}
+	filter.From, err = parseTime(query, "from")
+	if err != nil {
+		return "", Filter{}, err
+	}
+	filter.To, err = parseTime(query, "to")
+	if err != nil {
+		return "", Filter{}, err
+	}
+
+	return format, filter, nil
+}
+
+func parseTime(query url.Values, name string) (*time.Time, error) {
+	s := query.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, eexport.InvalidParameterError{Name: name, Value: s}
+	}
+	return &t, nil
+}
+
+// trackingWriter records whether anything was written to the response.
+type trackingWriter struct {
+	w       http.ResponseWriter
+	written bool
+}
+
+func (t *trackingWriter) Write(p []byte) (int, error) {
+	t.written = true
+	return t.w.Write(p)
+}
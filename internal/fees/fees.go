@@ -0,0 +1,148 @@
+package fees
+
+import (
+	"encoding/json"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/efees"
+)
+
+// BasisPointsDenominator is the number of basis points in 100%.
+const BasisPointsDenominator = 10_000
+
+// Schedule computes the fee charged for transferring an amount.
+// Fees are whole tokens, every schedule rounds down.
+type Schedule interface {
+	Fee(amount decimal.Decimal) decimal.Decimal
+}
+
+// Flat charges the same fee for every transfer.
+type Flat struct {
+	Amount decimal.Decimal
+}
+
+func (f Flat) Fee(decimal.Decimal) decimal.Decimal {
+	return f.Amount
+}
+
+// Percentage charges BasisPoints / 10000 of the amount, clamped to [Min, Max] when they are set.
+type Percentage struct {
+	BasisPoints int64
+	Min         *decimal.Decimal
+	Max         *decimal.Decimal
+}
+
+func (p Percentage) Fee(amount decimal.Decimal) decimal.Decimal {
+	fee := amount.
+		Mul(decimal.NewFromInt64(p.BasisPoints)).
+		QuoInt(decimal.NewFromInt64(BasisPointsDenominator))
+	if p.Min != nil && fee.LessThan(*p.Min) {
+		fee = *p.Min
+	}
+	if p.Max != nil && fee.GreaterThan(*p.Max) {
+		fee = *p.Max
+	}
+	return fee
+}
+
+// Tier applies Schedule to amounts up to and including UpTo.
+// A nil UpTo matches every amount.
+type Tier struct {
+	UpTo     *decimal.Decimal
+	Schedule Schedule
+}
+
+// Tiered picks the fee schedule of the first tier the amount falls into.
+// Amounts above the last tier are not charged.
+type Tiered struct {
+	Tiers []Tier
+}
+
+func (t Tiered) Fee(amount decimal.Decimal) decimal.Decimal {
+	for _, tier := range t.Tiers {
+		if tier.UpTo == nil || amount.LessThanOrEqual(*tier.UpTo) {
+			return tier.Schedule.Fee(amount)
+		}
+	}
+	return decimal.Zero
+}
+
+// config is the JSON representation of a Schedule, e.g.
+//
+//	{"type": "flat", "amount": "10"}
+//	{"type": "percentage", "bps": 25, "min": "1", "max": "1000"}
+//	{"type": "tiered", "tiers": [{"up_to": "1000", "fee": {"type": "flat", "amount": "1"}}, {"fee": {"type": "percentage", "bps": 10}}]}
+type config struct {
+	Type   string           `json:"type"`
+	Amount *decimal.Decimal `json:"amount"`
+	Bps    int64            `json:"bps"`
+	Min    *decimal.Decimal `json:"min"`
+	Max    *decimal.Decimal `json:"max"`
+	Tiers  []tierConfig     `json:"tiers"`
+}
+
+type tierConfig struct {
+	UpTo *decimal.Decimal `json:"up_to"`
+	Fee  config           `json:"fee"`
+}
+
+// Parse parses a JSON fee schedule.
+func Parse(data []byte) (Schedule, error) {
+	var c config
+	err := json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, err
+	}
+	return c.schedule()
+}
+
+func (c config) schedule() (Schedule, error) {
+	switch c.Type {
+	case "flat":
+		if c.Amount == nil || !validAmount(*c.Amount) {
+			return nil, efees.NegativeFeeError
+		}
+		return Flat{Amount: *c.Amount}, nil
+	case "percentage":
+		if c.Bps < 0 || c.Bps > BasisPointsDenominator {
+			return nil, efees.InvalidBasisPointsError
+		}
+		if (c.Min != nil && !validAmount(*c.Min)) || (c.Max != nil && !validAmount(*c.Max)) {
+			return nil, efees.NegativeFeeError
+		}
+		if c.Min != nil && c.Max != nil && c.Min.GreaterThan(*c.Max) {
+			return nil, efees.MinExceedsMaxError
+		}
+		return Percentage{BasisPoints: c.Bps, Min: c.Min, Max: c.Max}, nil
+	case "tiered":
+		return c.tiered()
+	default:
+		return nil, efees.UnknownFeeTypeError{Type: c.Type}
+	}
+}
+
+func (c config) tiered() (Schedule, error) {
+	if len(c.Tiers) == 0 {
+		return nil, efees.EmptyTiersError
+	}
+
+	tiers := make([]Tier, 0, len(c.Tiers))
+	for i, tc := range c.Tiers {
+		if tc.UpTo == nil && i != len(c.Tiers)-1 {
+			return nil, efees.UnboundedTierError
+		}
+		if i > 0 && tc.UpTo != nil && tc.UpTo.LessThanOrEqual(*c.Tiers[i-1].UpTo) {
+			return nil, efees.UnsortedTiersError
+		}
+
+		schedule, err := tc.Fee.schedule()
+		if err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, Tier{UpTo: tc.UpTo, Schedule: schedule})
+	}
+	return Tiered{Tiers: tiers}, nil
+}
+
+func validAmount(d decimal.Decimal) bool {
+	return d.IsInteger() && d.GreaterThanOrEqual(decimal.Zero)
+}
@@ -0,0 +1,86 @@
+package fees
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"testing"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/efees"
+)
+
+func testFee(t *testing.T, config string, amount int64, expected int64) {
+	t.Helper()
+
+	schedule, err := Parse([]byte(config))
+	require.NoError(t, err)
+
+	fee := schedule.Fee(decimal.NewFromInt64(amount))
+	assert.True(t, fee.Equal(decimal.NewFromInt64(expected)), "expected fee %d, got %s", expected, fee)
+}
+
+func testParseError(t *testing.T, config string, expectedErrorType interface{}) {
+	t.Helper()
+
+	_, err := Parse([]byte(config))
+	assert.Error(t, err)
+	assert.IsType(t, expectedErrorType, err, "Error should be of type: %s", reflect.TypeOf(expectedErrorType))
+}
+
+func TestFee_Flat(t *testing.T) {
+	testFee(t, `{"type": "flat", "amount": "10"}`, 12345, 10)
+}
+
+func TestFee_Percentage(t *testing.T) {
+	testFee(t, `{"type": "percentage", "bps": 25}`, 10_000, 25)
+}
+
+func TestFee_PercentageRoundsDown(t *testing.T) {
+	testFee(t, `{"type": "percentage", "bps": 25}`, 399, 0)
+}
+
+func TestFee_PercentageMin(t *testing.T) {
+	testFee(t, `{"type": "percentage", "bps": 25, "min": "5"}`, 100, 5)
+}
+
+func TestFee_PercentageMax(t *testing.T) {
+	testFee(t, `{"type": "percentage", "bps": 25, "max": "100"}`, 1_000_000, 100)
+}
+
+const tieredConfig = `{"type": "tiered", "tiers": [
+	{"up_to": "1000", "fee": {"type": "flat", "amount": "1"}},
+	{"up_to": "100000", "fee": {"type": "percentage", "bps": 10}},
+	{"fee": {"type": "flat", "amount": "50"}}
+]}`
+
+func TestFee_TieredFirstTier(t *testing.T) {
+	testFee(t, tieredConfig, 1000, 1)
+}
+
+func TestFee_TieredMiddleTier(t *testing.T) {
+	testFee(t, tieredConfig, 20_000, 20)
+}
+
+func TestFee_TieredLastTier(t *testing.T) {
+	testFee(t, tieredConfig, 1_000_000, 50)
+}
+
+func TestParse_UnknownType(t *testing.T) {
+	testParseError(t, `{"type": "magic"}`, efees.UnknownFeeTypeError{})
+}
+
+func TestParse_NegativeFlat(t *testing.T) {
+	testParseError(t, `{"type": "flat", "amount": "-1"}`, efees.NegativeFeeError)
+}
+
+func TestParse_InvalidBasisPoints(t *testing.T) {
+	testParseError(t, `{"type": "percentage", "bps": 10001}`, efees.InvalidBasisPointsError)
+}
+
+func TestParse_MinExceedsMax(t *testing.T) {
+	testParseError(t, `{"type": "percentage", "bps": 25, "min": "100", "max": "10"}`, efees.MinExceedsMaxError)
+}
+
+func TestParse_UnboundedMiddleTier(t *testing.T) {
+	testParseError(t, `{"type": "tiered", "tiers": [{"fee": {"type": "flat", "amount": "1"}}, {"up_to": "10", "fee": {"type": "flat", "amount": "1"}}]}`, efees.UnboundedTierError)
+}
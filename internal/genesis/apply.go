@@ -0,0 +1,147 @@
+package genesis
+
+import (
+	"context"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/egenesis"
+	"token-transfer-api/internal/ledger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockKey identifies the advisory lock serializing the application of the genesis
+// when several replicas start at once.
+const lockKey = 0x67656e65736973 // "genesis"
+
+// Apply seeds the balances of g into an empty database and records its hash.
+// It is idempotent: if the database was seeded from g before nothing is changed,
+// if it was seeded from a different genesis egenesis.GenesisMismatchError is returned.
+//
+// Databases seeded before genesis files were supported are assumed to have been
+// seeded from Default. Its statements are cancelled with ctx.
+func Apply(ctx context.Context, dbConnection *gorm.DB, g *Genesis) error {
+	ctx = audit.WithSystemActor(ctx, "genesis")
+	return dbConnection.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := db.AdvisoryLock(tx, lockKey)
+		if err != nil {
+			return egenesis.GenesisApplyError
+		}
+
+		stored, err := Get(tx)
+		if err != nil {
+			return err
+		}
+		if stored == nil {
+			var entries int64
+			err = tx.Model(&db.LedgerEntry{}).Count(&entries).Error
+			if err != nil {
+				return egenesis.GenesisApplyError
+			}
+
+			seed := g
+			if entries > 0 {
+				seed = Default()
+			} else {
+				err = mint(ctx, tx, g)
+				if err != nil {
+					return err
+				}
+			}
+
+			stored = &db.Genesis{
+				ID:       db.GenesisID,
+				Hash:     seed.Hash.Hex(),
+				Name:     seed.Token.Name,
+				Symbol:   seed.Token.Symbol,
+				Decimals: seed.Token.Decimals,
+			}
+			err = tx.Create(stored).Error
+			if err != nil {
+				return egenesis.GenesisApplyError
+			}
+		}
+
+		if stored.Hash != g.Hash.Hex() {
+			return egenesis.GenesisMismatchError{Stored: stored.Hash, Given: g.Hash.Hex()}
+		}
+		return nil
+	})
+}
+
+// Seed mints the allocations of g with a ledger kept in memory, which is empty on every start.
+func Seed(ctx context.Context, l *ledger.Ledger, g *Genesis) error {
+	ctx = audit.WithSystemActor(ctx, "genesis")
+	for _, balance := range g.Balances {
+		_, err := l.Mint(ctx, balance.Address, balance.Amount)
+		if err != nil {
+			return egenesis.GenesisApplyError
+		}
+	}
+	return nil
+}
+
+// Get returns the genesis the database was seeded from, or nil if it has not been seeded yet.
+func Get(tx *gorm.DB) (*db.Genesis, error) {
+	var stored []db.Genesis
+	err := tx.Where("id = ?", db.GenesisID).Find(&stored).Error
+	if err != nil {
+		return nil, egenesis.GenesisRetrievalError
+	}
+	if len(stored) == 0 {
+		return nil, nil
+	}
+	return &stored[0], nil
+}
+
+// mint creates the accounts of the allocations and records them as mints
+// in the ledger and in the audit log.
+func mint(ctx context.Context, tx *gorm.DB, g *Genesis) error {
+	accounts := make([]db.Account, 0, len(g.Balances))
+	mints := make([]*db.LedgerEntry, 0, len(g.Balances))
+	changes := make([]audit.BalanceChange, 0, len(g.Balances))
+	for _, balance := range g.Balances {
+		accounts = append(accounts, db.Account{Address: balance.Address, Amount: balance.Amount})
+		if balance.Amount.IsZero() {
+			continue
+		}
+		mints = append(mints, &db.LedgerEntry{
+			Kind:      db.LedgerEntryMint,
+			ToAddress: balance.Address,
+			Amount:    balance.Amount,
+		})
+		changes = append(changes, audit.BalanceChange{
+			Address: balance.Address,
+			Before:  decimal.Zero,
+			After:   balance.Amount,
+		})
+	}
+
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(accounts, 1000).Error
+	if err != nil {
+		return egenesis.GenesisApplyError
+	}
+	if len(mints) > 0 {
+		err = db.AppendLedgerEntries(tx, mints...)
+		if err != nil {
+			return egenesis.GenesisApplyError
+		}
+	}
+
+	err = audit.Record(ctx, tx, audit.Event{
+		Operation: "genesis",
+		Subject:   g.Hash.Hex(),
+		Details: map[string]any{
+			"hash":     g.Hash.Hex(),
+			"accounts": len(accounts),
+			"total":    g.Total,
+		},
+		Balances: changes,
+	})
+	if err != nil {
+		return egenesis.GenesisApplyError
+	}
+	return nil
+}
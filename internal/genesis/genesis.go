@@ -0,0 +1,183 @@
+// Package genesis seeds the initial balances from a genesis file.
+//
+// A genesis file lists the token metadata and the initial allocations, e.g. in YAML:
+//
+//	token:
+//	  name: Example Token
+//	  symbol: EXT
+//	  decimals: 18
+//	allocations:
+//	  - address: "0x1234567890123456789012345678901234567890"
+//	    amount: "1000000000000000000000"
+//
+// Amounts may be written as numbers or strings, large amounts should be quoted so
+// that they are not rounded by YAML or JSON tooling.
+package genesis
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/egenesis"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gopkg.in/yaml.v3"
+)
+
+// Token is the metadata of the token.
+type Token struct {
+	Name     string `json:"name" yaml:"name"`
+	Symbol   string `json:"symbol" yaml:"symbol"`
+	Decimals uint8  `json:"decimals" yaml:"decimals"`
+}
+
+// Allocation is an initial balance as written in the genesis file.
+type Allocation struct {
+	Address string      `json:"address" yaml:"address"`
+	Amount  json.Number `json:"amount" yaml:"amount"`
+}
+
+// Balance is a validated allocation.
+type Balance struct {
+	Address address.Address
+	Amount  decimal.Decimal
+}
+
+// Genesis is a validated genesis file.
+type Genesis struct {
+	Token    Token
+	Balances []Balance
+	Total    decimal.Decimal
+	// Hash identifies the genesis independently of the formatting of the file.
+	Hash common.Hash
+}
+
+type file struct {
+	Token       Token        `json:"token" yaml:"token"`
+	Allocations []Allocation `json:"allocations" yaml:"allocations"`
+}
+
+// Default is the genesis used if no genesis file is configured,
+// it allocates DefaultCurrencyAmount to the default account.
+func Default() *Genesis {
+	g, err := validate(file{
+		Token: Token{Name: "BTP", Symbol: "BTP"},
+		Allocations: []Allocation{{
+			Address: db.DefaultAccountHex,
+			Amount:  json.Number(decimal.NewFromInt64(db.DefaultCurrencyAmount).String()),
+		}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+// Load reads and validates a genesis file. Files with a .yaml or .yml
+// extension are parsed as YAML, all others as JSON.
+func Load(path string) (*Genesis, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, egenesis.GenesisReadError{Path: path, Err: err}
+	}
+
+	ext := filepath.Ext(path)
+	g, err := Parse(data, ext == ".yaml" || ext == ".yml")
+	if err != nil {
+		return nil, egenesis.GenesisReadError{Path: path, Err: err}
+	}
+	return g, nil
+}
+
+// Parse parses and validates the contents of a genesis file.
+func Parse(data []byte, isYAML bool) (*Genesis, error) {
+	var f file
+	var err error
+	if isYAML {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&f)
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return validate(f)
+}
+
+// validate checks that addresses are checksummed and unique and that amounts
+// are non-negative integers whose total fits into a uint256.
+func validate(f file) (*Genesis, error) {
+	if f.Token.Name == "" {
+		return nil, egenesis.InvalidTokenError{Field: "name", Reason: "must not be empty"}
+	}
+	if f.Token.Symbol == "" {
+		return nil, egenesis.InvalidTokenError{Field: "symbol", Reason: "must not be empty"}
+	}
+	if len(f.Allocations) == 0 {
+		return nil, egenesis.EmptyGenesisError
+	}
+
+	g := &Genesis{Token: f.Token, Total: decimal.Zero}
+	seen := make(map[address.Address]bool, len(f.Allocations))
+	for i, allocation := range f.Allocations {
+		if !common.IsHexAddress(allocation.Address) {
+			return nil, egenesis.InvalidAddressError{Index: i, Address: allocation.Address}
+		}
+		addr := address.HexToAddress(allocation.Address)
+		if addr.Hex() != allocation.Address {
+			return nil, egenesis.ChecksumMismatchError{Index: i, Address: allocation.Address, Expected: addr.Hex()}
+		}
+		if seen[addr] {
+			return nil, egenesis.DuplicateAddressError{Index: i, Address: allocation.Address}
+		}
+		seen[addr] = true
+
+		amount, err := decimal.NewFromString(allocation.Amount.String())
+		if err != nil || amount.LessThan(decimal.Zero) || !amount.IsInteger() {
+			return nil, egenesis.InvalidAmountError{Index: i, Amount: allocation.Amount.String()}
+		}
+
+		g.Total = g.Total.Add(amount)
+		if g.Total.BigInt().Cmp(math.MaxBig256) > 0 {
+			return nil, egenesis.TotalOverflowError{Total: g.Total.String()}
+		}
+		g.Balances = append(g.Balances, Balance{Address: addr, Amount: amount})
+	}
+
+	g.Hash = hash(g)
+	return g, nil
+}
+
+// hash returns the keccak256 hash of the canonical JSON form of the genesis, with
+// the allocations sorted by address and amounts in decimal notation. Reformatting
+// or reordering a genesis file therefore does not change its hash.
+func hash(g *Genesis) common.Hash {
+	allocations := make([]Allocation, 0, len(g.Balances))
+	for _, balance := range g.Balances {
+		allocations = append(allocations, Allocation{
+			Address: balance.Address.Hex(),
+			Amount:  json.Number(balance.Amount.String()),
+		})
+	}
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].Address < allocations[j].Address
+	})
+
+	data, err := json.Marshal(file{Token: g.Token, Allocations: allocations})
+	if err != nil {
+		panic(err)
+	}
+	return crypto.Keccak256Hash(data)
+}
@@ -0,0 +1,128 @@
+package genesis
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/egenesis"
+)
+
+// testYAML and testJSON allocate the maximum uint256 in total.
+const testYAML = `
+token:
+  name: Example Token
+  symbol: EXT
+  decimals: 18
+allocations:
+  - address: "0x1234567890123456789012345678901234567890"
+    amount: 1000
+  - address: "0xabCDEF1234567890ABcDEF1234567890aBCDeF12"
+    amount: "115792089237316195423570985008687907853269984665640564039457584007913129638935"
+`
+
+const testJSON = `{
+	"token": {"name": "Example Token", "symbol": "EXT", "decimals": 18},
+	"allocations": [
+		{"address": "0xabCDEF1234567890ABcDEF1234567890aBCDeF12", "amount": "115792089237316195423570985008687907853269984665640564039457584007913129638935"},
+		{"address": "0x1234567890123456789012345678901234567890", "amount": 1000}
+	]
+}`
+
+func testAllocation(address, amount string) string {
+	return `{"token": {"name": "Example Token", "symbol": "EXT"}, "allocations": [{"address": "` + address + `", "amount": ` + amount + `}]}`
+}
+
+func TestParse_YAML(t *testing.T) {
+	g, err := Parse([]byte(testYAML), true)
+
+	require.NoError(t, err)
+	assert.Equal(t, Token{Name: "Example Token", Symbol: "EXT", Decimals: 18}, g.Token)
+	require.Len(t, g.Balances, 2)
+	assert.Equal(t, "0x1234567890123456789012345678901234567890", g.Balances[0].Address.Hex())
+	assert.True(t, g.Balances[0].Amount.Equal(decimal.NewFromInt64(1000)))
+}
+
+func TestParse_HashIgnoresFormatAndOrder(t *testing.T) {
+	fromYAML, err := Parse([]byte(testYAML), true)
+	require.NoError(t, err)
+	fromJSON, err := Parse([]byte(testJSON), false)
+	require.NoError(t, err)
+
+	assert.Equal(t, fromYAML.Hash, fromJSON.Hash)
+	assert.NotEqual(t, Default().Hash, fromJSON.Hash)
+}
+
+func TestParse_HashCoversAmounts(t *testing.T) {
+	first, err := Parse([]byte(testAllocation("0x1234567890123456789012345678901234567890", "1")), false)
+	require.NoError(t, err)
+	second, err := Parse([]byte(testAllocation("0x1234567890123456789012345678901234567890", "2")), false)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first.Hash, second.Hash)
+}
+
+func TestParse_ChecksumMismatch(t *testing.T) {
+	_, err := Parse([]byte(testAllocation("0xabcdef1234567890abcdef1234567890abcdef12", "1")), false)
+
+	var mismatch egenesis.ChecksumMismatchError
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "0xabCDEF1234567890ABcDEF1234567890aBCDeF12", mismatch.Expected)
+}
+
+func TestParse_InvalidAddress(t *testing.T) {
+	_, err := Parse([]byte(testAllocation("0x1234", "1")), false)
+
+	assert.ErrorIs(t, err, egenesis.InvalidAddressError{Index: 0, Address: "0x1234"})
+}
+
+func TestParse_NegativeAmount(t *testing.T) {
+	_, err := Parse([]byte(testAllocation("0x1234567890123456789012345678901234567890", "-1")), false)
+
+	assert.ErrorIs(t, err, egenesis.InvalidAmountError{Index: 0, Amount: "-1"})
+}
+
+func TestParse_FractionalAmount(t *testing.T) {
+	_, err := Parse([]byte(testAllocation("0x1234567890123456789012345678901234567890", `"1.5"`)), false)
+
+	assert.ErrorIs(t, err, egenesis.InvalidAmountError{Index: 0, Amount: "1.5"})
+}
+
+func TestParse_DuplicateAddress(t *testing.T) {
+	data := `{"token": {"name": "Example Token", "symbol": "EXT"}, "allocations": [
+		{"address": "0x1234567890123456789012345678901234567890", "amount": 1},
+		{"address": "0x1234567890123456789012345678901234567890", "amount": 2}
+	]}`
+
+	_, err := Parse([]byte(data), false)
+
+	assert.ErrorIs(t, err, egenesis.DuplicateAddressError{Index: 1, Address: "0x1234567890123456789012345678901234567890"})
+}
+
+func TestParse_TotalOverflow(t *testing.T) {
+	data := `
+token: {name: Example Token, symbol: EXT}
+allocations:
+  - address: "0x1234567890123456789012345678901234567890"
+    amount: "115792089237316195423570985008687907853269984665640564039457584007913129639935"
+  - address: "0xabCDEF1234567890ABcDEF1234567890aBCDeF12"
+    amount: 1
+`
+
+	_, err := Parse([]byte(data), true)
+
+	var overflow egenesis.TotalOverflowError
+	assert.ErrorAs(t, err, &overflow)
+}
+
+func TestParse_UnknownField(t *testing.T) {
+	_, err := Parse([]byte(`{"token": {"name": "Example Token", "symbol": "EXT"}, "alocations": []}`), false)
+
+	assert.Error(t, err)
+}
+
+func TestParse_MissingSymbol(t *testing.T) {
+	_, err := Parse([]byte(`{"token": {"name": "Example Token"}, "allocations": [{"address": "0x1234567890123456789012345678901234567890", "amount": 1}]}`), false)
+
+	assert.ErrorIs(t, err, egenesis.InvalidTokenError{Field: "symbol", Reason: "must not be empty"})
+}
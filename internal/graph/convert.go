@@ -0,0 +1,328 @@
+package graph
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/errors/eaudit"
+	"token-transfer-api/internal/errors/efees"
+	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/history"
+	"token-transfer-api/internal/ledger"
+	"token-transfer-api/internal/reconcile"
+	"token-transfer-api/internal/snapshot"
+	"token-transfer-api/internal/stream"
+	"token-transfer-api/internal/vesting"
+)
+
+// This file contains conversions between database rows and GraphQL models.
+
+func parseID(id string) (uint64, error) {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, eresolvers.InvalidIDError{ID: id}
+	}
+	return n, nil
+}
+
+func formatID(id uint64) string {
+	return strconv.FormatUint(id, 10)
+}
+
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+// cursorPrefix makes cursors opaque, clients must not rely on them being ids.
+const cursorPrefix = "cursor:"
+
+func parseCursor(cursor string) (uint64, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, eaudit.InvalidCursorError{Cursor: cursor}
+	}
+
+	id, ok := strings.CutPrefix(string(data), cursorPrefix)
+	if !ok {
+		return 0, eaudit.InvalidCursorError{Cursor: cursor}
+	}
+
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, eaudit.InvalidCursorError{Cursor: cursor}
+	}
+	return n, nil
+}
+
+func formatCursor(id uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + formatID(id)))
+}
+
+func toScheduledTransfer(s *db.ScheduledTransfer) *model.ScheduledTransfer {
+	res := &model.ScheduledTransfer{
+		ID:          formatID(s.ID),
+		FromAddress: s.FromAddress,
+		ToAddress:   s.ToAddress,
+		Amount:      s.Amount,
+		ExecuteAt:   s.ExecuteAt,
+		Status:      model.ScheduledTransferStatus(s.Status),
+		Runs:        make([]*model.ScheduledTransferRun, 0, len(s.Runs)),
+	}
+	if s.Recurrence != "" {
+		recurrence := s.Recurrence
+		res.Recurrence = &recurrence
+	}
+	if s.Status == db.ScheduledTransferActive {
+		nextRunAt := s.NextRunAt
+		res.NextRunAt = &nextRunAt
+	}
+	for _, run := range s.Runs {
+		res.Runs = append(res.Runs, toScheduledTransferRun(&run))
+	}
+	return res
+}
+
+func toScheduledTransferRun(r *db.ScheduledTransferRun) *model.ScheduledTransferRun {
+	res := &model.ScheduledTransferRun{
+		ID:           formatID(r.ID),
+		ScheduledFor: r.ScheduledFor,
+		ExecutedAt:   r.ExecutedAt,
+		Succeeded:    r.Succeeded,
+		Balance:      r.Balance,
+	}
+	if r.Error != "" {
+		msg := r.Error
+		res.Error = &msg
+	}
+	return res
+}
+
+func toVesting(v *db.Vesting, now time.Time) *model.Vesting {
+	return &model.Vesting{
+		ID:          formatID(v.ID),
+		Beneficiary: v.Beneficiary,
+		Total:       v.Total,
+		Released:    v.Released,
+		Start:       v.Start,
+		Cliff:       v.Start.Add(time.Duration(v.CliffSeconds) * time.Second),
+		End:         v.Start.Add(time.Duration(v.DurationSeconds) * time.Second),
+		Vested:      vesting.VestedAmount(v, now),
+		Releasable:  vesting.ReleasableAmount(v, now),
+	}
+}
+
+func toStream(s *db.Stream, now time.Time) *model.Stream {
+	return &model.Stream{
+		ID:            formatID(s.ID),
+		Sender:        s.Sender,
+		Recipient:     s.Recipient,
+		RatePerSecond: s.RatePerSecond,
+		Deposit:       s.Deposit,
+		Withdrawn:     s.Withdrawn,
+		Start:         s.Start,
+		Stop:          s.Stop,
+		Status:        model.StreamStatus(s.Status),
+		CancelledAt:   s.CancelledAt,
+		Streamed:      stream.StreamedAmount(s, now),
+		Withdrawable:  stream.WithdrawableAmount(s, now),
+		Remaining:     stream.RemainingAmount(s, now),
+	}
+}
+
+func toFrozenAccount(f *db.FrozenAccount) *model.FrozenAccount {
+	return &model.FrozenAccount{
+		Address:  f.Address,
+		Reason:   f.Reason,
+		FrozenBy: f.FrozenBy,
+		FrozenAt: f.FrozenAt,
+	}
+}
+
+func toPauseState(p *db.Pause) *model.PauseState {
+	res := &model.PauseState{Paused: p.Paused}
+	if p.UpdatedBy != "" {
+		reason, updatedBy, updatedAt := p.Reason, p.UpdatedBy, p.UpdatedAt
+		res.Reason = &reason
+		res.UpdatedBy = &updatedBy
+		res.UpdatedAt = &updatedAt
+	}
+	return res
+}
+
+// toAuditEventConnection converts up to limit events, events beyond limit
+// only indicate that there is a next page.
+func toAuditEventConnection(events []db.AuditEvent, limit int) *model.AuditEventConnection {
+	res := &model.AuditEventConnection{
+		Edges:    make([]*model.AuditEventEdge, 0, min(len(events), limit)),
+		PageInfo: &model.PageInfo{HasNextPage: len(events) > limit},
+	}
+	for i := range events[:min(len(events), limit)] {
+		cursor := formatCursor(events[i].ID)
+		res.Edges = append(res.Edges, &model.AuditEventEdge{Cursor: cursor, Node: toAuditEvent(&events[i])})
+		res.PageInfo.EndCursor = &cursor
+	}
+	return res
+}
+
+func toAuditEvent(e *db.AuditEvent) *model.AuditEvent {
+	res := &model.AuditEvent{
+		ID:        formatID(e.ID),
+		CreatedAt: e.CreatedAt,
+		Actor:     e.Actor,
+		Operation: e.Operation,
+		Details:   e.Details,
+		Balances:  make([]*model.AuditBalanceChange, 0, len(e.Balances)),
+	}
+	if e.RequestID != "" {
+		requestID := e.RequestID
+		res.RequestID = &requestID
+	}
+	if e.ClientIP != "" {
+		clientIP := e.ClientIP
+		res.ClientIP = &clientIP
+	}
+	if e.Subject != "" {
+		subject := e.Subject
+		res.Subject = &subject
+	}
+	for _, change := range e.Balances {
+		res.Balances = append(res.Balances, &model.AuditBalanceChange{
+			Address: change.Address,
+			Before:  change.Before,
+			After:   change.After,
+		})
+	}
+	return res
+}
+
+func toLedgerVerification(v ledger.Verification) *model.LedgerVerification {
+	res := &model.LedgerVerification{
+		Checked:  v.Checked,
+		Valid:    v.Valid,
+		LastHash: v.LastHash,
+	}
+	if !v.Valid {
+		brokenAt, reason := formatID(v.BrokenAt), v.Reason
+		res.BrokenAt = &brokenAt
+		res.Reason = &reason
+	}
+	return res
+}
+
+func toReconciliationReport(r *reconcile.Report) *model.ReconciliationReport {
+	res := &model.ReconciliationReport{
+		CheckedAt:     r.CheckedAt,
+		LedgerEntries: r.LedgerEntries,
+		Accounts:      r.Accounts,
+		Supply:        r.Supply,
+		Total:         r.Total,
+		Balanced:      r.Balanced(),
+		Discrepancies: make([]*model.BalanceDiscrepancy, 0, len(r.Discrepancies)),
+	}
+	if r.LastEntryID != 0 {
+		lastEntryID := formatID(r.LastEntryID)
+		res.LastEntryID = &lastEntryID
+	}
+	for _, d := range r.Discrepancies {
+		res.Discrepancies = append(res.Discrepancies, &model.BalanceDiscrepancy{
+			Address:  d.Address,
+			Stored:   d.Stored,
+			Expected: d.Expected,
+		})
+	}
+	return res
+}
+
+func toHistoricalBalance(h history.Holding, position uint64) *model.HistoricalBalance {
+	res := &model.HistoricalBalance{Address: h.Address, Balance: h.Amount}
+	if position != 0 {
+		ledgerID := formatID(position)
+		res.LedgerID = &ledgerID
+	}
+	return res
+}
+
+func toSnapshot(s *db.Snapshot) *model.Snapshot {
+	res := &model.Snapshot{
+		ID:        formatID(s.ID),
+		CreatedAt: s.CreatedAt,
+		CreatedBy: s.CreatedBy,
+		Root:      s.Root,
+		Accounts:  int32(s.Accounts),
+		Total:     s.Total,
+	}
+	if s.LedgerEntryID != 0 {
+		ledgerEntryID := formatID(s.LedgerEntryID)
+		res.LedgerEntryID = &ledgerEntryID
+	}
+	return res
+}
+
+func toMerkleProof(addr address.Address, p *snapshot.Proof) *model.MerkleProof {
+	proof := make([]string, 0, len(p.Hashes))
+	for _, hash := range p.Hashes {
+		proof = append(proof, hash.Hex())
+	}
+	return &model.MerkleProof{
+		SnapshotID: formatID(p.Snapshot.ID),
+		Address:    addr,
+		Amount:     p.Amount,
+		Leaf:       p.Leaf.Hex(),
+		Proof:      proof,
+		Root:       p.Snapshot.Root,
+	}
+}
+
+func toToken(g *db.Genesis) *model.Token {
+	return &model.Token{
+		Name:        g.Name,
+		Symbol:      g.Symbol,
+		Decimals:    int32(g.Decimals),
+		GenesisHash: g.Hash,
+	}
+}
+
+func toTransferSimulation(s ledger.Simulation) *model.TransferSimulation {
+	res := &model.TransferSimulation{
+		SenderBalance:            s.SenderBalance,
+		ReceiverBalance:          s.ReceiverBalance,
+		ProjectedSenderBalance:   s.Receipt.Balance,
+		ProjectedReceiverBalance: s.ReceiverProjected,
+		Sent:                     s.Receipt.Amount,
+		Fee:                      s.Receipt.Fee,
+		Received:                 s.Receipt.Net,
+	}
+	if s.Err != nil {
+		code := toTransferError(s.Err)
+		msg := s.Err.Error()
+		res.Error = &code
+		res.Message = &msg
+	}
+	return res
+}
+
+// toTransferError returns the code of an error reported by ledger.Ledger.Simulate.
+func toTransferError(err error) model.TransferError {
+	switch {
+	case errors.Is(err, eresolvers.NegativeTransferError):
+		return model.TransferErrorNegativeAmount
+	case errors.Is(err, eresolvers.NonIntegerTransferError):
+		return model.TransferErrorNonIntegerAmount
+	case errors.As(err, new(efees.FeeExceedsAmountError)):
+		return model.TransferErrorFeeExceedsAmount
+	case errors.Is(err, eresolvers.TransfersPausedError):
+		return model.TransferErrorTransfersPaused
+	case errors.As(err, new(eresolvers.AccountFrozenError)):
+		return model.TransferErrorAccountFrozen
+	case errors.As(err, new(eresolvers.AddressNotFoundError)):
+		return model.TransferErrorAddressNotFound
+	default:
+		return model.TransferErrorInsufficientBalance
+	}
+}
@@ -0,0 +1,24 @@
+package graph
+
+import (
+	"context"
+	"token-transfer-api/internal/auth"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// Directives returns the implementations of the schema directives.
+func Directives() DirectiveRoot {
+	return DirectiveRoot{
+		Admin: admin,
+	}
+}
+
+// admin implements the @admin directive, it rejects requests not authenticated with an admin token.
+func admin(ctx context.Context, _ any, next graphql.Resolver) (any, error) {
+	_, err := auth.RequireOperator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"context"
+	"time"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/controls"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/history"
+	"token-transfer-api/internal/ledger"
+
+	"gorm.io/gorm"
+)
+
+// This file contains logic shared by several resolvers.
+
+func (r *mutationResolver) setPaused(ctx context.Context, paused bool, reason string) (*model.PauseState, error) {
+	operator, err := auth.RequireOperator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pause *db.Pause
+	err = r.Ledger.InTx(ctx, func(tx *gorm.DB) error {
+		var err error
+		pause, err = controls.SetPaused(ctx, tx, paused, reason, operator)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toPauseState(pause), nil
+}
+
+// readDb returns the connection of the Query resolvers: the replicas, unless there
+// are none or the request reads its own writes, see db.ReadYourWrites.
+func (r *Resolver) readDb(ctx context.Context) *gorm.DB {
+	return db.Reader(ctx, r.Db, r.Replicas)
+}
+
+// readLedger returns the Ledger of the Query resolvers, which reads from readDb
+// if the ledger is stored in the database.
+func (r *queryResolver) readLedger(ctx context.Context) *ledger.Ledger {
+	if r.Db == nil {
+		return r.Ledger
+	}
+
+	l := *r.Ledger
+	l.Db = r.readDb(ctx)
+	l.Store = ledger.NewSQLStore(l.Db)
+	return &l
+}
+
+// historyPosition resolves the ledger position of a historical balance query.
+func (r *queryResolver) historyPosition(ctx context.Context, at *time.Time, ledgerID *string) (uint64, error) {
+	var id uint64
+	if ledgerID != nil {
+		var err error
+		id, err = parseID(*ledgerID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return history.Position(r.readDb(ctx), at, id)
+}
@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/errors/eresolvers"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// AuditOperation is a root field middleware naming the audit events recorded
+// while resolving a mutation after the mutation field, e.g. "release".
+func AuditOperation(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+	field := graphql.GetRootFieldContext(ctx)
+	if field != nil && field.Object == "Mutation" {
+		ctx = audit.WithOperation(ctx, field.Field.Name)
+	}
+	return next(ctx)
+}
+
+// MarkWrites is an operation middleware marking the requests running a mutation as
+// writing to the primary, so that their clients read their own writes, see db.ReadYourWrites.
+func MarkWrites(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	operation := graphql.GetOperationContext(ctx)
+	if operation.Operation != nil && operation.Operation.Operation == ast.Mutation {
+		db.Wrote(ctx)
+	}
+	return next(ctx)
+}
+
+// ledgerFields are the root fields served by the Ledger alone.
+var ledgerFields = map[string]bool{"transfer": true, "balance": true, "simulateTransfer": true}
+
+// RequireDb is a root field middleware for resolvers without a database, i.e. with
+// the ledger kept in memory. It rejects the root fields not in ledgerFields.
+func RequireDb(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+	field := graphql.GetRootFieldContext(ctx)
+	if field == nil || ledgerFields[field.Field.Name] || strings.HasPrefix(field.Field.Name, "__") {
+		return next(ctx)
+	}
+
+	graphql.AddError(ctx, eresolvers.DatabaseRequiredError{Field: field.Field.Name})
+	return graphql.Null
+}
@@ -3,18 +3,183 @@
 package model
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
 	"token-transfer-api/internal/address"
 	"token-transfer-api/internal/decimal"
 )
 
+type AuditBalanceChange struct {
+	Address address.Address `json:"address"`
+	Before  decimal.Decimal `json:"before"`
+	After   decimal.Decimal `json:"after"`
+}
+
+type AuditEvent struct {
+	ID        string                `json:"id"`
+	CreatedAt time.Time             `json:"created_at"`
+	Actor     string                `json:"actor"`
+	RequestID *string               `json:"request_id,omitempty"`
+	ClientIP  *string               `json:"client_ip,omitempty"`
+	Operation string                `json:"operation"`
+	Subject   *string               `json:"subject,omitempty"`
+	Details   string                `json:"details"`
+	Balances  []*AuditBalanceChange `json:"balances"`
+}
+
+type AuditEventConnection struct {
+	Edges    []*AuditEventEdge `json:"edges"`
+	PageInfo *PageInfo         `json:"page_info"`
+}
+
+type AuditEventEdge struct {
+	Cursor string      `json:"cursor"`
+	Node   *AuditEvent `json:"node"`
+}
+
+type AuditEventFilter struct {
+	Actor     *string          `json:"actor,omitempty"`
+	Operation *string          `json:"operation,omitempty"`
+	Address   *address.Address `json:"address,omitempty"`
+	From      *time.Time       `json:"from,omitempty"`
+	To        *time.Time       `json:"to,omitempty"`
+}
+
+type Balance struct {
+	Address      address.Address `json:"address"`
+	Balance      decimal.Decimal `json:"balance"`
+	StreamingIn  decimal.Decimal `json:"streaming_in"`
+	StreamingOut decimal.Decimal `json:"streaming_out"`
+	Total        decimal.Decimal `json:"total"`
+}
+
+type BalanceDiscrepancy struct {
+	Address  address.Address `json:"address"`
+	Stored   decimal.Decimal `json:"stored"`
+	Expected decimal.Decimal `json:"expected"`
+}
+
+type FrozenAccount struct {
+	Address  address.Address `json:"address"`
+	Reason   string          `json:"reason"`
+	FrozenBy string          `json:"frozen_by"`
+	FrozenAt time.Time       `json:"frozen_at"`
+}
+
+type HistoricalBalance struct {
+	Address  address.Address `json:"address"`
+	Balance  decimal.Decimal `json:"balance"`
+	LedgerID *string         `json:"ledger_id,omitempty"`
+}
+
+type LedgerVerification struct {
+	Checked  int     `json:"checked"`
+	Valid    bool    `json:"valid"`
+	BrokenAt *string `json:"broken_at,omitempty"`
+	Reason   *string `json:"reason,omitempty"`
+	LastHash string  `json:"last_hash"`
+}
+
+type MerkleProof struct {
+	SnapshotID string          `json:"snapshot_id"`
+	Address    address.Address `json:"address"`
+	Amount     decimal.Decimal `json:"amount"`
+	Leaf       string          `json:"leaf"`
+	Proof      []string        `json:"proof"`
+	Root       string          `json:"root"`
+}
+
 type Mutation struct {
 }
 
+type PageInfo struct {
+	EndCursor   *string `json:"end_cursor,omitempty"`
+	HasNextPage bool    `json:"has_next_page"`
+}
+
+type PauseState struct {
+	Paused    bool       `json:"paused"`
+	Reason    *string    `json:"reason,omitempty"`
+	UpdatedBy *string    `json:"updated_by,omitempty"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
 type Query struct {
 }
 
+type ReconciliationReport struct {
+	CheckedAt     time.Time             `json:"checked_at"`
+	LedgerEntries int                   `json:"ledger_entries"`
+	LastEntryID   *string               `json:"last_entry_id,omitempty"`
+	Accounts      int                   `json:"accounts"`
+	Supply        decimal.Decimal       `json:"supply"`
+	Total         decimal.Decimal       `json:"total"`
+	Balanced      bool                  `json:"balanced"`
+	Discrepancies []*BalanceDiscrepancy `json:"discrepancies"`
+}
+
+type ScheduledTransfer struct {
+	ID          string                  `json:"id"`
+	FromAddress address.Address         `json:"from_address"`
+	ToAddress   address.Address         `json:"to_address"`
+	Amount      decimal.Decimal         `json:"amount"`
+	ExecuteAt   time.Time               `json:"execute_at"`
+	Recurrence  *string                 `json:"recurrence,omitempty"`
+	NextRunAt   *time.Time              `json:"next_run_at,omitempty"`
+	Status      ScheduledTransferStatus `json:"status"`
+	Runs        []*ScheduledTransferRun `json:"runs"`
+}
+
+type ScheduledTransferRun struct {
+	ID           string           `json:"id"`
+	ScheduledFor time.Time        `json:"scheduled_for"`
+	ExecutedAt   time.Time        `json:"executed_at"`
+	Succeeded    bool             `json:"succeeded"`
+	Error        *string          `json:"error,omitempty"`
+	Balance      *decimal.Decimal `json:"balance,omitempty"`
+}
+
 type Sender struct {
-	Balance decimal.Decimal `json:"balance"`
+	Balance  decimal.Decimal `json:"balance"`
+	Sent     decimal.Decimal `json:"sent"`
+	Fee      decimal.Decimal `json:"fee"`
+	Received decimal.Decimal `json:"received"`
+}
+
+type Snapshot struct {
+	ID            string          `json:"id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	CreatedBy     string          `json:"created_by"`
+	LedgerEntryID *string         `json:"ledger_entry_id,omitempty"`
+	Root          string          `json:"root"`
+	Accounts      int32           `json:"accounts"`
+	Total         decimal.Decimal `json:"total"`
+}
+
+type Stream struct {
+	ID            string          `json:"id"`
+	Sender        address.Address `json:"sender"`
+	Recipient     address.Address `json:"recipient"`
+	RatePerSecond decimal.Decimal `json:"rate_per_second"`
+	Deposit       decimal.Decimal `json:"deposit"`
+	Withdrawn     decimal.Decimal `json:"withdrawn"`
+	Start         time.Time       `json:"start"`
+	Stop          time.Time       `json:"stop"`
+	Status        StreamStatus    `json:"status"`
+	CancelledAt   *time.Time      `json:"cancelled_at,omitempty"`
+	Streamed      decimal.Decimal `json:"streamed"`
+	Withdrawable  decimal.Decimal `json:"withdrawable"`
+	Remaining     decimal.Decimal `json:"remaining"`
+}
+
+type Token struct {
+	Name        string `json:"name"`
+	Symbol      string `json:"symbol"`
+	Decimals    int32  `json:"decimals"`
+	GenesisHash string `json:"genesis_hash"`
 }
 
 type Transfer struct {
@@ -22,3 +187,206 @@ type Transfer struct {
 	ToAddress   address.Address `json:"to_address"`
 	Amount      decimal.Decimal `json:"amount"`
 }
+
+type TransferSimulation struct {
+	Error                    *TransferError  `json:"error,omitempty"`
+	Message                  *string         `json:"message,omitempty"`
+	SenderBalance            decimal.Decimal `json:"sender_balance"`
+	ReceiverBalance          decimal.Decimal `json:"receiver_balance"`
+	ProjectedSenderBalance   decimal.Decimal `json:"projected_sender_balance"`
+	ProjectedReceiverBalance decimal.Decimal `json:"projected_receiver_balance"`
+	Sent                     decimal.Decimal `json:"sent"`
+	Fee                      decimal.Decimal `json:"fee"`
+	Received                 decimal.Decimal `json:"received"`
+}
+
+type Vesting struct {
+	ID          string          `json:"id"`
+	Beneficiary address.Address `json:"beneficiary"`
+	Total       decimal.Decimal `json:"total"`
+	Released    decimal.Decimal `json:"released"`
+	Start       time.Time       `json:"start"`
+	Cliff       time.Time       `json:"cliff"`
+	End         time.Time       `json:"end"`
+	Vested      decimal.Decimal `json:"vested"`
+	Releasable  decimal.Decimal `json:"releasable"`
+}
+
+type ScheduledTransferStatus string
+
+const (
+	ScheduledTransferStatusActive    ScheduledTransferStatus = "ACTIVE"
+	ScheduledTransferStatusCompleted ScheduledTransferStatus = "COMPLETED"
+	ScheduledTransferStatusCancelled ScheduledTransferStatus = "CANCELLED"
+)
+
+var AllScheduledTransferStatus = []ScheduledTransferStatus{
+	ScheduledTransferStatusActive,
+	ScheduledTransferStatusCompleted,
+	ScheduledTransferStatusCancelled,
+}
+
+func (e ScheduledTransferStatus) IsValid() bool {
+	switch e {
+	case ScheduledTransferStatusActive, ScheduledTransferStatusCompleted, ScheduledTransferStatusCancelled:
+		return true
+	}
+	return false
+}
+
+func (e ScheduledTransferStatus) String() string {
+	return string(e)
+}
+
+func (e *ScheduledTransferStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ScheduledTransferStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ScheduledTransferStatus", str)
+	}
+	return nil
+}
+
+func (e ScheduledTransferStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ScheduledTransferStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ScheduledTransferStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type StreamStatus string
+
+const (
+	StreamStatusActive    StreamStatus = "ACTIVE"
+	StreamStatusCancelled StreamStatus = "CANCELLED"
+	StreamStatusCompleted StreamStatus = "COMPLETED"
+)
+
+var AllStreamStatus = []StreamStatus{
+	StreamStatusActive,
+	StreamStatusCancelled,
+	StreamStatusCompleted,
+}
+
+func (e StreamStatus) IsValid() bool {
+	switch e {
+	case StreamStatusActive, StreamStatusCancelled, StreamStatusCompleted:
+		return true
+	}
+	return false
+}
+
+func (e StreamStatus) String() string {
+	return string(e)
+}
+
+func (e *StreamStatus) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = StreamStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid StreamStatus", str)
+	}
+	return nil
+}
+
+func (e StreamStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *StreamStatus) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e StreamStatus) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type TransferError string
+
+const (
+	TransferErrorNegativeAmount      TransferError = "NEGATIVE_AMOUNT"
+	TransferErrorNonIntegerAmount    TransferError = "NON_INTEGER_AMOUNT"
+	TransferErrorFeeExceedsAmount    TransferError = "FEE_EXCEEDS_AMOUNT"
+	TransferErrorTransfersPaused     TransferError = "TRANSFERS_PAUSED"
+	TransferErrorAccountFrozen       TransferError = "ACCOUNT_FROZEN"
+	TransferErrorAddressNotFound     TransferError = "ADDRESS_NOT_FOUND"
+	TransferErrorInsufficientBalance TransferError = "INSUFFICIENT_BALANCE"
+)
+
+var AllTransferError = []TransferError{
+	TransferErrorNegativeAmount,
+	TransferErrorNonIntegerAmount,
+	TransferErrorFeeExceedsAmount,
+	TransferErrorTransfersPaused,
+	TransferErrorAccountFrozen,
+	TransferErrorAddressNotFound,
+	TransferErrorInsufficientBalance,
+}
+
+func (e TransferError) IsValid() bool {
+	switch e {
+	case TransferErrorNegativeAmount, TransferErrorNonIntegerAmount, TransferErrorFeeExceedsAmount, TransferErrorTransfersPaused, TransferErrorAccountFrozen, TransferErrorAddressNotFound, TransferErrorInsufficientBalance:
+		return true
+	}
+	return false
+}
+
+func (e TransferError) String() string {
+	return string(e)
+}
+
+func (e *TransferError) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TransferError(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TransferError", str)
+	}
+	return nil
+}
+
+func (e TransferError) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *TransferError) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e TransferError) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
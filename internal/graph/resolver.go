@@ -1,12 +1,20 @@
 package graph
 
 //go:generate go run github.com/99designs/gqlgen generate
-import "gorm.io/gorm"
+import (
+	"token-transfer-api/internal/ledger"
+
+	"gorm.io/gorm"
+)
 
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
+	// Db is nil if the ledger is kept in memory, see RequireDb.
 	Db *gorm.DB
+	// Replicas serve the Query resolvers if set, see readDb.
+	Replicas *gorm.DB
+	Ledger   *ledger.Ledger
 }
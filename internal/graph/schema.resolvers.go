@@ -6,137 +6,559 @@ package graph
 
 import (
 	"context"
-	"errors"
-	"sort"
+	"time"
 	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/controls"
 	"token-transfer-api/internal/db"
 	"token-transfer-api/internal/decimal"
-	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/errors/eaudit"
+	"token-transfer-api/internal/errors/egenesis"
+	"token-transfer-api/internal/genesis"
 	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/history"
+	"token-transfer-api/internal/ledger"
+	"token-transfer-api/internal/reconcile"
+	"token-transfer-api/internal/scheduler"
+	"token-transfer-api/internal/snapshot"
+	"token-transfer-api/internal/stream"
+	"token-transfer-api/internal/vesting"
 
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 // Transfer is the resolver for the transfer field.
 func (r *mutationResolver) Transfer(ctx context.Context, input model.Transfer) (*model.Sender, error) {
-	// do not allow negative transfers
-	if input.Amount.LessThan(decimal.Zero) {
-		return nil, eresolvers.NegativeTransferError
+	receipt, err := r.Ledger.Transfer(ctx, input.FromAddress, input.ToAddress, input.Amount)
+	if err != nil {
+		return nil, err
 	}
 
-	// only allow int values
-	if !input.Amount.IsInteger() {
-		return nil, eresolvers.NonIntegerTransferError
+	return &model.Sender{
+		Balance:  receipt.Balance,
+		Sent:     receipt.Amount,
+		Fee:      receipt.Fee,
+		Received: receipt.Net,
+	}, nil
+}
+
+// ScheduleTransfer is the resolver for the scheduleTransfer field.
+func (r *mutationResolver) ScheduleTransfer(ctx context.Context, input model.Transfer, executeAt time.Time, recurrence *string) (*model.ScheduledTransfer, error) {
+	var spec string
+	if recurrence != nil {
+		spec = *recurrence
 	}
 
-	// Handle same address transfer
-	if input.FromAddress == input.ToAddress {
-		tx := r.Db.Begin()
-		if tx.Error != nil {
-			return nil, eresolvers.BeginTransactionError
-		}
+	var scheduled *db.ScheduledTransfer
+	err := r.Ledger.InTx(ctx, func(tx *gorm.DB) error {
+		var err error
+		scheduled, err = scheduler.Schedule(ctx, tx, input.FromAddress, input.ToAddress, input.Amount, executeAt, spec)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toScheduledTransfer(scheduled), nil
+}
+
+// CancelScheduledTransfer is the resolver for the cancelScheduledTransfer field.
+func (r *mutationResolver) CancelScheduledTransfer(ctx context.Context, id string) (*model.ScheduledTransfer, error) {
+	scheduledID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.Ledger.InTx(ctx, func(tx *gorm.DB) error {
+		_, err := scheduler.Cancel(ctx, tx, scheduledID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled, err := scheduler.Get(r.Db.WithContext(ctx), scheduledID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toScheduledTransfer(scheduled), nil
+}
+
+// CreateVesting is the resolver for the createVesting field.
+func (r *mutationResolver) CreateVesting(ctx context.Context, beneficiary address.Address, total decimal.Decimal, start time.Time, cliff int, duration int) (*model.Vesting, error) {
+	_, err := auth.RequireOperator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := vesting.Create(ctx, r.Ledger, beneficiary, total, start, int64(cliff), int64(duration))
+	if err != nil {
+		return nil, err
+	}
+
+	return toVesting(created, time.Now()), nil
+}
+
+// Release is the resolver for the release field.
+func (r *mutationResolver) Release(ctx context.Context, vestingID string) (*model.Vesting, error) {
+	id, err := parseID(vestingID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	released, err := vesting.Release(ctx, r.Ledger, id, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return toVesting(released, now), nil
+}
+
+// CreateStream is the resolver for the createStream field.
+func (r *mutationResolver) CreateStream(ctx context.Context, from address.Address, to address.Address, ratePerSecond decimal.Decimal, start time.Time, stop time.Time) (*model.Stream, error) {
+	created, err := stream.Create(ctx, r.Ledger, from, to, ratePerSecond, start, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	return toStream(created, time.Now()), nil
+}
 
-		senderAccount := db.Account{}
-		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			Where("address = ?", input.FromAddress).
-			First(&senderAccount).Error
+// WithdrawFromStream is the resolver for the withdrawFromStream field.
+func (r *mutationResolver) WithdrawFromStream(ctx context.Context, streamID string, amount *decimal.Decimal) (*model.Stream, error) {
+	id, err := parseID(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updated, err := stream.Withdraw(ctx, r.Ledger, id, amount, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return toStream(updated, now), nil
+}
+
+// CancelStream is the resolver for the cancelStream field.
+func (r *mutationResolver) CancelStream(ctx context.Context, streamID string) (*model.Stream, error) {
+	id, err := parseID(streamID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cancelled, err := stream.Cancel(ctx, r.Ledger, id, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return toStream(cancelled, now), nil
+}
+
+// FreezeAccount is the resolver for the freezeAccount field.
+func (r *mutationResolver) FreezeAccount(ctx context.Context, address address.Address, reason string) (*model.FrozenAccount, error) {
+	operator, err := auth.RequireOperator(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var frozen *db.FrozenAccount
+	err = r.Ledger.InTx(ctx, func(tx *gorm.DB) error {
+		var err error
+		frozen, err = controls.Freeze(ctx, tx, address, reason, operator)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toFrozenAccount(frozen), nil
+}
+
+// UnfreezeAccount is the resolver for the unfreezeAccount field.
+func (r *mutationResolver) UnfreezeAccount(ctx context.Context, address address.Address) (bool, error) {
+	operator, err := auth.RequireOperator(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.Ledger.InTx(ctx, func(tx *gorm.DB) error {
+		return controls.Unfreeze(ctx, tx, address, operator)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Pause is the resolver for the pause field.
+func (r *mutationResolver) Pause(ctx context.Context, reason string) (*model.PauseState, error) {
+	return r.setPaused(ctx, true, reason)
+}
+
+// Unpause is the resolver for the unpause field.
+func (r *mutationResolver) Unpause(ctx context.Context) (*model.PauseState, error) {
+	return r.setPaused(ctx, false, "")
+}
+
+// CreateSnapshot is the resolver for the createSnapshot field.
+func (r *mutationResolver) CreateSnapshot(ctx context.Context) (*model.Snapshot, error) {
+	s, err := snapshot.Create(ctx, r.Db)
+	if err != nil {
+		return nil, err
+	}
+
+	return toSnapshot(s), nil
+}
+
+// Token is the resolver for the token field.
+func (r *queryResolver) Token(ctx context.Context) (*model.Token, error) {
+	stored, err := genesis.Get(r.readDb(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, egenesis.NotSeededError
+	}
+
+	return toToken(stored), nil
+}
+
+// Balance is the resolver for the balance field.
+func (r *queryResolver) Balance(ctx context.Context, address address.Address) (*model.Balance, error) {
+	balance, err := r.readLedger(ctx).Balance(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// streams need the database, there are none with the ledger kept in memory
+	incoming, outgoing := decimal.Zero, decimal.Zero
+	if r.Db != nil {
+		incoming, outgoing, err = stream.Balances(r.readDb(ctx), address, time.Now())
 		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return nil, eresolvers.AddressNotFoundError{Address: input.FromAddress}
-			}
-			tx.Rollback()
-			return nil, eresolvers.AddressRetrievalError{Address: input.FromAddress}
+			return nil, err
 		}
+	}
 
-		if senderAccount.Amount.LessThan(input.Amount) {
-			tx.Rollback()
-			return nil, eresolvers.InsufficientBalanceError
-		}
+	return &model.Balance{
+		Address:      address,
+		Balance:      balance,
+		StreamingIn:  incoming,
+		StreamingOut: outgoing,
+		Total:        balance.Add(incoming).Add(outgoing),
+	}, nil
+}
+
+// SimulateTransfer is the resolver for the simulateTransfer field.
+func (r *queryResolver) SimulateTransfer(ctx context.Context, input model.Transfer) (*model.TransferSimulation, error) {
+	simulation, err := r.readLedger(ctx).Simulate(ctx, input.FromAddress, input.ToAddress, input.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	return toTransferSimulation(simulation), nil
+}
+
+// ScheduledTransfer is the resolver for the scheduledTransfer field.
+func (r *queryResolver) ScheduledTransfer(ctx context.Context, id string) (*model.ScheduledTransfer, error) {
+	scheduledID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled, err := scheduler.Get(r.readDb(ctx), scheduledID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toScheduledTransfer(scheduled), nil
+}
+
+// ScheduledTransfers is the resolver for the scheduledTransfers field.
+func (r *queryResolver) ScheduledTransfers(ctx context.Context, fromAddress address.Address) ([]*model.ScheduledTransfer, error) {
+	scheduled, err := scheduler.ListBySender(r.readDb(ctx), fromAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*model.ScheduledTransfer, 0, len(scheduled))
+	for _, s := range scheduled {
+		res = append(res, toScheduledTransfer(&s))
+	}
+	return res, nil
+}
+
+// Vesting is the resolver for the vesting field.
+func (r *queryResolver) Vesting(ctx context.Context, id string) (*model.Vesting, error) {
+	vestingID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := vesting.Get(r.readDb(ctx), vestingID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toVesting(found, time.Now()), nil
+}
+
+// Vestings is the resolver for the vestings field.
+func (r *queryResolver) Vestings(ctx context.Context, beneficiary address.Address) ([]*model.Vesting, error) {
+	vestings, err := vesting.ListByBeneficiary(r.readDb(ctx), beneficiary)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	res := make([]*model.Vesting, 0, len(vestings))
+	for _, v := range vestings {
+		res = append(res, toVesting(&v, now))
+	}
+	return res, nil
+}
+
+// Vested is the resolver for the vested field.
+func (r *queryResolver) Vested(ctx context.Context, address address.Address) (*decimal.Decimal, error) {
+	vested, err := vesting.Vested(r.readDb(ctx), address, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &vested, nil
+}
+
+// Releasable is the resolver for the releasable field.
+func (r *queryResolver) Releasable(ctx context.Context, address address.Address) (*decimal.Decimal, error) {
+	releasable, err := vesting.Releasable(r.readDb(ctx), address, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &releasable, nil
+}
+
+// Stream is the resolver for the stream field.
+func (r *queryResolver) Stream(ctx context.Context, id string) (*model.Stream, error) {
+	streamID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
 
-		tx.Commit()
-		return &model.Sender{Balance: senderAccount.Amount}, nil
+	found, err := stream.Get(r.readDb(ctx), streamID)
+	if err != nil {
+		return nil, err
 	}
 
-	// handle transfer between two different accounts
-	tx := r.Db.Begin()
-	if tx.Error != nil {
-		return nil, eresolvers.BeginTransactionError
+	return toStream(found, time.Now()), nil
+}
+
+// Streams is the resolver for the streams field.
+func (r *queryResolver) Streams(ctx context.Context, address address.Address) ([]*model.Stream, error) {
+	streams, err := stream.ListByAddress(r.readDb(ctx), address)
+	if err != nil {
+		return nil, err
 	}
 
-	var addressesToLock []string
-	addressesToLock = append(addressesToLock, input.FromAddress.Hex())
-	addressesToLock = append(addressesToLock, input.ToAddress.Hex())
-	sort.Strings(addressesToLock)
+	now := time.Now()
+	res := make([]*model.Stream, 0, len(streams))
+	for _, s := range streams {
+		res = append(res, toStream(&s, now))
+	}
+	return res, nil
+}
+
+// PauseState is the resolver for the pauseState field.
+func (r *queryResolver) PauseState(ctx context.Context) (*model.PauseState, error) {
+	pause, err := controls.GetPause(r.readDb(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return toPauseState(pause), nil
+}
+
+// FrozenAccount is the resolver for the frozenAccount field.
+func (r *queryResolver) FrozenAccount(ctx context.Context, address address.Address) (*model.FrozenAccount, error) {
+	frozen, err := controls.GetFrozen(r.readDb(ctx), address)
+	if err != nil {
+		return nil, err
+	}
+	if frozen == nil {
+		return nil, nil
+	}
+
+	return toFrozenAccount(frozen), nil
+}
+
+// FrozenAccounts is the resolver for the frozenAccounts field.
+func (r *queryResolver) FrozenAccounts(ctx context.Context) ([]*model.FrozenAccount, error) {
+	frozen, err := controls.ListFrozen(r.readDb(ctx))
+	if err != nil {
+		return nil, err
+	}
 
-	accounts := make(map[string]*db.Account)
-	for _, addr := range addressesToLock {
-		account := db.Account{}
+	res := make([]*model.FrozenAccount, 0, len(frozen))
+	for _, f := range frozen {
+		res = append(res, toFrozenAccount(&f))
+	}
+	return res, nil
+}
+
+// AuditEvents is the resolver for the auditEvents field.
+func (r *queryResolver) AuditEvents(ctx context.Context, filter *model.AuditEventFilter, first *int32, after *string) (*model.AuditEventConnection, error) {
+	limit := defaultAuditPageSize
+	if first != nil {
+		if *first < 1 || *first > maxAuditPageSize {
+			return nil, eaudit.InvalidPageSizeError{First: int(*first), Max: maxAuditPageSize}
+		}
+		limit = int(*first)
+	}
+
+	var afterID uint64
+	if after != nil {
 		var err error
-		if addr == input.FromAddress.Hex() {
-			// Sender account must exist, so use First
-			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-				Where("address = ?", addr).
-				First(&account).Error
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				tx.Rollback()
-				return nil, eresolvers.AddressNotFoundError{Address: input.FromAddress}
-			}
-		} else {
-			err = tx.Clauses(clause.OnConflict{
-				Columns:   []clause.Column{{Name: "address"}},
-				DoNothing: true,
-			}).Create(&db.Account{Address: address.FromHex(addr), Amount: decimal.Zero}).Error
-			if err != nil {
-				tx.Rollback()
-				return nil, eresolvers.AddressCreationError{Address: address.FromHex(addr)}
-			}
-
-			err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-				Where(db.Account{Address: address.FromHex(addr)}).
-				First(&account).Error
-			if err != nil {
-				tx.Rollback()
-				return nil, eresolvers.AddressRetrievalError{Address: address.FromHex(addr)}
-			}
+		afterID, err = parseCursor(*after)
+		if err != nil {
+			return nil, err
 		}
-		accounts[addr] = &account
 	}
 
-	senderAccount := accounts[input.FromAddress.Hex()]
-	receiverAccount := accounts[input.ToAddress.Hex()]
+	var auditFilter audit.Filter
+	if filter != nil {
+		auditFilter = audit.Filter{Address: filter.Address, From: filter.From, To: filter.To}
+		if filter.Actor != nil {
+			auditFilter.Actor = *filter.Actor
+		}
+		if filter.Operation != nil {
+			auditFilter.Operation = *filter.Operation
+		}
+	}
 
-	if senderAccount.Amount.LessThan(input.Amount) {
-		tx.Rollback()
-		return nil, eresolvers.InsufficientBalanceError
+	// one more event than requested tells whether there is a next page
+	events, err := audit.List(r.readDb(ctx), auditFilter, afterID, limit+1)
+	if err != nil {
+		return nil, err
 	}
 
-	senderAccount.Amount = senderAccount.Amount.Sub(input.Amount)
-	receiverAccount.Amount = receiverAccount.Amount.Add(input.Amount)
+	return toAuditEventConnection(events, limit), nil
+}
 
-	err := tx.Model(senderAccount).Where("address = ?", senderAccount.Address).Update("Amount", senderAccount.Amount).Error
+// VerifyLedger is the resolver for the verifyLedger field.
+func (r *queryResolver) VerifyLedger(ctx context.Context, from *string, to *string) (*model.LedgerVerification, error) {
+	var fromID, toID uint64
+	var err error
+	if from != nil {
+		fromID, err = parseID(*from)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if to != nil {
+		toID, err = parseID(*to)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// a lagging replica would report a shorter chain
+	verification, err := ledger.Verify(r.readDb(db.WithPrimary(ctx)), fromID, toID)
 	if err != nil {
-		tx.Rollback()
-		return nil, eresolvers.AddressAmountUpdateError{Address: senderAccount.Address}
+		return nil, err
 	}
 
-	err = tx.Model(receiverAccount).
-		Where("address = ?", receiverAccount.Address).
-		Update("Amount", receiverAccount.Amount).Error
+	return toLedgerVerification(verification), nil
+}
+
+// Reconciliation is the resolver for the reconciliation field.
+func (r *queryResolver) Reconciliation(ctx context.Context) (*model.ReconciliationReport, error) {
+	// a lagging replica could report drift that is not there
+	ctx = db.WithPrimary(ctx)
+	report, err := reconcile.Run(ctx, r.readDb(ctx))
 	if err != nil {
-		tx.Rollback()
-		return nil, eresolvers.AddressAmountUpdateError{Address: receiverAccount.Address}
+		return nil, err
 	}
 
-	err = tx.Commit().Error
+	return toReconciliationReport(report), nil
+}
+
+// BalanceAt is the resolver for the balanceAt field.
+func (r *queryResolver) BalanceAt(ctx context.Context, address address.Address, at *time.Time, ledgerID *string) (*model.HistoricalBalance, error) {
+	position, err := r.historyPosition(ctx, at, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	balance, err := history.BalanceAt(r.readDb(ctx), address, position)
 	if err != nil {
-		return nil, eresolvers.CommitTransactionError
+		return nil, err
 	}
 
-	return &model.Sender{Balance: senderAccount.Amount}, nil
+	return toHistoricalBalance(history.Holding{Address: address, Amount: balance}, position), nil
+}
+
+// BalancesAt is the resolver for the balancesAt field.
+func (r *queryResolver) BalancesAt(ctx context.Context, at *time.Time, ledgerID *string) ([]*model.HistoricalBalance, error) {
+	position, err := r.historyPosition(ctx, at, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	holdings, err := history.BalancesAt(r.readDb(ctx), position)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*model.HistoricalBalance, 0, len(holdings))
+	for _, holding := range holdings {
+		res = append(res, toHistoricalBalance(holding, position))
+	}
+	return res, nil
+}
+
+// Snapshot is the resolver for the snapshot field.
+func (r *queryResolver) Snapshot(ctx context.Context, id string) (*model.Snapshot, error) {
+	snapshotID, err := parseID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := snapshot.Get(r.readDb(ctx), snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toSnapshot(s), nil
+}
+
+// MerkleProof is the resolver for the merkleProof field.
+func (r *queryResolver) MerkleProof(ctx context.Context, snapshotID string, address address.Address) (*model.MerkleProof, error) {
+	id, err := parseID(snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	proof, err := snapshot.MerkleProof(r.readDb(ctx), id, address)
+	if err != nil {
+		return nil, err
+	}
+	if proof == nil {
+		return nil, nil
+	}
+
+	return toMerkleProof(address, proof), nil
 }
 
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
 type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
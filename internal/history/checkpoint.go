@@ -0,0 +1,116 @@
+package history
+
+import (
+	"context"
+	"sort"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/ehistory"
+	"token-transfer-api/internal/ledger"
+
+	"gorm.io/gorm"
+)
+
+// checkpointLockKey identifies the advisory lock taken while creating a checkpoint,
+// so that replicas do not create the same checkpoint concurrently.
+const checkpointLockKey = 0x636b7074 // "ckpt"
+
+// insertBatchSize is the number of checkpoint rows inserted per statement.
+const insertBatchSize = 1000
+
+// Checkpoint records the balances of the addresses changed since the previous checkpoint
+// as of the last ledger entry. It returns the position of the new checkpoint, or zero if
+// there were no new entries or another checkpoint is being created.
+func Checkpoint(ctx context.Context, dbConnection *gorm.DB) (uint64, error) {
+	var position uint64
+	err := dbConnection.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, err := db.TryAdvisoryLock(tx, checkpointLockKey)
+		if err != nil {
+			return ehistory.CheckpointError
+		}
+		if !locked {
+			return nil
+		}
+
+		previous, err := maxID(tx.Model(&db.BalanceCheckpoint{}), "ledger_entry_id")
+		if err != nil {
+			return err
+		}
+		last, err := Position(tx, nil, 0)
+		if err != nil {
+			return err
+		}
+		if last == previous {
+			return nil
+		}
+
+		// replaying the new entries from empty balances gives the change of every address
+		changes := ledger.NewBalances()
+		changes.LastEntryID = previous
+		err = ledger.Replay(tx, changes, last)
+		if err != nil {
+			return err
+		}
+
+		addresses := make([]address.Address, 0, len(changes.Amounts))
+		for addr := range changes.Amounts {
+			addresses = append(addresses, addr)
+		}
+		sort.Slice(addresses, func(i, j int) bool {
+			return addresses[i].Hex() < addresses[j].Hex()
+		})
+
+		rows := make([]db.BalanceCheckpoint, 0, len(addresses))
+		for start := 0; start < len(addresses); start += lookupBatchSize {
+			batch := addresses[start:min(start+lookupBatchSize, len(addresses))]
+			base, err := checkpointed(tx, batch, previous)
+			if err != nil {
+				return err
+			}
+
+			for _, addr := range batch {
+				amount := changes.Get(addr)
+				if checkpointedAmount, ok := base[addr]; ok {
+					amount = amount.Add(checkpointedAmount)
+				}
+				rows = append(rows, db.BalanceCheckpoint{LedgerEntryID: last, Address: addr, Amount: amount})
+			}
+		}
+
+		err = tx.CreateInBatches(rows, insertBatchSize).Error
+		if err != nil {
+			return ehistory.CheckpointError
+		}
+		position = last
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return position, nil
+}
+
+// checkpointed returns the checkpointed balances of the addresses at position.
+func checkpointed(tx *gorm.DB, addresses []address.Address, position uint64) (map[address.Address]decimal.Decimal, error) {
+	hexes := make([]string, 0, len(addresses))
+	for _, addr := range addresses {
+		hexes = append(hexes, addr.Hex())
+	}
+
+	var rows []db.BalanceCheckpoint
+	err := tx.Raw(`SELECT c.ledger_entry_id, c.address, c.amount FROM balance_checkpoints c
+WHERE c.address IN ? AND c.ledger_entry_id = (
+	SELECT MAX(ledger_entry_id) FROM balance_checkpoints WHERE address = c.address AND ledger_entry_id <= ?
+)`, hexes, position).Scan(&rows).Error
+	if err != nil {
+		return nil, ehistory.HistoryRetrievalError
+	}
+
+	res := make(map[address.Address]decimal.Decimal, len(rows))
+	for _, row := range rows {
+		res[row.Address] = row.Amount
+	}
+	return res, nil
+}
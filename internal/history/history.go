@@ -0,0 +1,148 @@
+// Package history answers balance queries at past ledger positions.
+//
+// Balances are computed from the latest balance checkpoint at or before the requested
+// position plus a replay of the ledger entries after it, so the answer is exact while
+// only the entries since the checkpoint have to be read.
+package history
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/ehistory"
+	"token-transfer-api/internal/ledger"
+
+	"gorm.io/gorm"
+)
+
+// lookupBatchSize bounds the number of addresses looked up per query.
+const lookupBatchSize = 1000
+
+// Holding is the balance of an address at a ledger position.
+type Holding struct {
+	Address address.Address
+	Amount  decimal.Decimal
+}
+
+// Position resolves the ledger position a historical query refers to: the entry ledgerID,
+// the last entry created at or before at, or the last entry if neither is given.
+// Zero is the position before the first entry, where every balance is zero.
+func Position(tx *gorm.DB, at *time.Time, ledgerID uint64) (uint64, error) {
+	if at != nil && ledgerID != 0 {
+		return 0, ehistory.ConflictingPositionError
+	}
+
+	if ledgerID != 0 {
+		var count int64
+		err := tx.Model(&db.LedgerEntry{}).Where("id = ?", ledgerID).Count(&count).Error
+		if err != nil {
+			return 0, ehistory.HistoryRetrievalError
+		}
+		if count == 0 {
+			return 0, ehistory.PositionNotFoundError{LedgerID: ledgerID}
+		}
+		return ledgerID, nil
+	}
+
+	query := tx.Model(&db.LedgerEntry{})
+	if at != nil {
+		query = query.Where("created_at <= ?", *at)
+	}
+	return maxID(query, "id")
+}
+
+// lastCheckpoint returns the position of the latest checkpoint at or before position.
+func lastCheckpoint(tx *gorm.DB, position uint64) (uint64, error) {
+	return maxID(tx.Model(&db.BalanceCheckpoint{}).Where("ledger_entry_id <= ?", position), "ledger_entry_id")
+}
+
+// maxID returns the greatest value of column matched by query, zero if nothing matches.
+func maxID(query *gorm.DB, column string) (uint64, error) {
+	var last sql.NullInt64
+	err := query.Select("MAX(" + column + ")").Scan(&last).Error
+	if err != nil {
+		return 0, ehistory.HistoryRetrievalError
+	}
+	return uint64(last.Int64), nil
+}
+
+// BalanceAt returns the balance of addr after the ledger entry position.
+func BalanceAt(tx *gorm.DB, addr address.Address, position uint64) (decimal.Decimal, error) {
+	var checkpoints []db.BalanceCheckpoint
+	err := tx.Where("address = ? AND ledger_entry_id <= ?", addr.Hex(), position).
+		Order("ledger_entry_id DESC").
+		Limit(1).
+		Find(&checkpoints).Error
+	if err != nil {
+		return decimal.Zero, ehistory.HistoryRetrievalError
+	}
+
+	balances := ledger.NewBalances()
+	if len(checkpoints) > 0 {
+		balances.Amounts[addr] = checkpoints[0].Amount
+		balances.LastEntryID = checkpoints[0].LedgerEntryID
+	}
+
+	var entries []db.LedgerEntry
+	err = tx.Where("id > ? AND id <= ?", balances.LastEntryID, position).
+		Where("from_address = ? OR to_address = ?", addr.Hex(), addr.Hex()).
+		Order("id").
+		Find(&entries).Error
+	if err != nil {
+		return decimal.Zero, ehistory.HistoryRetrievalError
+	}
+
+	for i := range entries {
+		balances.Apply(&entries[i])
+	}
+	return balances.Get(addr), nil
+}
+
+// BalancesAt returns the non-zero balances of all addresses after the ledger entry
+// position, ordered by address.
+func BalancesAt(tx *gorm.DB, position uint64) ([]Holding, error) {
+	if position == 0 {
+		return []Holding{}, nil
+	}
+
+	checkpoint, err := lastCheckpoint(tx, position)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := ledger.NewBalances()
+	if checkpoint != 0 {
+		// the balance of every address at the checkpoint is its latest row
+		var rows []db.BalanceCheckpoint
+		err = tx.Raw(`SELECT c.ledger_entry_id, c.address, c.amount FROM balance_checkpoints c
+WHERE c.ledger_entry_id = (
+	SELECT MAX(ledger_entry_id) FROM balance_checkpoints WHERE address = c.address AND ledger_entry_id <= ?
+)`, checkpoint).Scan(&rows).Error
+		if err != nil {
+			return nil, ehistory.HistoryRetrievalError
+		}
+		for _, row := range rows {
+			balances.Amounts[row.Address] = row.Amount
+		}
+		balances.LastEntryID = checkpoint
+	}
+
+	err = ledger.Replay(tx, balances, position)
+	if err != nil {
+		return nil, err
+	}
+
+	holdings := make([]Holding, 0, len(balances.Amounts))
+	for addr, amount := range balances.Amounts {
+		if !amount.IsZero() {
+			holdings = append(holdings, Holding{Address: addr, Amount: amount})
+		}
+	}
+	sort.Slice(holdings, func(i, j int) bool {
+		return holdings[i].Address.Hex() < holdings[j].Address.Hex()
+	})
+	return holdings, nil
+}
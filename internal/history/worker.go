@@ -0,0 +1,34 @@
+package history
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Worker periodically creates balance checkpoints.
+type Worker struct {
+	Db       *gorm.DB
+	Interval time.Duration
+}
+
+// Run creates a checkpoint every Interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := Checkpoint(ctx, w.Db)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("history: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
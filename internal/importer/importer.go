@@ -0,0 +1,314 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eimport"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// DefaultBatchSize is the number of rows imported per transaction.
+const DefaultBatchSize = 5000
+
+// stagingTable receives the rows of a batch with COPY.
+const stagingTable = "import_balances"
+
+// insertBatchSize is the number of staged rows inserted per statement into SQLite.
+const insertBatchSize = 500
+
+// Options configure an import.
+type Options struct {
+	// DryRun computes the changes without applying them.
+	DryRun bool
+	// BatchSize is the number of rows imported per transaction, DefaultBatchSize if zero.
+	BatchSize int
+}
+
+// Change is the difference between the current and the imported balance of an address.
+type Change struct {
+	Address address.Address
+	Current decimal.Decimal
+	New     decimal.Decimal
+}
+
+// Delta is the amount minted, or burned if negative, to apply the change.
+func (c Change) Delta() decimal.Decimal {
+	return c.New.Sub(c.Current)
+}
+
+// Result is the outcome of an import.
+type Result struct {
+	Rows int
+	// Changes lists the balances that differ from the imported ones, ordered by address within each batch.
+	Changes []Change
+	Minted  decimal.Decimal
+	Burned  decimal.Decimal
+}
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// Run sets the balances of the rows. Each batch is copied into a staging table
+// and applied in its own transaction: changed balances are upserted, increases are
+// recorded as mints and decreases as burns in the ledger, and the batch is recorded
+// in the audit log. A dry run computes the changes of all batches in a single
+// repeatable read transaction, which is rolled back.
+func Run(ctx context.Context, dbConnection *gorm.DB, rows []Row, opts Options) (*Result, error) {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	result := &Result{Rows: len(rows), Minted: decimal.Zero, Burned: decimal.Zero}
+	// COPY needs the pgx connection underlying the transaction, so pin one,
+	// the staging table of SQLite lives on the connection too
+	err := dbConnection.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		sqlConn, ok := conn.Statement.ConnPool.(*sql.Conn)
+		if !ok {
+			return eimport.ImportError
+		}
+
+		if opts.DryRun {
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				for start := 0; start < len(rows); start += batchSize {
+					changes, err := stage(ctx, tx, sqlConn, rows[start:min(start+batchSize, len(rows))], false)
+					if err != nil {
+						return err
+					}
+					result.add(changes)
+				}
+				return errDryRun
+			}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+			if errors.Is(err, errDryRun) {
+				return nil
+			}
+			return err
+		}
+
+		for start := 0; start < len(rows); start += batchSize {
+			batch := rows[start:min(start+batchSize, len(rows))]
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				changes, err := stage(ctx, tx, sqlConn, batch, true)
+				if err != nil {
+					return err
+				}
+
+				err = apply(ctx, tx, len(batch), changes)
+				if err != nil {
+					return err
+				}
+				result.add(changes)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *Result) add(changes []Change) {
+	minted, burned := totals(changes)
+	r.Changes = append(r.Changes, changes...)
+	r.Minted = r.Minted.Add(minted)
+	r.Burned = r.Burned.Add(burned)
+}
+
+// totals returns the amounts minted and burned by the changes.
+func totals(changes []Change) (minted, burned decimal.Decimal) {
+	minted, burned = decimal.Zero, decimal.Zero
+	for _, change := range changes {
+		delta := change.Delta()
+		if delta.GreaterThan(decimal.Zero) {
+			minted = minted.Add(delta)
+		} else {
+			burned = burned.Sub(delta)
+		}
+	}
+	return minted, burned
+}
+
+// stagedRow is a row of the staging table.
+type stagedRow struct {
+	Address address.Address
+	Amount  decimal.Decimal
+}
+
+// stage copies the batch into the staging table and returns the balances it changes.
+// With lock the affected accounts are locked in the order transfers lock them.
+//
+// SQLite has no COPY and no row locks: the batch is inserted and its transaction,
+// which began IMMEDIATE, holds the write lock of the database instead.
+func stage(ctx context.Context, tx *gorm.DB, sqlConn *sql.Conn, batch []Row, lock bool) ([]Change, error) {
+	sqlite := db.IsSQLite(tx)
+	// the collation of PostgreSQL may not compare bytewise, SQLite compares bytewise by default
+	collate := ` COLLATE "C"`
+	if sqlite {
+		collate = ""
+	}
+
+	if sqlite {
+		err := tx.Exec("CREATE TEMP TABLE IF NOT EXISTS " + stagingTable +
+			" (address varchar(42) PRIMARY KEY, amount text NOT NULL)").Error
+		if err != nil {
+			return nil, eimport.ImportError
+		}
+		err = tx.Exec("DELETE FROM " + stagingTable).Error
+		if err != nil {
+			return nil, eimport.ImportError
+		}
+
+		staged := make([]stagedRow, 0, len(batch))
+		for _, row := range batch {
+			staged = append(staged, stagedRow{Address: row.Address, Amount: row.Amount})
+		}
+		err = tx.Table(stagingTable).CreateInBatches(staged, insertBatchSize).Error
+		if err != nil {
+			return nil, eimport.ImportError
+		}
+	} else {
+		err := copyBatch(ctx, tx, sqlConn, batch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if lock && !sqlite {
+		// addresses are compared bytewise, like the sorted addresses locked by transfers
+		err := tx.Exec(`SELECT 1 FROM accounts
+WHERE address IN (SELECT address FROM ` + stagingTable + `)
+ORDER BY address` + collate + ` FOR UPDATE`).Error
+		if err != nil {
+			return nil, eimport.ImportError
+		}
+	}
+
+	var changes []Change
+	err := tx.Raw(`SELECT i.address, COALESCE(a.amount, '0') AS current, i.amount AS new
+FROM ` + stagingTable + ` i LEFT JOIN (` + db.BalancesSQL(tx) + `) a ON a.address = i.address
+WHERE COALESCE(a.amount, '0') <> i.amount
+ORDER BY i.address` + collate).Scan(&changes).Error
+	if err != nil {
+		return nil, eimport.ImportError
+	}
+	return changes, nil
+}
+
+// copyBatch creates the PostgreSQL staging table and copies the batch into it.
+func copyBatch(ctx context.Context, tx *gorm.DB, sqlConn *sql.Conn, batch []Row) error {
+	err := tx.Exec("CREATE TEMP TABLE IF NOT EXISTS " + stagingTable +
+		" (address varchar(42) PRIMARY KEY, amount numeric(78,0) NOT NULL) ON COMMIT DROP").Error
+	if err != nil {
+		return eimport.ImportError
+	}
+	err = tx.Exec("TRUNCATE " + stagingTable).Error
+	if err != nil {
+		return eimport.ImportError
+	}
+
+	records := make([][]any, 0, len(batch))
+	for _, row := range batch {
+		records = append(records, []any{
+			row.Address.Hex(),
+			pgtype.Numeric{Int: row.Amount.BigInt(), Valid: true},
+		})
+	}
+	err = sqlConn.Raw(func(driverConn any) error {
+		conn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return eimport.ImportError
+		}
+		_, err := conn.Conn().CopyFrom(ctx, pgx.Identifier{stagingTable}, []string{"address", "amount"}, pgx.CopyFromRows(records))
+		return err
+	})
+	if err != nil {
+		return eimport.ImportError
+	}
+	return nil
+}
+
+// mergeShards moves the shards of the staged hot accounts back into their accounts,
+// so that their balances can be replaced. The accounts are locked by stage, which
+// keeps transfers from debiting or crediting the shards meanwhile.
+func mergeShards(tx *gorm.DB) error {
+	if db.IsSQLite(tx) {
+		return nil
+	}
+
+	err := tx.Exec(`UPDATE accounts a SET amount = a.amount + s.amount
+FROM (SELECT address, SUM(amount) AS amount FROM account_shards
+WHERE address IN (SELECT address FROM ` + stagingTable + `) GROUP BY address) s
+WHERE a.address = s.address`).Error
+	if err != nil {
+		return eimport.ImportError
+	}
+	err = tx.Exec(`DELETE FROM account_shards WHERE address IN (SELECT address FROM ` + stagingTable + `)`).Error
+	if err != nil {
+		return eimport.ImportError
+	}
+	return nil
+}
+
+// apply upserts the changed balances of the staged batch and records the changes.
+func apply(ctx context.Context, tx *gorm.DB, rows int, changes []Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	err := mergeShards(tx)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Exec(`INSERT INTO accounts (address, amount)
+SELECT i.address, i.amount FROM ` + stagingTable + ` i
+WHERE i.amount <> COALESCE((SELECT a.amount FROM accounts a WHERE a.address = i.address), '0')
+ON CONFLICT (address) DO UPDATE SET amount = EXCLUDED.amount`).Error
+	if err != nil {
+		return eimport.ImportError
+	}
+
+	entries := make([]*db.LedgerEntry, 0, len(changes))
+	balances := make([]audit.BalanceChange, 0, len(changes))
+	for _, change := range changes {
+		delta := change.Delta()
+		if delta.GreaterThan(decimal.Zero) {
+			entries = append(entries, &db.LedgerEntry{Kind: db.LedgerEntryMint, ToAddress: change.Address, Amount: delta})
+		} else {
+			entries = append(entries, &db.LedgerEntry{Kind: db.LedgerEntryBurn, FromAddress: change.Address, Amount: decimal.Zero.Sub(delta)})
+		}
+		balances = append(balances, audit.BalanceChange{Address: change.Address, Before: change.Current, After: change.New})
+	}
+
+	err = db.AppendLedgerEntries(tx, entries...)
+	if err != nil {
+		return eimport.ImportError
+	}
+
+	minted, burned := totals(changes)
+	return audit.Record(ctx, tx, audit.Event{
+		Operation: "importBalances",
+		Details: map[string]any{
+			"rows":    rows,
+			"changes": len(changes),
+			"minted":  minted,
+			"burned":  burned,
+		},
+		Balances: balances,
+	})
+}
@@ -0,0 +1,97 @@
+// Package importer loads balances from a CSV file, e.g. when migrating users
+// from another system.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eimport"
+)
+
+// Row is a validated line of an import file.
+type Row struct {
+	Line    int
+	Address address.Address
+	Amount  decimal.Decimal
+}
+
+// Parse reads address,amount records. A first record starting with "address" is
+// skipped as a header. Every invalid record is reported as an eimport.RowError,
+// the errors are joined with errors.Join.
+func Parse(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows []Row
+	var errs []error
+	lines := make(map[address.Address]int)
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				err = eimport.RowError{Line: parseErr.Line, Err: parseErr.Err}
+			}
+			// the reader cannot recover from malformed quoting, stop at the first one
+			errs = append(errs, err)
+			break
+		}
+
+		line, _ := reader.FieldPos(0)
+		if first && strings.EqualFold(strings.TrimSpace(record[0]), "address") {
+			continue
+		}
+
+		row, err := parseRecord(line, record)
+		if err != nil {
+			errs = append(errs, eimport.RowError{Line: line, Err: err})
+			continue
+		}
+		if firstLine, ok := lines[row.Address]; ok {
+			errs = append(errs, eimport.RowError{
+				Line: line,
+				Err:  eimport.DuplicateAddressError{Address: row.Address.Hex(), FirstLine: firstLine},
+			})
+			continue
+		}
+		lines[row.Address] = line
+		rows = append(rows, row)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(rows) == 0 {
+		return nil, eimport.EmptyImportError
+	}
+	return rows, nil
+}
+
+func parseRecord(line int, record []string) (Row, error) {
+	if len(record) != 2 {
+		return Row{}, eimport.ColumnCountError{Columns: len(record)}
+	}
+
+	s := strings.TrimSpace(record[0])
+	var addr address.Address
+	err := addr.UnmarshalGQL(s)
+	if err != nil {
+		return Row{}, eimport.InvalidAddressError{Address: s}
+	}
+
+	s = strings.TrimSpace(record[1])
+	amount, err := decimal.NewFromString(s)
+	if err != nil || amount.LessThan(decimal.Zero) || !amount.IsInteger() {
+		return Row{}, eimport.InvalidAmountError{Amount: s}
+	}
+
+	return Row{Line: line, Address: addr, Amount: amount}, nil
+}
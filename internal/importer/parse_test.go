@@ -0,0 +1,68 @@
+package importer
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eimport"
+)
+
+func TestParse_SkipsHeader(t *testing.T) {
+	rows, err := Parse(strings.NewReader("address,amount\n0x1234567890123456789012345678901234567890,100\n"))
+
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "0x1234567890123456789012345678901234567890", rows[0].Address.Hex())
+	assert.True(t, rows[0].Amount.Equal(decimal.NewFromInt64(100)))
+}
+
+func TestParse_WithoutHeader(t *testing.T) {
+	rows, err := Parse(strings.NewReader("0x1234567890123456789012345678901234567890, 100\n0xabcdef1234567890abcdef1234567890abcdef12,0\n"))
+
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, 1, rows[0].Line)
+	assert.True(t, rows[1].Amount.IsZero())
+}
+
+func TestParse_ReportsAllInvalidLines(t *testing.T) {
+	data := strings.Join([]string{
+		"address,amount",
+		"0x1234,100",
+		"0x1234567890123456789012345678901234567890,-1",
+		"0x1234567890123456789012345678901234567890,1.5",
+		"0x1234567890123456789012345678901234567890",
+		"0x1234567890123456789012345678901234567890,100",
+		"0x1234567890123456789012345678901234567890,200",
+	}, "\n")
+
+	_, err := Parse(strings.NewReader(data))
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, eimport.RowError{Line: 2, Err: eimport.InvalidAddressError{Address: "0x1234"}})
+	assert.ErrorIs(t, err, eimport.RowError{Line: 3, Err: eimport.InvalidAmountError{Amount: "-1"}})
+	assert.ErrorIs(t, err, eimport.RowError{Line: 4, Err: eimport.InvalidAmountError{Amount: "1.5"}})
+	assert.ErrorIs(t, err, eimport.RowError{Line: 5, Err: eimport.ColumnCountError{Columns: 1}})
+	assert.ErrorIs(t, err, eimport.RowError{Line: 7, Err: eimport.DuplicateAddressError{
+		Address:   "0x1234567890123456789012345678901234567890",
+		FirstLine: 6,
+	}})
+}
+
+func TestParse_MalformedQuoting(t *testing.T) {
+	_, err := Parse(strings.NewReader("0x1234567890123456789012345678901234567890,100\n\"0x12,100\n"))
+
+	var rowErr eimport.RowError
+	require.True(t, errors.As(err, &rowErr))
+	assert.Equal(t, 2, rowErr.Line)
+}
+
+func TestParse_Empty(t *testing.T) {
+	_, err := Parse(strings.NewReader("address,amount\n"))
+
+	assert.ErrorIs(t, err, eimport.EmptyImportError)
+}
@@ -0,0 +1,580 @@
+package ledger
+
+import (
+	"context"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/config"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/efees"
+	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/fees"
+
+	"gorm.io/gorm"
+)
+
+// Ledger moves tokens between accounts. It is shared by the GraphQL
+// resolvers and the background workers so that every transfer goes
+// through the same validation and locking rules.
+type Ledger struct {
+	// Db is the database of the operations combining transfers with their own
+	// tables, e.g. scheduled transfers, see InTx.
+	Db *gorm.DB
+	// Store persists the accounts and the ledger entries.
+	Store Store
+	// Fees is the fee schedule charged on transfers, nil disables fees.
+	Fees fees.Schedule
+	// FeeCollector is credited with the charged fees.
+	FeeCollector address.Address
+	// ConditionalUpdates moves balances with a conditional debit and upserting
+	// credits instead of locking and reading the accounts before updating them,
+	// saving round trips while the accounts are locked, see moveConditional.
+	ConditionalUpdates bool
+	// Retry retries transfers and mints aborted by a concurrent transaction,
+	// the zero value does not retry.
+	Retry RetryPolicy
+	// HotAccounts are the accounts debited or credited by many concurrent transfers,
+	// e.g. a faucet. Their balance is split over HotAccountShards shards so that the
+	// transfers lock different rows, see Store.DebitShard.
+	HotAccounts []address.Address
+	// HotAccountShards is the number of shards of each hot account.
+	HotAccountShards int
+	// Timeouts are applied to the transactions begun by InTx, the Store applies its own.
+	Timeouts db.Timeouts
+}
+
+// New returns a Ledger stored in the database.
+func New(db *gorm.DB) *Ledger {
+	return &Ledger{Db: db, Store: NewSQLStore(db)}
+}
+
+// FromConfig returns a Ledger stored in the database and configured by cfg like the
+// server's: charging the transfer fees, retrying conflicting transfers, bounding their
+// locks and statements and sharding the hot accounts.
+func FromConfig(dbConnection *gorm.DB, cfg config.Config) *Ledger {
+	timeouts := db.Timeouts{Lock: cfg.LockTimeout, Statement: cfg.StatementTimeout}
+	store := NewSQLStore(dbConnection)
+	store.Timeouts = timeouts
+	l := &Ledger{Db: dbConnection, Store: store, Timeouts: timeouts}
+	l.Configure(cfg)
+	return l
+}
+
+// Configure applies the transfer settings of cfg that do not depend on the store.
+func (l *Ledger) Configure(cfg config.Config) {
+	l.Fees = cfg.TransferFees
+	l.FeeCollector = cfg.FeeCollector
+	l.ConditionalUpdates = cfg.ConditionalUpdates
+	l.Retry = RetryPolicy{Attempts: cfg.TransferRetryAttempts, Backoff: cfg.TransferRetryBackoff}
+	l.HotAccounts = cfg.HotAccounts
+	l.HotAccountShards = cfg.HotAccountShards
+}
+
+// Receipt describes the outcome of a transfer.
+type Receipt struct {
+	// Balance is the balance of the sender after the transfer.
+	Balance decimal.Decimal
+	// Amount is the amount debited from the sender.
+	Amount decimal.Decimal
+	// Fee is the part of Amount credited to the fee collector.
+	Fee decimal.Decimal
+	// Net is the part of Amount credited to the receiver.
+	Net decimal.Decimal
+}
+
+// InTx runs fn in a new database transaction bound to ctx. The transaction is committed
+// if fn succeeds and rolled back otherwise. Operations writing their own tables use it
+// together with TransferTx, MoveTx and MintTx.
+func (l *Ledger) InTx(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	tx, err := db.Begin(ctx, l.Db, l.Timeouts)
+	if err != nil {
+		return storeError(err, eresolvers.BeginTransactionError)
+	}
+
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return storeError(err, eresolvers.CommitTransactionError)
+	}
+
+	return nil
+}
+
+// RetryTx runs fn in a new transaction like InTx. If a concurrent transaction aborts it,
+// fn runs again in another transaction following the Retry policy, so fn must not keep
+// state across attempts. Operations settled with MoveTx use it.
+func (l *Ledger) RetryTx(ctx context.Context, operation string, fn func(tx *gorm.DB) error) error {
+	return l.Retry.retry(ctx, operation, func() error {
+		return l.InTx(ctx, fn)
+	})
+}
+
+// RetrySavepoint runs fn in a savepoint of tx. If a concurrent transaction aborts it,
+// tx is rolled back to the savepoint and fn runs again following the Retry policy.
+// A failed fn leaves tx usable, so the caller can still record the failure.
+func (l *Ledger) RetrySavepoint(ctx context.Context, tx *gorm.DB, operation string, fn func(tx *gorm.DB) error) error {
+	return l.Retry.retry(ctx, operation, func() error {
+		return tx.Transaction(fn)
+	})
+}
+
+// Transfer moves amount from one address to another in its own transaction.
+func (l *Ledger) Transfer(ctx context.Context, from, to address.Address, amount decimal.Decimal) (Receipt, error) {
+	var receipt Receipt
+	err := l.Retry.retry(ctx, "transfer", func() error {
+		return l.Store.InTx(ctx, func(tx Store) error {
+			var err error
+			receipt, err = l.transfer(ctx, tx, from, to, amount)
+			return err
+		})
+	})
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	return receipt, nil
+}
+
+// TransferTx moves amount from one address to another inside the given transaction,
+// charging the configured fee. The receiver is credited with the amount minus the fee.
+// The caller is responsible for committing or rolling back tx.
+func (l *Ledger) TransferTx(ctx context.Context, tx *gorm.DB, from, to address.Address, amount decimal.Decimal) (Receipt, error) {
+	return l.transfer(ctx, NewSQLStore(tx), from, to, amount)
+}
+
+// MoveTx moves amount from one address to another inside the given transaction without
+// charging a fee. It settles internal operations, e.g. escrow deposits and releases.
+// The caller is responsible for committing or rolling back tx.
+func (l *Ledger) MoveTx(ctx context.Context, tx *gorm.DB, from, to address.Address, amount decimal.Decimal) (Receipt, error) {
+	err := ValidateAmount(amount)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	return l.move(ctx, NewSQLStore(tx), "move", from, to, amount, decimal.Zero)
+}
+
+// Mint creates amount new tokens credited to the address in its own transaction
+// and returns the new balance of the address.
+func (l *Ledger) Mint(ctx context.Context, to address.Address, amount decimal.Decimal) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+	err := l.Retry.retry(ctx, "mint", func() error {
+		return l.Store.InTx(ctx, func(tx Store) error {
+			var err error
+			balance, err = l.mint(ctx, tx, to, amount)
+			return err
+		})
+	})
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return balance, nil
+}
+
+// MintTx creates amount new tokens credited to the address inside the given transaction.
+// The caller is responsible for committing or rolling back tx.
+func (l *Ledger) MintTx(ctx context.Context, tx *gorm.DB, to address.Address, amount decimal.Decimal) (decimal.Decimal, error) {
+	return l.mint(ctx, NewSQLStore(tx), to, amount)
+}
+
+// Balance returns the balance of the address, addresses without an account have a zero balance.
+func (l *Ledger) Balance(ctx context.Context, addr address.Address) (decimal.Decimal, error) {
+	return balance(ctx, l.Store, addr)
+}
+
+func balance(ctx context.Context, s Store, addr address.Address) (decimal.Decimal, error) {
+	account, err := s.GetAccount(ctx, addr)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	if account == nil {
+		return decimal.Zero, nil
+	}
+	return account.Amount, nil
+}
+
+// hot reports whether the address is one of the hot accounts.
+func (l *Ledger) hot(addr address.Address) bool {
+	for _, hot := range l.HotAccounts {
+		if hot == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// Fee returns the fee charged for transferring amount between the addresses.
+// Self transfers and transfers from or to the fee collector are free.
+func (l *Ledger) Fee(from, to address.Address, amount decimal.Decimal) (decimal.Decimal, error) {
+	if l.Fees == nil || from == to || from == l.FeeCollector || to == l.FeeCollector {
+		return decimal.Zero, nil
+	}
+
+	fee := l.Fees.Fee(amount)
+	if fee.GreaterThan(amount) {
+		return decimal.Zero, efees.FeeExceedsAmountError{Amount: amount, Fee: fee}
+	}
+	return fee, nil
+}
+
+// checkTransfer fails if transfers are paused or one of the addresses is frozen,
+// including the fee collector if a fee is charged, so no fees flow into a frozen account.
+// It runs once the accounts are locked, so that freezing and pausing wait for the
+// transfers which passed it, see controls.Freeze.
+func (l *Ledger) checkTransfer(ctx context.Context, s Store, from, to address.Address, fee decimal.Decimal) error {
+	if fee.GreaterThan(decimal.Zero) {
+		return s.CheckTransfer(ctx, from, to, l.FeeCollector)
+	}
+	return s.CheckTransfer(ctx, from, to)
+}
+
+func (l *Ledger) transfer(ctx context.Context, s Store, from, to address.Address, amount decimal.Decimal) (Receipt, error) {
+	err := ValidateAmount(amount)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	fee, err := l.Fee(from, to, amount)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	return l.move(ctx, s, "transfer", from, to, amount, fee)
+}
+
+// mint credits amount new tokens to the address, recording them as a mint in the ledger
+// and in the audit log. Mints are administrative operations and are not subject to
+// freezes or the pause.
+func (l *Ledger) mint(ctx context.Context, s Store, to address.Address, amount decimal.Decimal) (decimal.Decimal, error) {
+	err := ValidateAmount(amount)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	balance, err := l.credit(ctx, s, to, amount)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	err = s.RecordEvent(ctx, audit.Event{
+		Operation: "mint",
+		Subject:   to.Hex(),
+		Details:   map[string]any{"amount": amount},
+		Balances:  []audit.BalanceChange{{Address: to, Before: balance.Sub(amount), After: balance}},
+	})
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	// appended last, see recordTransfer
+	err = s.RecordEntries(ctx, &db.LedgerEntry{
+		Kind:      db.LedgerEntryMint,
+		ToAddress: to,
+		Amount:    amount,
+	})
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return balance, nil
+}
+
+// move updates the balances, appends the movement to the hash-chained ledger and
+// records it in the audit log as operation. Self transfers do not change balances
+// and are only audit-logged.
+func (l *Ledger) move(
+	ctx context.Context,
+	s Store,
+	operation string,
+	from, to address.Address,
+	amount, fee decimal.Decimal,
+) (Receipt, error) {
+	// Handle same address transfer
+	if from == to {
+		accounts, err := s.LockAccounts(ctx, []address.Address{from})
+		if err != nil {
+			return Receipt{}, err
+		}
+		err = l.checkTransfer(ctx, s, from, to, fee)
+		if err != nil {
+			return Receipt{}, err
+		}
+		senderAccount, ok := accounts[from]
+		if !ok {
+			return Receipt{}, eresolvers.AddressNotFoundError{Address: from}
+		}
+		if l.hot(from) {
+			// the locked account holds only the part of the balance not in its shards
+			senderAccount, err = s.GetAccount(ctx, from)
+			if err != nil {
+				return Receipt{}, err
+			}
+		}
+
+		if senderAccount.Amount.LessThan(amount) {
+			return Receipt{}, eresolvers.InsufficientBalanceError
+		}
+
+		receipt := Receipt{Balance: senderAccount.Amount, Amount: amount, Fee: decimal.Zero, Net: amount}
+		err = recordMove(ctx, s, operation, from, to, receipt, []audit.BalanceChange{
+			{Address: from, Before: senderAccount.Amount, After: senderAccount.Amount},
+		})
+		if err != nil {
+			return Receipt{}, err
+		}
+		return receipt, nil
+	}
+
+	// the shards of hot accounts are only moved by conditional updates
+	if l.ConditionalUpdates || l.hot(from) || l.hot(to) || (fee.GreaterThan(decimal.Zero) && l.hot(l.FeeCollector)) {
+		return l.moveConditional(ctx, s, operation, from, to, amount, fee)
+	}
+
+	// handle transfer between different accounts, the sender account must exist
+	receivers := []address.Address{to}
+	if fee.GreaterThan(decimal.Zero) {
+		receivers = append(receivers, l.FeeCollector)
+	}
+	accounts, err := s.LockAccounts(ctx, []address.Address{from}, receivers...)
+	if err != nil {
+		return Receipt{}, err
+	}
+	err = l.checkTransfer(ctx, s, from, to, fee)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	senderAccount, ok := accounts[from]
+	if !ok {
+		return Receipt{}, eresolvers.AddressNotFoundError{Address: from}
+	}
+	receiverAccount := accounts[to]
+
+	if senderAccount.Amount.LessThan(amount) {
+		return Receipt{}, eresolvers.InsufficientBalanceError
+	}
+
+	locked := sortedHex(append([]address.Address{from}, receivers...))
+	before := make(map[address.Address]decimal.Decimal, len(accounts))
+	for addr, account := range accounts {
+		before[addr] = account.Amount
+	}
+
+	net := amount.Sub(fee)
+	senderAccount.Amount = senderAccount.Amount.Sub(amount)
+	receiverAccount.Amount = receiverAccount.Amount.Add(net)
+	if collectorAccount, ok := accounts[l.FeeCollector]; ok && fee.GreaterThan(decimal.Zero) {
+		collectorAccount.Amount = collectorAccount.Amount.Add(fee)
+	}
+
+	updated := make([]*db.Account, 0, len(locked))
+	changes := make([]audit.BalanceChange, 0, len(locked))
+	for _, hex := range locked {
+		account := accounts[address.FromHex(hex)]
+		updated = append(updated, account)
+		changes = append(changes, audit.BalanceChange{Address: account.Address, Before: before[account.Address], After: account.Amount})
+	}
+	err = s.ApplyTransfer(ctx, updated...)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	receipt := Receipt{Balance: senderAccount.Amount, Amount: amount, Fee: fee, Net: net}
+	err = l.recordTransfer(ctx, s, operation, from, to, receipt, changes)
+	if err != nil {
+		return Receipt{}, err
+	}
+	return receipt, nil
+}
+
+// moveConditional moves amount between different addresses like move, but debits
+// the sender with a single conditional update and credits the receivers with
+// upserts, each returning the new balance. The accounts are still updated, and so
+// locked, in sorted address order.
+func (l *Ledger) moveConditional(
+	ctx context.Context,
+	s Store,
+	operation string,
+	from, to address.Address,
+	amount, fee decimal.Decimal,
+) (Receipt, error) {
+	net := amount.Sub(fee)
+	involved := []address.Address{from, to}
+	credits := map[address.Address]decimal.Decimal{to: net}
+	if fee.GreaterThan(decimal.Zero) {
+		involved = append(involved, l.FeeCollector)
+		credits[l.FeeCollector] = fee
+	}
+
+	receipt := Receipt{Amount: amount, Fee: fee, Net: net}
+	locked := sortedHex(involved)
+	changes := make([]audit.BalanceChange, 0, len(locked))
+	for _, hex := range locked {
+		addr := address.FromHex(hex)
+		if addr == from {
+			balance, err := l.debit(ctx, s, from, amount)
+			if err != nil {
+				return Receipt{}, err
+			}
+			receipt.Balance = balance
+			changes = append(changes, audit.BalanceChange{Address: from, Before: balance.Add(amount), After: balance})
+			continue
+		}
+
+		balance, err := l.credit(ctx, s, addr, credits[addr])
+		if err != nil {
+			return Receipt{}, err
+		}
+		changes = append(changes, audit.BalanceChange{Address: addr, Before: balance.Sub(credits[addr]), After: balance})
+	}
+
+	// checked once the updates hold the accounts, or their shards FOR KEY SHARE
+	err := l.checkTransfer(ctx, s, from, to, fee)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	err = l.recordTransfer(ctx, s, operation, from, to, receipt, changes)
+	if err != nil {
+		return Receipt{}, err
+	}
+	return receipt, nil
+}
+
+// debit subtracts amount from the balance of the address, or one of its shards if it is
+// a hot account, and returns the new balance. The balance of a hot account is read
+// after the debit without locking its other shards, concurrent transfers may have
+// changed them.
+func (l *Ledger) debit(ctx context.Context, s Store, addr address.Address, amount decimal.Decimal) (decimal.Decimal, error) {
+	if !l.hot(addr) {
+		balance, ok, err := s.Debit(ctx, addr, amount)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		if !ok {
+			return decimal.Zero, debitError(ctx, s, addr)
+		}
+		return balance, nil
+	}
+
+	ok, err := s.DebitShard(ctx, addr, amount, l.HotAccountShards)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if !ok {
+		return decimal.Zero, debitError(ctx, s, addr)
+	}
+	return balance(ctx, s, addr)
+}
+
+// credit adds amount to the balance of the address, or one of its shards if it is a
+// hot account, and returns the new balance, see debit.
+func (l *Ledger) credit(ctx context.Context, s Store, addr address.Address, amount decimal.Decimal) (decimal.Decimal, error) {
+	if !l.hot(addr) {
+		return s.Credit(ctx, addr, amount)
+	}
+
+	err := s.CreditShard(ctx, addr, amount, l.HotAccountShards)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return balance(ctx, s, addr)
+}
+
+// debitError tells why the sender could not be debited.
+func debitError(ctx context.Context, s Store, from address.Address) error {
+	account, err := s.GetAccount(ctx, from)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return eresolvers.AddressNotFoundError{Address: from}
+	}
+	return eresolvers.InsufficientBalanceError
+}
+
+// recordTransfer records the transfer in the audit log and appends its net amount and
+// fee to the ledger. Appending serializes all transfers until they commit, see
+// db.AppendLedgerEntries, so it is the last statement of the transfer.
+func (l *Ledger) recordTransfer(
+	ctx context.Context,
+	s Store,
+	operation string,
+	from, to address.Address,
+	receipt Receipt,
+	changes []audit.BalanceChange,
+) error {
+	entries := []*db.LedgerEntry{{
+		Kind:        db.LedgerEntryTransfer,
+		FromAddress: from,
+		ToAddress:   to,
+		Amount:      receipt.Net,
+	}}
+	if receipt.Fee.GreaterThan(decimal.Zero) {
+		entries = append(entries, &db.LedgerEntry{
+			Kind:        db.LedgerEntryFee,
+			FromAddress: from,
+			ToAddress:   l.FeeCollector,
+			Amount:      receipt.Fee,
+		})
+	}
+	err := recordMove(ctx, s, operation, from, to, receipt, changes)
+	if err != nil {
+		return err
+	}
+
+	return s.RecordEntries(ctx, entries...)
+}
+
+func recordMove(
+	ctx context.Context,
+	s Store,
+	operation string,
+	from, to address.Address,
+	receipt Receipt,
+	changes []audit.BalanceChange,
+) error {
+	return s.RecordEvent(ctx, audit.Event{
+		Operation: operation,
+		Subject:   from.Hex(),
+		Details: map[string]any{
+			"from":   from.Hex(),
+			"to":     to.Hex(),
+			"amount": receipt.Amount,
+			"fee":    receipt.Fee,
+			"net":    receipt.Net,
+		},
+		Balances: changes,
+	})
+}
+
+// LockAccounts locks the existing accounts of the given addresses in sorted order.
+// Operations performing several transfers in one transaction use it to take
+// all their locks upfront, in the same order TransferTx does, to avoid deadlocks.
+func (l *Ledger) LockAccounts(tx *gorm.DB, addresses ...address.Address) error {
+	_, err := NewSQLStore(tx).LockAccounts(tx.Statement.Context, addresses)
+	return err
+}
+
+// ValidateAmount checks that amount can be transferred,
+// i.e. it is a non-negative integer.
+func ValidateAmount(amount decimal.Decimal) error {
+	// do not allow negative transfers
+	if amount.LessThan(decimal.Zero) {
+		return eresolvers.NegativeTransferError
+	}
+
+	// only allow int values
+	if !amount.IsInteger() {
+		return eresolvers.NonIntegerTransferError
+	}
+
+	return nil
+}
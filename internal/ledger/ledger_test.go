@@ -0,0 +1,159 @@
+package ledger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/fees"
+)
+
+// newMemoryLedger returns a Ledger kept in memory holding the given balances.
+func newMemoryLedger(t *testing.T, balances map[address.Address]int64) (*Ledger, *MemoryStore) {
+	t.Helper()
+	store := NewMemoryStore()
+	l := &Ledger{Store: store}
+	for addr, amount := range balances {
+		_, err := l.Mint(context.Background(), addr, decimal.NewFromInt64(amount))
+		require.NoError(t, err)
+	}
+	return l, store
+}
+
+func assertStoredBalance(t *testing.T, l *Ledger, addr address.Address, expected int64) {
+	t.Helper()
+	balance, err := l.Balance(context.Background(), addr)
+	require.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.NewFromInt64(expected)), "expected %s to hold %d, got %s", addr, expected, balance)
+}
+
+func TestLedger_Transfer(t *testing.T) {
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 1000})
+	l.Fees = fees.Flat{Amount: decimal.NewFromInt64(10)}
+	l.FeeCollector = testCollector
+
+	receipt, err := l.Transfer(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(100))
+
+	require.NoError(t, err)
+	assert.True(t, receipt.Balance.Equal(decimal.NewFromInt64(900)))
+	assert.True(t, receipt.Net.Equal(decimal.NewFromInt64(90)))
+	assertStoredBalance(t, l, testReceiver, 90)
+	assertStoredBalance(t, l, testCollector, 10)
+	entries := store.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, db.LedgerEntryTransfer, entries[1].Kind)
+	assert.Equal(t, db.LedgerEntryFee, entries[2].Kind)
+	events := store.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "transfer", events[1].Operation)
+}
+
+func TestLedger_TransferInsufficientBalance(t *testing.T) {
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 10})
+
+	_, err := l.Transfer(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(11))
+
+	assert.ErrorIs(t, err, eresolvers.InsufficientBalanceError)
+	assert.Len(t, store.Entries(), 1)
+	assertStoredBalance(t, l, testTreasury, 10)
+}
+
+func TestLedger_TransferFromMissingAccount(t *testing.T) {
+	l, _ := newMemoryLedger(t, nil)
+
+	_, err := l.Transfer(context.Background(), testReceiver, testTreasury, decimal.NewFromInt64(1))
+
+	assert.Equal(t, eresolvers.AddressNotFoundError{Address: testReceiver}, err)
+}
+
+func TestLedger_TransferToSelf(t *testing.T) {
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 10})
+
+	receipt, err := l.Transfer(context.Background(), testTreasury, testTreasury, decimal.NewFromInt64(5))
+
+	require.NoError(t, err)
+	assert.True(t, receipt.Balance.Equal(decimal.NewFromInt64(10)))
+	assert.Len(t, store.Entries(), 1)
+}
+
+func TestLedger_Mint(t *testing.T) {
+	l, store := newMemoryLedger(t, nil)
+
+	balance, err := l.Mint(context.Background(), testReceiver, decimal.NewFromInt64(50))
+
+	require.NoError(t, err)
+	assert.True(t, balance.Equal(decimal.NewFromInt64(50)))
+	entries := store.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, db.LedgerEntryMint, entries[0].Kind)
+}
+
+func TestLedger_TransferConditionalUpdates(t *testing.T) {
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 1000})
+	l.Fees = fees.Flat{Amount: decimal.NewFromInt64(10)}
+	l.FeeCollector = testCollector
+	l.ConditionalUpdates = true
+
+	receipt, err := l.Transfer(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(100))
+
+	require.NoError(t, err)
+	assert.True(t, receipt.Balance.Equal(decimal.NewFromInt64(900)))
+	assertStoredBalance(t, l, testTreasury, 900)
+	assertStoredBalance(t, l, testReceiver, 90)
+	assertStoredBalance(t, l, testCollector, 10)
+	require.Len(t, store.Entries(), 3)
+	events := store.Events()
+	require.Len(t, events, 2)
+	before := decimal.Zero
+	for _, change := range events[1].Balances {
+		balance, err := l.Balance(context.Background(), change.Address)
+		require.NoError(t, err)
+		assert.True(t, change.After.Equal(balance), "unexpected balance change of %s", change.Address)
+		before = before.Add(change.Before)
+	}
+	assert.True(t, before.Equal(decimal.NewFromInt64(1000)))
+}
+
+func TestLedger_TransferConditionalUpdatesFailures(t *testing.T) {
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 10})
+	l.ConditionalUpdates = true
+
+	_, insufficientErr := l.Transfer(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(11))
+	_, missingErr := l.Transfer(context.Background(), testReceiver, testTreasury, decimal.NewFromInt64(1))
+
+	assert.ErrorIs(t, insufficientErr, eresolvers.InsufficientBalanceError)
+	assert.Equal(t, eresolvers.AddressNotFoundError{Address: testReceiver}, missingErr)
+	assert.Len(t, store.Entries(), 1)
+	assertStoredBalance(t, l, testTreasury, 10)
+	account, err := store.GetAccount(context.Background(), testReceiver)
+	require.NoError(t, err)
+	assert.Nil(t, account)
+}
+
+func TestLedger_HotAccounts(t *testing.T) {
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 1000})
+	l.Fees = fees.Flat{Amount: decimal.NewFromInt64(10)}
+	l.FeeCollector = testCollector
+	l.HotAccounts = []address.Address{testTreasury, testCollector}
+	l.HotAccountShards = 4
+
+	receipt, transferErr := l.Transfer(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(100))
+	balance, mintErr := l.Mint(context.Background(), testTreasury, decimal.NewFromInt64(50))
+	_, selfErr := l.Transfer(context.Background(), testTreasury, testTreasury, decimal.NewFromInt64(950))
+
+	require.NoError(t, transferErr)
+	require.NoError(t, mintErr)
+	require.NoError(t, selfErr)
+	assert.True(t, receipt.Balance.Equal(decimal.NewFromInt64(900)))
+	assert.True(t, balance.Equal(decimal.NewFromInt64(950)))
+	assertStoredBalance(t, l, testReceiver, 90)
+	assertStoredBalance(t, l, testCollector, 10)
+	events := store.Events()
+	require.Len(t, events, 4)
+	assert.Equal(t, "mint", events[2].Operation)
+	assert.True(t, events[2].Balances[0].Before.Equal(decimal.NewFromInt64(900)))
+}
@@ -0,0 +1,380 @@
+package ledger
+
+import (
+	"context"
+	"sync"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eresolvers"
+)
+
+// MemoryStore keeps the ledger in memory, e.g. for tests and local demos. Like
+// SQLStore it locks accounts in sorted address order until the end of the
+// transaction and serializes appends to the hash chain, so it gives transfers the
+// same isolation. Like controls.CheckTransfer, CheckTransfer fails while transfers
+// are paused or an address is frozen, see SetPaused and SetFrozen. The controls are
+// not persisted and the server cannot change them without a database.
+type MemoryStore struct {
+	// mu guards the maps and slices below, the locks order the transactions
+	mu       sync.Mutex
+	accounts map[address.Address]decimal.Decimal
+	locks    map[address.Address]lock
+	entries  []db.LedgerEntry
+	events   []audit.Event
+	paused   bool
+	frozen   map[address.Address]bool
+	// ledgerLock serializes appends to the hash chain, like the advisory lock of SQLStore
+	ledgerLock lock
+}
+
+// lock is a mutex whose acquisition can be cancelled with a context.
+type lock chan struct{}
+
+func (l lock) acquire(ctx context.Context) error {
+	select {
+	case l <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l lock) release() {
+	<-l
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		accounts:   make(map[address.Address]decimal.Decimal),
+		locks:      make(map[address.Address]lock),
+		frozen:     make(map[address.Address]bool),
+		ledgerLock: make(lock, 1),
+	}
+}
+
+func (s *MemoryStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	tx := &memoryTx{store: s, balances: make(map[address.Address]decimal.Decimal)}
+	err := fn(tx)
+	if err != nil {
+		tx.end(false)
+		return err
+	}
+
+	tx.end(true)
+	return nil
+}
+
+func (s *MemoryStore) GetAccount(ctx context.Context, addr address.Address) (*db.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	amount, ok := s.accounts[addr]
+	if !ok {
+		return nil, nil
+	}
+	return &db.Account{Address: addr, Amount: amount}, nil
+}
+
+func (s *MemoryStore) LockAccounts(ctx context.Context, addresses []address.Address, upsert ...address.Address) (map[address.Address]*db.Account, error) {
+	// the locks would be released right away, so only create the missing accounts
+	err := s.InTx(ctx, func(tx Store) error {
+		_, err := tx.LockAccounts(ctx, addresses, upsert...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make(map[address.Address]*db.Account)
+	for _, addr := range append(append([]address.Address{}, addresses...), upsert...) {
+		account, _ := s.GetAccount(ctx, addr)
+		if account != nil {
+			accounts[addr] = account
+		}
+	}
+	return accounts, nil
+}
+
+func (s *MemoryStore) ApplyTransfer(ctx context.Context, accounts ...*db.Account) error {
+	return s.InTx(ctx, func(tx Store) error {
+		return tx.ApplyTransfer(ctx, accounts...)
+	})
+}
+
+func (s *MemoryStore) Debit(ctx context.Context, addr address.Address, amount decimal.Decimal) (decimal.Decimal, bool, error) {
+	var balance decimal.Decimal
+	var ok bool
+	err := s.InTx(ctx, func(tx Store) error {
+		var err error
+		balance, ok, err = tx.Debit(ctx, addr, amount)
+		return err
+	})
+	return balance, ok, err
+}
+
+func (s *MemoryStore) Credit(ctx context.Context, addr address.Address, amount decimal.Decimal) (decimal.Decimal, error) {
+	var balance decimal.Decimal
+	err := s.InTx(ctx, func(tx Store) error {
+		var err error
+		balance, err = tx.Credit(ctx, addr, amount)
+		return err
+	})
+	return balance, err
+}
+
+func (s *MemoryStore) DebitShard(ctx context.Context, addr address.Address, amount decimal.Decimal, shards int) (bool, error) {
+	_, ok, err := s.Debit(ctx, addr, amount)
+	return ok, err
+}
+
+func (s *MemoryStore) CreditShard(ctx context.Context, addr address.Address, amount decimal.Decimal, shards int) error {
+	_, err := s.Credit(ctx, addr, amount)
+	return err
+}
+
+func (s *MemoryStore) RecordEntries(ctx context.Context, entries ...*db.LedgerEntry) error {
+	return s.InTx(ctx, func(tx Store) error {
+		return tx.RecordEntries(ctx, entries...)
+	})
+}
+
+func (s *MemoryStore) CheckTransfer(ctx context.Context, addresses ...address.Address) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.paused {
+		return eresolvers.TransfersPausedError
+	}
+	for _, hex := range sortedHex(addresses) {
+		addr := address.FromHex(hex)
+		if s.frozen[addr] {
+			return eresolvers.AccountFrozenError{Address: addr}
+		}
+	}
+	return nil
+}
+
+// SetPaused pauses or resumes all transfers.
+func (s *MemoryStore) SetPaused(paused bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.paused = paused
+}
+
+// SetFrozen freezes or unfreezes the address. Transfers from or to a frozen address fail.
+func (s *MemoryStore) SetFrozen(addr address.Address, frozen bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if frozen {
+		s.frozen[addr] = true
+	} else {
+		delete(s.frozen, addr)
+	}
+}
+
+func (s *MemoryStore) RecordEvent(ctx context.Context, event audit.Event) error {
+	return s.InTx(ctx, func(tx Store) error {
+		return tx.RecordEvent(ctx, event)
+	})
+}
+
+// Entries returns the committed ledger entries in chain order.
+func (s *MemoryStore) Entries() []db.LedgerEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]db.LedgerEntry(nil), s.entries...)
+}
+
+// Events returns the committed audit events in the order they were recorded.
+func (s *MemoryStore) Events() []audit.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]audit.Event(nil), s.events...)
+}
+
+func (s *MemoryStore) accountLock(addr address.Address) lock {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	l, ok := s.locks[addr]
+	if !ok {
+		l = make(lock, 1)
+		s.locks[addr] = l
+	}
+	return l
+}
+
+// memoryTx is a transaction of a MemoryStore. Its writes are buffered and
+// applied when it commits, its locks are held until it ends.
+type memoryTx struct {
+	store    *MemoryStore
+	held     map[address.Address]bool
+	appends  bool
+	balances map[address.Address]decimal.Decimal
+	entries  []*db.LedgerEntry
+	events   []audit.Event
+}
+
+func (tx *memoryTx) InTx(ctx context.Context, fn func(tx Store) error) error {
+	// nested transactions share the locks and writes of the outer one
+	return fn(tx)
+}
+
+func (tx *memoryTx) GetAccount(ctx context.Context, addr address.Address) (*db.Account, error) {
+	if amount, ok := tx.balances[addr]; ok {
+		return &db.Account{Address: addr, Amount: amount}, nil
+	}
+	return tx.store.GetAccount(ctx, addr)
+}
+
+func (tx *memoryTx) LockAccounts(ctx context.Context, addresses []address.Address, upsert ...address.Address) (map[address.Address]*db.Account, error) {
+	create := make(map[address.Address]bool, len(upsert))
+	for _, addr := range upsert {
+		create[addr] = true
+	}
+
+	accounts := make(map[address.Address]*db.Account)
+	for _, hex := range sortedHex(append(append([]address.Address{}, addresses...), upsert...)) {
+		addr := address.FromHex(hex)
+		if !tx.held[addr] {
+			err := tx.store.accountLock(addr).acquire(ctx)
+			if err != nil {
+				return nil, err
+			}
+			if tx.held == nil {
+				tx.held = make(map[address.Address]bool)
+			}
+			tx.held[addr] = true
+		}
+
+		account, err := tx.GetAccount(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil && create[addr] {
+			account = &db.Account{Address: addr, Amount: decimal.Zero}
+			tx.balances[addr] = account.Amount
+		}
+		if account != nil {
+			accounts[addr] = account
+		}
+	}
+
+	return accounts, nil
+}
+
+func (tx *memoryTx) ApplyTransfer(ctx context.Context, accounts ...*db.Account) error {
+	for _, account := range accounts {
+		tx.balances[account.Address] = account.Amount
+	}
+	return nil
+}
+
+func (tx *memoryTx) Debit(ctx context.Context, addr address.Address, amount decimal.Decimal) (decimal.Decimal, bool, error) {
+	accounts, err := tx.LockAccounts(ctx, []address.Address{addr})
+	if err != nil {
+		return decimal.Zero, false, err
+	}
+
+	account, ok := accounts[addr]
+	if !ok || account.Amount.LessThan(amount) {
+		return decimal.Zero, false, nil
+	}
+	tx.balances[addr] = account.Amount.Sub(amount)
+	return tx.balances[addr], true, nil
+}
+
+func (tx *memoryTx) Credit(ctx context.Context, addr address.Address, amount decimal.Decimal) (decimal.Decimal, error) {
+	accounts, err := tx.LockAccounts(ctx, nil, addr)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	tx.balances[addr] = accounts[addr].Amount.Add(amount)
+	return tx.balances[addr], nil
+}
+
+// DebitShard debits the account as a whole, the memory store does not shard accounts.
+func (tx *memoryTx) DebitShard(ctx context.Context, addr address.Address, amount decimal.Decimal, shards int) (bool, error) {
+	_, ok, err := tx.Debit(ctx, addr, amount)
+	return ok, err
+}
+
+// CreditShard credits the account as a whole, see DebitShard.
+func (tx *memoryTx) CreditShard(ctx context.Context, addr address.Address, amount decimal.Decimal, shards int) error {
+	_, err := tx.Credit(ctx, addr, amount)
+	return err
+}
+
+func (tx *memoryTx) RecordEntries(ctx context.Context, entries ...*db.LedgerEntry) error {
+	if !tx.appends {
+		err := tx.store.ledgerLock.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		tx.appends = true
+	}
+
+	prevHash, nextID := db.GenesisHash, uint64(1)
+	if len(tx.entries) > 0 {
+		last := tx.entries[len(tx.entries)-1]
+		prevHash, nextID = last.Hash, last.ID+1
+	} else {
+		tx.store.mu.Lock()
+		if n := len(tx.store.entries); n > 0 {
+			prevHash, nextID = tx.store.entries[n-1].Hash, tx.store.entries[n-1].ID+1
+		}
+		tx.store.mu.Unlock()
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	for _, entry := range entries {
+		entry.ID = nextID
+		entry.CreatedAt = now
+		entry.PrevHash = prevHash
+		entry.Hash = entry.ComputeHash()
+		prevHash, nextID = entry.Hash, nextID+1
+	}
+	tx.entries = append(tx.entries, entries...)
+	return nil
+}
+
+func (tx *memoryTx) CheckTransfer(ctx context.Context, addresses ...address.Address) error {
+	return tx.store.CheckTransfer(ctx, addresses...)
+}
+
+func (tx *memoryTx) RecordEvent(ctx context.Context, event audit.Event) error {
+	tx.events = append(tx.events, event)
+	return nil
+}
+
+// end applies the buffered writes if commit is set and releases the locks.
+func (tx *memoryTx) end(commit bool) {
+	s := tx.store
+	if commit {
+		s.mu.Lock()
+		for addr, amount := range tx.balances {
+			s.accounts[addr] = amount
+		}
+		for _, entry := range tx.entries {
+			s.entries = append(s.entries, *entry)
+		}
+		s.events = append(s.events, tx.events...)
+		s.mu.Unlock()
+	}
+
+	if tx.appends {
+		s.ledgerLock.release()
+	}
+	for addr := range tx.held {
+		s.accountLock(addr).release()
+	}
+}
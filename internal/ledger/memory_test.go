@@ -0,0 +1,123 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eresolvers"
+)
+
+func TestMemoryStore_ConcurrentTransfers(t *testing.T) {
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 1000, testReceiver: 1000})
+
+	// opposing transfers lock the same accounts, sorted locking keeps them from deadlocking
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, err := l.Transfer(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(3))
+			assert.NoError(t, err)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := l.Transfer(context.Background(), testReceiver, testTreasury, decimal.NewFromInt64(1))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assertStoredBalance(t, l, testTreasury, 900)
+	assertStoredBalance(t, l, testReceiver, 1100)
+
+	entries := store.Entries()
+	require.Len(t, entries, 102)
+	c := newChain(nil)
+	for i := range entries {
+		c.check(&entries[i])
+	}
+	assert.True(t, c.Valid, c.Reason)
+}
+
+func TestMemoryStore_Rollback(t *testing.T) {
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 10})
+	errRollback := errors.New("rollback")
+
+	err := store.InTx(context.Background(), func(tx Store) error {
+		_, err := l.move(context.Background(), tx, "transfer", testTreasury, testReceiver, decimal.NewFromInt64(5), decimal.Zero)
+		require.NoError(t, err)
+		return errRollback
+	})
+
+	assert.ErrorIs(t, err, errRollback)
+	assertStoredBalance(t, l, testTreasury, 10)
+	account, err := store.GetAccount(context.Background(), testReceiver)
+	require.NoError(t, err)
+	assert.Nil(t, account)
+	assert.Len(t, store.Entries(), 1)
+}
+
+func TestMemoryStore_LockWaitCancelled(t *testing.T) {
+	store := NewMemoryStore()
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = store.InTx(context.Background(), func(tx Store) error {
+			_, err := tx.LockAccounts(context.Background(), nil, testTreasury)
+			close(locked)
+			<-release
+			return err
+		})
+	}()
+	<-locked
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := store.InTx(ctx, func(tx Store) error {
+		_, err := tx.LockAccounts(ctx, []address.Address{testTreasury})
+		return err
+	})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestMemoryStore_EntriesAreChained(t *testing.T) {
+	store := NewMemoryStore()
+
+	err := store.RecordEntries(context.Background(), testEntry(db.LedgerEntryMint, address.Address{}, testTreasury, 5))
+	require.NoError(t, err)
+	err = store.RecordEntries(context.Background(), testEntry(db.LedgerEntryBurn, testTreasury, address.Address{}, 5))
+	require.NoError(t, err)
+
+	entries := store.Entries()
+	require.Len(t, entries, 2)
+	assert.Equal(t, db.GenesisHash, entries[0].PrevHash)
+	assert.Equal(t, entries[0].Hash, entries[1].PrevHash)
+	assert.Equal(t, uint64(2), entries[1].ID)
+}
+
+func TestMemoryStore_Controls(t *testing.T) {
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 10})
+	amount := decimal.NewFromInt64(1)
+
+	store.SetPaused(true)
+	_, pausedErr := l.Transfer(context.Background(), testTreasury, testReceiver, amount)
+	store.SetPaused(false)
+	store.SetFrozen(testReceiver, true)
+	_, frozenErr := l.Transfer(context.Background(), testTreasury, testReceiver, amount)
+	store.SetFrozen(testReceiver, false)
+	_, err := l.Transfer(context.Background(), testTreasury, testReceiver, amount)
+
+	assert.ErrorIs(t, pausedErr, eresolvers.TransfersPausedError)
+	assert.Equal(t, eresolvers.AccountFrozenError{Address: testReceiver}, frozenErr)
+	assert.NoError(t, err)
+	assertStoredBalance(t, l, testTreasury, 9)
+}
@@ -0,0 +1,80 @@
+package ledger
+
+import (
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eledger"
+
+	"gorm.io/gorm"
+)
+
+// replayBatchSize is the number of entries loaded at once while replaying the ledger.
+const replayBatchSize = 1000
+
+// Balances are the account balances resulting from replaying ledger entries.
+type Balances struct {
+	Amounts map[address.Address]decimal.Decimal
+	// Supply is the total amount minted minus the total amount burned.
+	Supply decimal.Decimal
+	// LastEntryID is the id of the last applied entry.
+	LastEntryID uint64
+	// Entries is the number of applied entries.
+	Entries int
+}
+
+func NewBalances() *Balances {
+	return &Balances{Amounts: make(map[address.Address]decimal.Decimal), Supply: decimal.Zero}
+}
+
+// Get returns the balance of addr, zero for addresses without entries.
+func (b *Balances) Get(addr address.Address) decimal.Decimal {
+	amount, ok := b.Amounts[addr]
+	if !ok {
+		return decimal.Zero
+	}
+	return amount
+}
+
+// Apply applies a single entry, entries must be applied in id order.
+func (b *Balances) Apply(entry *db.LedgerEntry) {
+	switch entry.Kind {
+	case db.LedgerEntryMint:
+		b.Supply = b.Supply.Add(entry.Amount)
+		b.Amounts[entry.ToAddress] = b.Get(entry.ToAddress).Add(entry.Amount)
+	case db.LedgerEntryBurn:
+		b.Supply = b.Supply.Sub(entry.Amount)
+		b.Amounts[entry.FromAddress] = b.Get(entry.FromAddress).Sub(entry.Amount)
+	default:
+		b.Amounts[entry.FromAddress] = b.Get(entry.FromAddress).Sub(entry.Amount)
+		b.Amounts[entry.ToAddress] = b.Get(entry.ToAddress).Add(entry.Amount)
+	}
+	b.LastEntryID = entry.ID
+	b.Entries++
+}
+
+// Replay applies the entries following b.LastEntryID up to and including the entry
+// with id to, zero replays to the end of the ledger. Entries are loaded in batches,
+// so memory use only depends on the number of addresses.
+func Replay(tx *gorm.DB, b *Balances, to uint64) error {
+	for {
+		query := tx.Where("id > ?", b.LastEntryID)
+		if to != 0 {
+			query = query.Where("id <= ?", to)
+		}
+
+		var entries []db.LedgerEntry
+		err := query.Order("id").Limit(replayBatchSize).Find(&entries).Error
+		if err != nil {
+			return eledger.LedgerRetrievalError
+		}
+
+		for i := range entries {
+			b.Apply(&entries[i])
+		}
+
+		if len(entries) < replayBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package ledger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+)
+
+var (
+	testTreasury  = address.HexToAddress("0x0000000000000000000000000000000000000000")
+	testReceiver  = address.HexToAddress("0x1234567890123456789012345678901234567890")
+	testCollector = address.HexToAddress("0xFEEFEEFEEFEEFEEFEEFEEFEEFEEFEEFEEFEEFEE0")
+)
+
+func testEntry(kind db.LedgerEntryKind, from, to address.Address, amount int64) *db.LedgerEntry {
+	return &db.LedgerEntry{Kind: kind, FromAddress: from, ToAddress: to, Amount: decimal.NewFromInt64(amount)}
+}
+
+func assertBalance(t *testing.T, b *Balances, addr address.Address, expected int64) {
+	t.Helper()
+	assert.True(t, b.Get(addr).Equal(decimal.NewFromInt64(expected)), "expected %s to hold %d, got %s", addr, expected, b.Get(addr))
+}
+
+func TestBalances_Apply(t *testing.T) {
+	b := NewBalances()
+	entries := []*db.LedgerEntry{
+		testEntry(db.LedgerEntryMint, address.Address{}, testTreasury, 1000),
+		testEntry(db.LedgerEntryTransfer, testTreasury, testReceiver, 90),
+		testEntry(db.LedgerEntryFee, testTreasury, testCollector, 10),
+		testEntry(db.LedgerEntryTransfer, testReceiver, testTreasury, 40),
+	}
+	for i, entry := range entries {
+		entry.ID = uint64(i + 1)
+		b.Apply(entry)
+	}
+
+	assertBalance(t, b, testTreasury, 940)
+	assertBalance(t, b, testReceiver, 50)
+	assertBalance(t, b, testCollector, 10)
+	assert.True(t, b.Supply.Equal(decimal.NewFromInt64(1000)))
+	assert.Equal(t, uint64(4), b.LastEntryID)
+	assert.Equal(t, 4, b.Entries)
+}
+
+func TestBalances_MintToZeroAddress(t *testing.T) {
+	// the default account is the zero address, a mint must not debit its sender
+	b := NewBalances()
+	b.Apply(testEntry(db.LedgerEntryMint, testTreasury, testTreasury, 1000))
+
+	assertBalance(t, b, testTreasury, 1000)
+}
+
+func TestBalances_Burn(t *testing.T) {
+	// a burn has no receiver, it must not credit the zero address
+	b := NewBalances()
+	b.Apply(testEntry(db.LedgerEntryMint, address.Address{}, testReceiver, 1000))
+	b.Apply(testEntry(db.LedgerEntryBurn, testReceiver, address.Address{}, 400))
+
+	assertBalance(t, b, testReceiver, 600)
+	assertBalance(t, b, testTreasury, 0)
+	assert.True(t, b.Supply.Equal(decimal.NewFromInt64(600)))
+}
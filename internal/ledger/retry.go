@@ -0,0 +1,55 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/errors/eledger"
+	"token-transfer-api/internal/metrics"
+)
+
+// RetryPolicy configures how transactions aborted by a concurrent transaction,
+// e.g. on a deadlock or a lock timeout, are retried.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts, one or less disables retries.
+	Attempts int
+	// Backoff is the maximum delay before the first retry. It doubles with every
+	// retry, the actual delay is drawn at random up to it so that the conflicting
+	// transactions do not retry in lockstep.
+	Backoff time.Duration
+}
+
+// retry runs fn until it succeeds, fails with an error other than a ConflictError,
+// the attempts of the policy are used up or ctx is done. Lock timeouts still failing
+// after the last attempt are returned as a TimeoutError. Retries are counted in
+// metrics.TransactionRetries by operation.
+func (p RetryPolicy) retry(ctx context.Context, operation string, fn func() error) error {
+	backoff := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		var conflict eledger.ConflictError
+		if err == nil || !errors.As(err, &conflict) {
+			return err
+		}
+		if attempt >= p.Attempts {
+			if conflict.Reason == db.ConflictLockTimeout {
+				return eledger.TimeoutError{Timeout: db.ConflictLockTimeout, Err: err}
+			}
+			return err
+		}
+
+		metrics.TransactionRetries.WithLabelValues(operation, conflict.Reason).Inc()
+		if backoff > 0 {
+			timer := time.NewTimer(rand.N(backoff) + 1)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			}
+			backoff *= 2
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eledger"
+	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/metrics"
+)
+
+// conflictingStore fails the first transactions with a conflict, by default a deadlock.
+type conflictingStore struct {
+	Store
+	conflicts int
+	reason    string
+	attempts  int
+}
+
+func (s *conflictingStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	s.attempts++
+	return s.Store.InTx(ctx, func(tx Store) error {
+		err := fn(tx)
+		if err == nil && s.attempts <= s.conflicts {
+			reason := s.reason
+			if reason == "" {
+				reason = db.ConflictDeadlock
+			}
+			return eledger.ConflictError{Reason: reason, Err: errors.New(reason)}
+		}
+		return err
+	})
+}
+
+func newConflictingLedger(t *testing.T, conflicts int) (*Ledger, *conflictingStore) {
+	t.Helper()
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 100})
+	conflicting := &conflictingStore{Store: store, conflicts: conflicts}
+	l.Store = conflicting
+	l.Retry = RetryPolicy{Attempts: 3, Backoff: time.Millisecond}
+	return l, conflicting
+}
+
+func TestRetryPolicy_RetriesConflicts(t *testing.T) {
+	l, store := newConflictingLedger(t, 2)
+	retries := metrics.TransactionRetries.WithLabelValues("transfer", db.ConflictDeadlock)
+	before := testutil.ToFloat64(retries)
+
+	receipt, err := l.Transfer(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(10))
+
+	require.NoError(t, err)
+	assert.True(t, receipt.Balance.Equal(decimal.NewFromInt64(90)))
+	assert.Equal(t, 3, store.attempts)
+	assert.Equal(t, float64(2), testutil.ToFloat64(retries)-before)
+	assertStoredBalance(t, l, testTreasury, 90)
+	assertStoredBalance(t, l, testReceiver, 10)
+}
+
+func TestRetryPolicy_GivesUp(t *testing.T) {
+	l, store := newConflictingLedger(t, 3)
+
+	_, err := l.Transfer(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(10))
+
+	var conflict eledger.ConflictError
+	require.ErrorAs(t, err, &conflict)
+	assert.Equal(t, db.ConflictDeadlock, conflict.Reason)
+	assert.Equal(t, 3, store.attempts)
+	assertStoredBalance(t, l, testTreasury, 100)
+}
+
+func TestRetryPolicy_DoesNotRetryOtherErrors(t *testing.T) {
+	l, store := newConflictingLedger(t, 3)
+
+	_, err := l.Transfer(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(1000))
+
+	assert.ErrorIs(t, err, eresolvers.InsufficientBalanceError)
+	assert.Equal(t, 1, store.attempts)
+}
+
+func TestRetryPolicy_LockTimeout(t *testing.T) {
+	l, store := newConflictingLedger(t, 3)
+	store.reason = db.ConflictLockTimeout
+
+	_, err := l.Transfer(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(10))
+
+	var timeout eledger.TimeoutError
+	require.ErrorAs(t, err, &timeout)
+	assert.Equal(t, db.ConflictLockTimeout, timeout.Timeout)
+	assert.Equal(t, 3, store.attempts)
+}
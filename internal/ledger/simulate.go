@@ -0,0 +1,90 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eresolvers"
+)
+
+// Simulation is the outcome a transfer would have if it was submitted now, see Simulate.
+type Simulation struct {
+	// Receipt is the receipt the transfer would return, its Balance is the projected
+	// balance of the sender. The fee is zero if the transfer fails before it is computed.
+	Receipt Receipt
+	// SenderBalance is the current balance of the sender.
+	SenderBalance decimal.Decimal
+	// ReceiverBalance is the current balance of the receiver.
+	ReceiverBalance decimal.Decimal
+	// ReceiverProjected is the balance of the receiver after the transfer.
+	ReceiverProjected decimal.Decimal
+	// Err is the error the transfer would fail with, nil if it would succeed.
+	// The projected balances are the current ones if it is set.
+	Err error
+}
+
+// Simulate runs the validation of Transfer without locking or writing anything and
+// returns the projected balances. Errors the transfer would fail with are reported
+// in Simulation.Err, the returned error is set only if the simulation itself failed.
+// A concurrent transfer may change the outcome before the transfer is submitted.
+func (l *Ledger) Simulate(ctx context.Context, from, to address.Address, amount decimal.Decimal) (Simulation, error) {
+	sender, err := l.Store.GetAccount(ctx, from)
+	if err != nil {
+		return Simulation{}, err
+	}
+	senderBalance := decimal.Zero
+	if sender != nil {
+		senderBalance = sender.Amount
+	}
+	receiverBalance, err := balance(ctx, l.Store, to)
+	if err != nil {
+		return Simulation{}, err
+	}
+
+	sim := Simulation{
+		Receipt:           Receipt{Balance: senderBalance, Amount: amount, Fee: decimal.Zero, Net: decimal.Zero},
+		SenderBalance:     senderBalance,
+		ReceiverBalance:   receiverBalance,
+		ReceiverProjected: receiverBalance,
+	}
+
+	err = ValidateAmount(amount)
+	if err != nil {
+		sim.Err = err
+		return sim, nil
+	}
+
+	fee, err := l.Fee(from, to, amount)
+	if err != nil {
+		sim.Err = err
+		return sim, nil
+	}
+	sim.Receipt.Fee = fee
+	sim.Receipt.Net = amount.Sub(fee)
+
+	err = l.checkTransfer(ctx, l.Store, from, to, fee)
+	if errors.Is(err, eresolvers.TransfersPausedError) || errors.As(err, new(eresolvers.AccountFrozenError)) {
+		sim.Err = err
+		return sim, nil
+	}
+	if err != nil {
+		return Simulation{}, err
+	}
+
+	if sender == nil {
+		sim.Err = eresolvers.AddressNotFoundError{Address: from}
+		return sim, nil
+	}
+	if senderBalance.LessThan(amount) {
+		sim.Err = eresolvers.InsufficientBalanceError
+		return sim, nil
+	}
+
+	// self transfers do not change the balance
+	if from != to {
+		sim.Receipt.Balance = senderBalance.Sub(amount)
+		sim.ReceiverProjected = receiverBalance.Add(sim.Receipt.Net)
+	}
+	return sim, nil
+}
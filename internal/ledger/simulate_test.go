@@ -0,0 +1,97 @@
+package ledger
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/efees"
+	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/fees"
+)
+
+func TestLedger_Simulate(t *testing.T) {
+	l, store := newMemoryLedger(t, map[address.Address]int64{testTreasury: 1000, testReceiver: 5})
+	l.Fees = fees.Flat{Amount: decimal.NewFromInt64(10)}
+	l.FeeCollector = testCollector
+
+	sim, err := l.Simulate(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(100))
+
+	require.NoError(t, err)
+	require.NoError(t, sim.Err)
+	assert.True(t, sim.SenderBalance.Equal(decimal.NewFromInt64(1000)))
+	assert.True(t, sim.Receipt.Balance.Equal(decimal.NewFromInt64(900)))
+	assert.True(t, sim.Receipt.Fee.Equal(decimal.NewFromInt64(10)))
+	assert.True(t, sim.ReceiverBalance.Equal(decimal.NewFromInt64(5)))
+	assert.True(t, sim.ReceiverProjected.Equal(decimal.NewFromInt64(95)))
+	// nothing was written
+	assertStoredBalance(t, l, testTreasury, 1000)
+	assertStoredBalance(t, l, testReceiver, 5)
+	assert.Len(t, store.Entries(), 2)
+	assert.Len(t, store.Events(), 2)
+}
+
+func TestLedger_SimulateToSelf(t *testing.T) {
+	l, _ := newMemoryLedger(t, map[address.Address]int64{testTreasury: 10})
+
+	sim, err := l.Simulate(context.Background(), testTreasury, testTreasury, decimal.NewFromInt64(5))
+
+	require.NoError(t, err)
+	require.NoError(t, sim.Err)
+	assert.True(t, sim.Receipt.Balance.Equal(decimal.NewFromInt64(10)))
+	assert.True(t, sim.ReceiverProjected.Equal(decimal.NewFromInt64(10)))
+}
+
+// assertNothingMoves checks that a failing simulation projects the current balances.
+func assertNothingMoves(t *testing.T, sim Simulation) {
+	t.Helper()
+	assert.True(t, sim.Receipt.Balance.Equal(sim.SenderBalance))
+	assert.True(t, sim.ReceiverProjected.Equal(sim.ReceiverBalance))
+}
+
+func TestLedger_SimulateNegativeAmount(t *testing.T) {
+	l, _ := newMemoryLedger(t, map[address.Address]int64{testTreasury: 10})
+
+	sim, err := l.Simulate(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(-1))
+
+	require.NoError(t, err)
+	assert.ErrorIs(t, sim.Err, eresolvers.NegativeTransferError)
+	assertNothingMoves(t, sim)
+}
+
+func TestLedger_SimulateFeeExceedsAmount(t *testing.T) {
+	l, _ := newMemoryLedger(t, map[address.Address]int64{testTreasury: 10})
+	l.Fees = fees.Flat{Amount: decimal.NewFromInt64(10)}
+	l.FeeCollector = testCollector
+
+	sim, err := l.Simulate(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(5))
+
+	require.NoError(t, err)
+	assert.ErrorAs(t, sim.Err, new(efees.FeeExceedsAmountError))
+	assertNothingMoves(t, sim)
+}
+
+func TestLedger_SimulateFromMissingAccount(t *testing.T) {
+	l, _ := newMemoryLedger(t, nil)
+
+	sim, err := l.Simulate(context.Background(), testReceiver, testTreasury, decimal.NewFromInt64(1))
+
+	require.NoError(t, err)
+	assert.Equal(t, eresolvers.AddressNotFoundError{Address: testReceiver}, sim.Err)
+	assertNothingMoves(t, sim)
+}
+
+func TestLedger_SimulateInsufficientBalance(t *testing.T) {
+	l, _ := newMemoryLedger(t, map[address.Address]int64{testTreasury: 10})
+	l.Fees = fees.Flat{Amount: decimal.NewFromInt64(1)}
+	l.FeeCollector = testCollector
+
+	sim, err := l.Simulate(context.Background(), testTreasury, testReceiver, decimal.NewFromInt64(11))
+
+	require.NoError(t, err)
+	assert.ErrorIs(t, sim.Err, eresolvers.InsufficientBalanceError)
+	assert.True(t, sim.Receipt.Fee.Equal(decimal.NewFromInt64(1)))
+	assertNothingMoves(t, sim)
+}
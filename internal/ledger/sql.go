@@ -0,0 +1,368 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sort"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/controls"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eledger"
+	"token-transfer-api/internal/errors/eresolvers"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SQLStore stores the ledger in a PostgreSQL or SQLite database. PostgreSQL accounts are
+// locked with SELECT ... FOR UPDATE. SQLite has no row locks: its transactions begin
+// IMMEDIATE, taking the write lock of the database, see db.ConnectDb.
+type SQLStore struct {
+	db *gorm.DB
+	// Timeouts are applied to the transactions begun by InTx.
+	Timeouts db.Timeouts
+}
+
+// NewSQLStore returns a Store backed by the database. If db is a transaction,
+// the store works within it.
+func NewSQLStore(db *gorm.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) InTx(ctx context.Context, fn func(tx Store) error) error {
+	tx, err := db.Begin(ctx, s.db, s.Timeouts)
+	if err != nil {
+		return storeError(err, eresolvers.BeginTransactionError)
+	}
+
+	err = fn(&SQLStore{db: tx, Timeouts: s.Timeouts})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return storeError(err, eresolvers.CommitTransactionError)
+	}
+
+	return nil
+}
+
+func (s *SQLStore) GetAccount(ctx context.Context, addr address.Address) (*db.Account, error) {
+	var accounts []db.Account
+	err := db.Balances(s.db.WithContext(ctx)).Where("address = ?", addr.Hex()).Limit(1).Find(&accounts).Error
+	if err != nil {
+		return nil, storeError(err, eresolvers.AddressRetrievalError{Address: addr})
+	}
+
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+	return &accounts[0], nil
+}
+
+func (s *SQLStore) LockAccounts(ctx context.Context, addresses []address.Address, upsert ...address.Address) (map[address.Address]*db.Account, error) {
+	tx := s.db.WithContext(ctx)
+
+	create := make(map[address.Address]bool, len(upsert))
+	for _, addr := range upsert {
+		create[addr] = true
+	}
+	addressesToLock := sortedHex(append(append([]address.Address{}, addresses...), upsert...))
+
+	accounts := make(map[address.Address]*db.Account, len(addressesToLock))
+	for _, hex := range addressesToLock {
+		addr := address.FromHex(hex)
+		// created right before locking, so inserts happen in lock order too
+		if create[addr] {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "address"}},
+				DoNothing: true,
+			}).Create(&db.Account{Address: addr, Amount: decimal.Zero}).Error
+			if err != nil {
+				return nil, storeError(err, eresolvers.AddressCreationError{Address: addr})
+			}
+		}
+
+		var found []db.Account
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("address = ?", hex).
+			Limit(1).
+			Find(&found).Error
+		if err != nil {
+			return nil, storeError(err, eresolvers.AddressRetrievalError{Address: addr})
+		}
+		if len(found) > 0 {
+			accounts[addr] = &found[0]
+		}
+	}
+
+	return accounts, nil
+}
+
+func (s *SQLStore) ApplyTransfer(ctx context.Context, accounts ...*db.Account) error {
+	tx := s.db.WithContext(ctx)
+	for _, account := range accounts {
+		err := tx.Model(account).
+			Where("address = ?", account.Address).
+			Update("Amount", account.Amount).Error
+		if err != nil {
+			return storeError(err, eresolvers.AddressAmountUpdateError{Address: account.Address})
+		}
+	}
+	return nil
+}
+
+func (s *SQLStore) Debit(ctx context.Context, addr address.Address, amount decimal.Decimal) (decimal.Decimal, bool, error) {
+	tx := s.db.WithContext(ctx)
+	if db.IsSQLite(tx) {
+		return s.debitLocked(ctx, addr, amount)
+	}
+
+	var accounts []db.Account
+	err := tx.Raw(`UPDATE accounts SET amount = amount - ?
+WHERE address = ? AND amount >= ?
+RETURNING address, amount`, amount, addr.Hex(), amount).Scan(&accounts).Error
+	if err != nil {
+		return decimal.Zero, false, storeError(err, eresolvers.AddressAmountUpdateError{Address: addr})
+	}
+
+	if len(accounts) == 0 {
+		return decimal.Zero, false, nil
+	}
+	return accounts[0].Amount, true, nil
+}
+
+func (s *SQLStore) Credit(ctx context.Context, addr address.Address, amount decimal.Decimal) (decimal.Decimal, error) {
+	tx := s.db.WithContext(ctx)
+	if db.IsSQLite(tx) {
+		return s.creditLocked(ctx, addr, amount)
+	}
+
+	var accounts []db.Account
+	err := tx.Raw(`INSERT INTO accounts (address, amount) VALUES (?, ?)
+ON CONFLICT (address) DO UPDATE SET amount = accounts.amount + EXCLUDED.amount
+RETURNING address, amount`, addr.Hex(), amount).Scan(&accounts).Error
+	if err != nil {
+		return decimal.Zero, storeError(err, eresolvers.AddressAmountUpdateError{Address: addr})
+	}
+	if len(accounts) == 0 {
+		return decimal.Zero, eresolvers.AddressAmountUpdateError{Address: addr}
+	}
+	return accounts[0].Amount, nil
+}
+
+// DebitShard locks the account FOR KEY SHARE before the shard, so debits of different
+// shards do not block each other while operations locking the account FOR UPDATE,
+// e.g. imports, wait for them. Shards locked by concurrent debits are skipped.
+func (s *SQLStore) DebitShard(ctx context.Context, addr address.Address, amount decimal.Decimal, shards int) (bool, error) {
+	tx := s.db.WithContext(ctx)
+	if db.IsSQLite(tx) {
+		_, ok, err := s.debitLocked(ctx, addr, amount)
+		return ok, err
+	}
+
+	var debited []db.AccountShard
+	err := tx.Raw(`WITH account AS MATERIALIZED (
+	SELECT address FROM accounts WHERE address = ? FOR KEY SHARE
+), shard AS (
+	SELECT s.address, s.shard FROM account_shards s JOIN account a ON a.address = s.address
+	WHERE s.amount >= ?
+	ORDER BY random() LIMIT 1
+	FOR UPDATE OF s SKIP LOCKED
+)
+UPDATE account_shards s SET amount = s.amount - ?
+FROM shard WHERE s.address = shard.address AND s.shard = shard.shard
+RETURNING s.address, s.shard, s.amount`, addr.Hex(), amount, amount).Scan(&debited).Error
+	if err != nil {
+		return false, storeError(err, eresolvers.AddressAmountUpdateError{Address: addr})
+	}
+
+	if len(debited) > 0 {
+		return true, nil
+	}
+	return s.poolShards(ctx, addr, amount, shards)
+}
+
+// poolShards debits amount from the total of the hot account and all its shards and
+// spreads the rest evenly over shards shards. The account is locked FOR NO KEY UPDATE,
+// which does not conflict with the locks of DebitShard and CreditShard, and its
+// shards are locked in order after it.
+func (s *SQLStore) poolShards(ctx context.Context, addr address.Address, amount decimal.Decimal, shards int) (bool, error) {
+	tx := s.db.WithContext(ctx)
+
+	var accounts []db.Account
+	err := tx.Raw("SELECT address, amount FROM accounts WHERE address = ? FOR NO KEY UPDATE", addr.Hex()).Scan(&accounts).Error
+	if err != nil {
+		return false, storeError(err, eresolvers.AddressRetrievalError{Address: addr})
+	}
+	if len(accounts) == 0 {
+		return false, nil
+	}
+
+	var current []db.AccountShard
+	err = tx.Raw("SELECT address, shard, amount FROM account_shards WHERE address = ? ORDER BY shard FOR UPDATE", addr.Hex()).Scan(&current).Error
+	if err != nil {
+		return false, storeError(err, eresolvers.AddressRetrievalError{Address: addr})
+	}
+
+	total := accounts[0].Amount
+	for _, shard := range current {
+		total = total.Add(shard.Amount)
+	}
+	if total.LessThan(amount) {
+		return false, nil
+	}
+
+	shards = max(shards, 1)
+	rest := total.Sub(amount)
+	share := rest.QuoInt(decimal.NewFromInt64(int64(shards)))
+	spread := make([]db.AccountShard, 0, shards)
+	for i := 0; i < shards; i++ {
+		spread = append(spread, db.AccountShard{Address: addr, Shard: i, Amount: share})
+	}
+	// the remainder of the division goes to the first shard
+	spread[0].Amount = rest.Sub(share.Mul(decimal.NewFromInt64(int64(shards - 1))))
+
+	err = tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "address"}, {Name: "shard"}},
+		DoUpdates: clause.AssignmentColumns([]string{"amount"}),
+	}).Create(&spread).Error
+	if err != nil {
+		return false, storeError(err, eresolvers.AddressAmountUpdateError{Address: addr})
+	}
+	// shards beyond the configured number are left over from a larger configuration
+	err = tx.Where("address = ? AND shard >= ?", addr.Hex(), shards).Delete(&db.AccountShard{}).Error
+	if err != nil {
+		return false, storeError(err, eresolvers.AddressAmountUpdateError{Address: addr})
+	}
+	err = tx.Model(&db.Account{}).Where("address = ?", addr.Hex()).Update("amount", decimal.Zero).Error
+	if err != nil {
+		return false, storeError(err, eresolvers.AddressAmountUpdateError{Address: addr})
+	}
+	return true, nil
+}
+
+// CreditShard locks the account FOR KEY SHARE before the shard, like DebitShard.
+func (s *SQLStore) CreditShard(ctx context.Context, addr address.Address, amount decimal.Decimal, shards int) error {
+	tx := s.db.WithContext(ctx)
+	if db.IsSQLite(tx) {
+		_, err := s.creditLocked(ctx, addr, amount)
+		return err
+	}
+
+	shard := rand.IntN(max(shards, 1))
+	for attempt := 0; attempt < 2; attempt++ {
+		var credited []db.AccountShard
+		err := tx.Raw(`WITH account AS MATERIALIZED (
+	SELECT address FROM accounts WHERE address = ? FOR KEY SHARE
+)
+INSERT INTO account_shards (address, shard, amount)
+SELECT address, CAST(? AS integer), CAST(? AS numeric) FROM account
+ON CONFLICT (address, shard) DO UPDATE SET amount = account_shards.amount + EXCLUDED.amount
+RETURNING address, shard, amount`, addr.Hex(), shard, amount).Scan(&credited).Error
+		if err != nil {
+			return storeError(err, eresolvers.AddressAmountUpdateError{Address: addr})
+		}
+		if len(credited) > 0 {
+			return nil
+		}
+
+		// the shards belong to an account, create it and try again
+		err = tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "address"}},
+			DoNothing: true,
+		}).Create(&db.Account{Address: addr, Amount: decimal.Zero}).Error
+		if err != nil {
+			return storeError(err, eresolvers.AddressCreationError{Address: addr})
+		}
+	}
+	return eresolvers.AddressAmountUpdateError{Address: addr}
+}
+
+// debitLocked is Debit for SQLite, whose amounts are text and cannot be computed
+// exactly in SQL. The write lock held by the transaction makes reading the balance
+// and updating it atomic.
+func (s *SQLStore) debitLocked(ctx context.Context, addr address.Address, amount decimal.Decimal) (decimal.Decimal, bool, error) {
+	accounts, err := s.LockAccounts(ctx, []address.Address{addr})
+	if err != nil {
+		return decimal.Zero, false, err
+	}
+
+	account, ok := accounts[addr]
+	if !ok || account.Amount.LessThan(amount) {
+		return decimal.Zero, false, nil
+	}
+	account.Amount = account.Amount.Sub(amount)
+	err = s.ApplyTransfer(ctx, account)
+	if err != nil {
+		return decimal.Zero, false, err
+	}
+	return account.Amount, true, nil
+}
+
+// creditLocked is Credit for SQLite, see debitLocked.
+func (s *SQLStore) creditLocked(ctx context.Context, addr address.Address, amount decimal.Decimal) (decimal.Decimal, error) {
+	accounts, err := s.LockAccounts(ctx, nil, addr)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	account := accounts[addr]
+	account.Amount = account.Amount.Add(amount)
+	err = s.ApplyTransfer(ctx, account)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return account.Amount, nil
+}
+
+func (s *SQLStore) RecordEntries(ctx context.Context, entries ...*db.LedgerEntry) error {
+	err := db.AppendLedgerEntries(s.db.WithContext(ctx), entries...)
+	if err != nil {
+		return storeError(err, eledger.LedgerAppendError)
+	}
+	return nil
+}
+
+func (s *SQLStore) CheckTransfer(ctx context.Context, addresses ...address.Address) error {
+	return controls.CheckTransfer(s.db.WithContext(ctx), addresses...)
+}
+
+func (s *SQLStore) RecordEvent(ctx context.Context, event audit.Event) error {
+	return audit.Record(ctx, s.db.WithContext(ctx), event)
+}
+
+// storeError returns a ConflictError if the database aborted the transaction because
+// of a concurrent one, so that it can be retried, a TimeoutError if the statement timed
+// out, the context error if the request was cancelled and fallback otherwise.
+func storeError(err error, fallback error) error {
+	if reason := db.ConflictReason(err); reason != "" {
+		return eledger.ConflictError{Reason: reason, Err: err}
+	}
+	if db.IsStatementTimeout(err) {
+		return eledger.TimeoutError{Timeout: db.TimeoutStatement, Err: err}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fallback
+}
+
+// sortedHex returns the distinct hex forms of the addresses in sorted order.
+func sortedHex(addresses []address.Address) []string {
+	seen := make(map[address.Address]bool, len(addresses))
+	var sorted []string
+	for _, addr := range addresses {
+		if !seen[addr] {
+			seen[addr] = true
+			sorted = append(sorted, addr.Hex())
+		}
+	}
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,51 @@
+package ledger
+
+import (
+	"context"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+)
+
+// Store persists the accounts and the ledger entries moved by a Ledger.
+// Implementations must lock accounts in sorted address order, so that
+// concurrent transactions locking overlapping accounts cannot deadlock.
+type Store interface {
+	// InTx runs fn in a new transaction. The Store passed to fn works within the
+	// transaction, which is committed if fn succeeds and rolled back otherwise.
+	InTx(ctx context.Context, fn func(tx Store) error) error
+	// GetAccount returns the account of the address, or nil if it has none.
+	GetAccount(ctx context.Context, addr address.Address) (*db.Account, error)
+	// LockAccounts locks the accounts of addresses and upsert until the end of the
+	// transaction, in sorted address order, and returns them by address. Missing
+	// accounts of upsert are created with a zero balance, other missing accounts
+	// are not returned.
+	LockAccounts(ctx context.Context, addresses []address.Address, upsert ...address.Address) (map[address.Address]*db.Account, error)
+	// ApplyTransfer stores the new balances of locked accounts.
+	ApplyTransfer(ctx context.Context, accounts ...*db.Account) error
+	// Debit subtracts amount from the balance of the address if it covers amount,
+	// locks the account until the end of the transaction and returns its new balance.
+	// It returns false and changes nothing if the address has no account or its
+	// balance is less than amount.
+	Debit(ctx context.Context, addr address.Address, amount decimal.Decimal) (decimal.Decimal, bool, error)
+	// Credit adds amount to the balance of the address, creating its account if
+	// missing, locks the account until the end of the transaction and returns its
+	// new balance.
+	Credit(ctx context.Context, addr address.Address, amount decimal.Decimal) (decimal.Decimal, error)
+	// DebitShard subtracts amount from a shard of the hot account of the address
+	// holding at least amount. If no shard does, the account and all its shards are
+	// locked, amount is subtracted from their total and the rest is spread evenly
+	// over shards shards. It returns false and changes nothing if the address has no
+	// account or its balance is less than amount.
+	DebitShard(ctx context.Context, addr address.Address, amount decimal.Decimal, shards int) (bool, error)
+	// CreditShard adds amount to a random one of the shards shards of the hot account
+	// of the address, creating the account if missing.
+	CreditShard(ctx context.Context, addr address.Address, amount decimal.Decimal, shards int) error
+	// RecordEntries appends the entries to the hash-chained ledger.
+	RecordEntries(ctx context.Context, entries ...*db.LedgerEntry) error
+	// CheckTransfer fails if transfers are paused or one of the addresses is frozen.
+	CheckTransfer(ctx context.Context, addresses ...address.Address) error
+	// RecordEvent records the event in the audit log.
+	RecordEvent(ctx context.Context, event audit.Event) error
+}
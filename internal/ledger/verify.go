@@ -0,0 +1,117 @@
+package ledger
+
+import (
+	"fmt"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/errors/eledger"
+
+	"gorm.io/gorm"
+)
+
+// verifyBatchSize is the number of entries loaded at once while verifying the chain.
+const verifyBatchSize = 1000
+
+// Verification is the outcome of walking the ledger hash chain.
+type Verification struct {
+	// Checked is the number of entries checked.
+	Checked int
+	// Valid reports whether all checked links are intact.
+	Valid bool
+	// BrokenAt is the id of the first entry with a broken link, if not Valid.
+	BrokenAt uint64
+	// Reason describes why the link at BrokenAt is broken.
+	Reason string
+	// LastHash is the hash of the last intact entry.
+	LastHash string
+}
+
+// chain checks that entries passed in id order link to each other.
+type chain struct {
+	Verification
+	prevID   uint64
+	prevHash string
+}
+
+// newChain returns a chain continuing after the entry prev, nil starts at the genesis.
+func newChain(prev *db.LedgerEntry) *chain {
+	c := &chain{Verification: Verification{Valid: true}, prevHash: db.GenesisHash}
+	if prev != nil {
+		c.prevID, c.prevHash = prev.ID, prev.Hash
+	}
+	c.LastHash = c.prevHash
+	return c
+}
+
+// check verifies the link of entry to the previously checked entry.
+// It returns false once a broken link is found.
+func (c *chain) check(entry *db.LedgerEntry) bool {
+	c.Checked++
+	switch {
+	case entry.ID != c.prevID+1:
+		c.broken(entry.ID, fmt.Sprintf("entry %d is missing", c.prevID+1))
+	case entry.PrevHash != c.prevHash:
+		c.broken(entry.ID, "previous hash does not match the preceding entry")
+	case entry.Hash != entry.ComputeHash():
+		c.broken(entry.ID, "hash does not match the entry contents")
+	default:
+		c.prevID, c.prevHash = entry.ID, entry.Hash
+		c.LastHash = entry.Hash
+		return true
+	}
+	return false
+}
+
+func (c *chain) broken(id uint64, reason string) {
+	c.Valid = false
+	c.BrokenAt = id
+	c.Reason = reason
+}
+
+// Verify walks the ledger entries with ids between from and to, both inclusive,
+// and reports the first broken link of the hash chain. Zero from starts at the first
+// entry, zero to ends at the last one. Entries are loaded in batches.
+func Verify(tx *gorm.DB, from, to uint64) (Verification, error) {
+	if to != 0 && from > to {
+		return Verification{}, eledger.InvalidRangeError{From: from, To: to}
+	}
+
+	var prev []db.LedgerEntry
+	if from > 1 {
+		err := tx.Where("id < ?", from).Order("id DESC").Limit(1).Find(&prev).Error
+		if err != nil {
+			return Verification{}, eledger.LedgerRetrievalError
+		}
+	}
+
+	var c *chain
+	if len(prev) > 0 {
+		c = newChain(&prev[0])
+	} else {
+		c = newChain(nil)
+	}
+
+	after := c.prevID
+	for {
+		query := tx.Where("id > ?", after)
+		if to != 0 {
+			query = query.Where("id <= ?", to)
+		}
+
+		var entries []db.LedgerEntry
+		err := query.Order("id").Limit(verifyBatchSize).Find(&entries).Error
+		if err != nil {
+			return Verification{}, eledger.LedgerRetrievalError
+		}
+
+		for i := range entries {
+			if !c.check(&entries[i]) {
+				return c.Verification, nil
+			}
+		}
+
+		if len(entries) < verifyBatchSize {
+			return c.Verification, nil
+		}
+		after = entries[len(entries)-1].ID
+	}
+}
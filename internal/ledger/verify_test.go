@@ -0,0 +1,99 @@
+package ledger
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+)
+
+// testEntries returns a correctly linked chain of n transfer entries.
+func testEntries(n int) []*db.LedgerEntry {
+	entries := make([]*db.LedgerEntry, 0, n)
+	prevHash := db.GenesisHash
+	for i := 1; i <= n; i++ {
+		entry := &db.LedgerEntry{
+			ID:          uint64(i),
+			CreatedAt:   time.Date(2025, 1, 1, 0, 0, i, 0, time.UTC),
+			Kind:        db.LedgerEntryTransfer,
+			FromAddress: address.HexToAddress("0x0000000000000000000000000000000000000000"),
+			ToAddress:   address.HexToAddress("0x1234567890123456789012345678901234567890"),
+			Amount:      decimal.NewFromInt64(int64(i)),
+			PrevHash:    prevHash,
+		}
+		entry.Hash = entry.ComputeHash()
+		prevHash = entry.Hash
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func testChain(t *testing.T, entries []*db.LedgerEntry, expectedBrokenAt uint64) {
+	t.Helper()
+
+	c := newChain(nil)
+	for _, entry := range entries {
+		if !c.check(entry) {
+			break
+		}
+	}
+
+	assert.Equal(t, expectedBrokenAt == 0, c.Valid)
+	assert.Equal(t, expectedBrokenAt, c.BrokenAt)
+	if expectedBrokenAt == 0 {
+		assert.Equal(t, entries[len(entries)-1].Hash, c.LastHash)
+	} else {
+		assert.NotEmpty(t, c.Reason)
+	}
+}
+
+func TestChain_Valid(t *testing.T) {
+	testChain(t, testEntries(5), 0)
+}
+
+func TestChain_EditedAmount(t *testing.T) {
+	entries := testEntries(5)
+	entries[2].Amount = decimal.NewFromInt64(1000)
+	testChain(t, entries, 3)
+}
+
+func TestChain_EditedAndRehashed(t *testing.T) {
+	entries := testEntries(5)
+	entries[2].Amount = decimal.NewFromInt64(1000)
+	entries[2].Hash = entries[2].ComputeHash()
+	testChain(t, entries, 4)
+}
+
+func TestChain_RemovedEntry(t *testing.T) {
+	entries := testEntries(5)
+	entries = append(entries[:2], entries[3:]...)
+	testChain(t, entries, 4)
+}
+
+func TestChain_SwappedEntries(t *testing.T) {
+	entries := testEntries(5)
+	entries[1].ID, entries[2].ID = entries[2].ID, entries[1].ID
+	entries[1], entries[2] = entries[2], entries[1]
+	testChain(t, entries, 2)
+}
+
+func TestComputeHash_CoversAllFields(t *testing.T) {
+	base := testEntries(1)[0]
+	edits := []func(e *db.LedgerEntry){
+		func(e *db.LedgerEntry) { e.ID++ },
+		func(e *db.LedgerEntry) { e.CreatedAt = e.CreatedAt.Add(time.Microsecond) },
+		func(e *db.LedgerEntry) { e.Kind = db.LedgerEntryFee },
+		func(e *db.LedgerEntry) { e.FromAddress = e.ToAddress },
+		func(e *db.LedgerEntry) { e.ToAddress = e.FromAddress },
+		func(e *db.LedgerEntry) { e.Amount = decimal.NewFromInt64(2) },
+		func(e *db.LedgerEntry) { e.PrevHash = e.Hash },
+	}
+
+	for i, edit := range edits {
+		edited := *base
+		edit(&edited)
+		assert.NotEqual(t, base.Hash, edited.ComputeHash(), "edit %d", i)
+	}
+}
@@ -0,0 +1,119 @@
+// Package merkle builds Merkle trees of (address, amount) pairs verifiable with
+// OpenZeppelin's MerkleProof library.
+//
+// Trees are laid out like the StandardMerkleTree of @openzeppelin/merkle-tree with
+// sorted leaves, so the roots match the ones computed by the JavaScript library:
+// leaves are keccak256(keccak256(abi.encode(address, uint256))) and inner nodes
+// hash the sorted pair of their children.
+package merkle
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/emerkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var maxUint256 = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1))
+
+// Leaf returns the leaf hash of an (address, amount) pair.
+// The amount must be an integer between 0 and 2^256-1.
+func Leaf(addr address.Address, amount decimal.Decimal) (common.Hash, error) {
+	if !amount.IsInteger() {
+		return common.Hash{}, emerkle.InvalidAmountError{Amount: amount}
+	}
+	value := amount.BigInt()
+	if value.Sign() < 0 || value.Cmp(maxUint256) > 0 {
+		return common.Hash{}, emerkle.InvalidAmountError{Amount: amount}
+	}
+
+	// abi.encode(address, uint256) pads both values to 32 bytes
+	encoded := make([]byte, 64)
+	copy(encoded[12:32], addr[:])
+	value.FillBytes(encoded[32:])
+
+	return crypto.Keccak256Hash(crypto.Keccak256(encoded)), nil
+}
+
+// hashPair hashes two nodes in sorted order, as MerkleProof does.
+func hashPair(a, b common.Hash) common.Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return crypto.Keccak256Hash(a[:], b[:])
+}
+
+// Tree is a Merkle tree stored as an array: the children of node i
+// are nodes 2i+1 and 2i+2 and the leaves are at the end in reverse order.
+type Tree struct {
+	nodes []common.Hash
+	// index maps leaves to their position in nodes.
+	index map[common.Hash]int
+}
+
+// New builds a tree of the leaves, which are sorted first.
+func New(leaves []common.Hash) (*Tree, error) {
+	if len(leaves) == 0 {
+		return nil, emerkle.EmptyTreeError
+	}
+
+	sorted := make([]common.Hash, len(leaves))
+	copy(sorted, leaves)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+
+	t := &Tree{
+		nodes: make([]common.Hash, 2*len(sorted)-1),
+		index: make(map[common.Hash]int, len(sorted)),
+	}
+	for i, leaf := range sorted {
+		position := len(t.nodes) - 1 - i
+		t.nodes[position] = leaf
+		t.index[leaf] = position
+	}
+	for i := len(t.nodes) - 1 - len(sorted); i >= 0; i-- {
+		t.nodes[i] = hashPair(t.nodes[2*i+1], t.nodes[2*i+2])
+	}
+
+	return t, nil
+}
+
+// Root returns the root of the tree.
+func (t *Tree) Root() common.Hash {
+	return t.nodes[0]
+}
+
+// Proof returns the sibling hashes proving that leaf is part of the tree,
+// ordered from the leaf to the root. It returns false if leaf is not in the tree.
+func (t *Tree) Proof(leaf common.Hash) ([]common.Hash, bool) {
+	i, ok := t.index[leaf]
+	if !ok {
+		return nil, false
+	}
+
+	var proof []common.Hash
+	for i > 0 {
+		sibling := i + 1
+		if i%2 == 0 {
+			sibling = i - 1
+		}
+		proof = append(proof, t.nodes[sibling])
+		i = (i - 1) / 2
+	}
+	return proof, true
+}
+
+// Verify reports whether proof proves that leaf is part of the tree with the given root.
+func Verify(root, leaf common.Hash, proof []common.Hash) bool {
+	node := leaf
+	for _, sibling := range proof {
+		node = hashPair(node, sibling)
+	}
+	return node == root
+}
@@ -0,0 +1,95 @@
+package merkle
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/emerkle"
+)
+
+func testLeaf(t *testing.T, hex string, amount string) common.Hash {
+	t.Helper()
+
+	value, err := decimal.NewFromString(amount)
+	require.NoError(t, err)
+	leaf, err := Leaf(address.HexToAddress(hex), value)
+	require.NoError(t, err)
+	return leaf
+}
+
+func testLeaves(t *testing.T, n int) []common.Hash {
+	t.Helper()
+
+	leaves := make([]common.Hash, 0, n)
+	for i := 1; i <= n; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i))).Hex()
+		leaves = append(leaves, testLeaf(t, addr, decimal.NewFromInt64(int64(i)).String()))
+	}
+	return leaves
+}
+
+func TestNew_OpenZeppelinRoot(t *testing.T) {
+	// example of the @openzeppelin/merkle-tree README
+	tree, err := New([]common.Hash{
+		testLeaf(t, "0x1111111111111111111111111111111111111111", "5000000000000000000"),
+		testLeaf(t, "0x2222222222222222222222222222222222222222", "2500000000000000000"),
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "0xd4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77", tree.Root().Hex())
+}
+
+func TestProof_AllLeaves(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 4, 5, 8, 13} {
+		leaves := testLeaves(t, n)
+		tree, err := New(leaves)
+		require.NoError(t, err)
+
+		for _, leaf := range leaves {
+			proof, ok := tree.Proof(leaf)
+			assert.True(t, ok)
+			assert.True(t, Verify(tree.Root(), leaf, proof), "leaf %s of %d", leaf, n)
+		}
+	}
+}
+
+func TestProof_UnknownLeaf(t *testing.T) {
+	tree, err := New(testLeaves(t, 3))
+	require.NoError(t, err)
+
+	_, ok := tree.Proof(testLeaf(t, "0x1234567890123456789012345678901234567890", "1"))
+
+	assert.False(t, ok)
+}
+
+func TestVerify_WrongAmount(t *testing.T) {
+	leaves := testLeaves(t, 4)
+	tree, err := New(leaves)
+	require.NoError(t, err)
+	proof, _ := tree.Proof(leaves[0])
+
+	forged := testLeaf(t, common.BigToAddress(big.NewInt(1)).Hex(), "1000")
+
+	assert.False(t, Verify(tree.Root(), forged, proof))
+}
+
+func TestNew_Empty(t *testing.T) {
+	_, err := New(nil)
+
+	assert.ErrorIs(t, err, emerkle.EmptyTreeError)
+}
+
+func TestLeaf_InvalidAmount(t *testing.T) {
+	addr := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	tooLarge, err := decimal.NewFromString("115792089237316195423570985008687907853269984665640564039457584007913129639936")
+	require.NoError(t, err)
+
+	for _, amount := range []decimal.Decimal{decimal.NewFromInt64(-1), decimal.NewFromFloat64(1.5), tooLarge} {
+		_, err := Leaf(addr, amount)
+		assert.ErrorAs(t, err, &emerkle.InvalidAmountError{}, "amount %s", amount)
+	}
+}
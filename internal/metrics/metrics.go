@@ -0,0 +1,58 @@
+// Package metrics defines the Prometheus metrics exported on /metrics.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "token_transfer"
+
+var (
+	// ReconciliationRuns counts reconciliation runs by result: "balanced", "discrepancy" or "error".
+	ReconciliationRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconciliation_runs_total",
+		Help:      "Number of reconciliation runs by result.",
+	}, []string{"result"})
+
+	// ReconciliationDiscrepancies is the number of accounts whose stored balance
+	// differed from the replayed ledger in the last reconciliation.
+	ReconciliationDiscrepancies = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "reconciliation_discrepancies",
+		Help:      "Number of accounts with a balance differing from the ledger in the last reconciliation.",
+	})
+
+	// ReconciliationSupplyDifference is the sum of stored balances minus the total supply
+	// in the last reconciliation, it is zero if no tokens leaked.
+	ReconciliationSupplyDifference = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "reconciliation_supply_difference",
+		Help:      "Sum of stored balances minus the total supply in the last reconciliation.",
+	})
+
+	// ReconciliationLastRun is the unix time of the last completed reconciliation.
+	ReconciliationLastRun = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "reconciliation_last_run_timestamp_seconds",
+		Help:      "Unix time of the last completed reconciliation.",
+	})
+
+	// TransactionRetries counts ledger transactions retried after the database aborted them
+	// because of a concurrent transaction, by operation and reason: "serialization_failure",
+	// "deadlock" or "lock_timeout".
+	TransactionRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transaction_retries_total",
+		Help:      "Number of ledger transactions retried after a conflict with a concurrent transaction.",
+	}, []string{"operation", "reason"})
+)
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
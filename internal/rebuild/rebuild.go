@@ -0,0 +1,259 @@
+// Package rebuild reconstructs the accounts table deterministically from the ledger.
+package rebuild
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/erebuild"
+	"token-transfer-api/internal/ledger"
+
+	"gorm.io/gorm"
+)
+
+// DefaultSideTable receives the rebuilt balances if no other side table is given.
+const DefaultSideTable = "accounts_rebuild"
+
+// insertBatchSize is the number of rebuilt accounts inserted per statement.
+const insertBatchSize = 1000
+
+var tableName = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// Options select which entries are replayed and where the balances are written.
+type Options struct {
+	// AsOf replays the entries created at or before AsOf.
+	AsOf *time.Time
+	// LedgerID replays the entries up to and including LedgerID.
+	LedgerID uint64
+	// SideTable is created and receives the rebuilt balances, the accounts table is left untouched.
+	SideTable string
+	// Swap replaces the accounts table with the rebuilt balances instead of writing a side table.
+	// It requires replaying the whole ledger, see erebuild.BoundedSwapError.
+	Swap bool
+}
+
+// Result describes a rebuild.
+type Result struct {
+	// Entries is the number of replayed ledger entries.
+	Entries int
+	// LastEntryID is the id of the last replayed ledger entry.
+	LastEntryID uint64
+	// Accounts is the number of rebuilt accounts.
+	Accounts int
+	// Supply is the total amount minted minus the total amount burned by the replayed entries.
+	Supply decimal.Decimal
+	// Table holds the rebuilt balances.
+	Table string
+	// Replaced is the name the previous accounts table was renamed to, if swapped.
+	Replaced string
+	// Differences is the number of addresses whose rebuilt balance differs from
+	// the accounts table as it was before the rebuild.
+	Differences int64
+}
+
+// Run verifies the hash chain of the replayed entries, replays them into a new table
+// and either leaves it as a side table for comparison or atomically swaps it in.
+//
+// A side table is rebuilt from a repeatable read snapshot while transfers continue.
+// Swapping locks the accounts table against writes for the duration of the rebuild,
+// the previous table is kept under a timestamped name. The swap is recorded in the
+// audit log with the actor of ctx.
+func Run(ctx context.Context, dbConnection *gorm.DB, opts Options) (*Result, error) {
+	if opts.AsOf != nil && opts.LedgerID != 0 {
+		return nil, erebuild.ConflictingBoundsError
+	}
+	if opts.Swap && (opts.AsOf != nil || opts.LedgerID != 0) {
+		return nil, erebuild.BoundedSwapError
+	}
+
+	now := time.Now().UTC()
+	result := &Result{Table: opts.SideTable}
+	txOptions := &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+	if opts.Swap {
+		result.Table = "accounts_rebuild_" + now.Format("20060102150405")
+		result.Replaced = "accounts_replaced_" + now.Format("20060102150405")
+		txOptions = nil
+	} else if result.Table == "" {
+		result.Table = DefaultSideTable
+	}
+	if !tableName.MatchString(result.Table) || result.Table == "accounts" {
+		return nil, erebuild.InvalidTableError{Table: result.Table}
+	}
+
+	err := dbConnection.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// SQLite transactions hold the write lock of the database, which blocks transfers already
+		if opts.Swap && !db.IsSQLite(tx) {
+			// block transfers, reads of the old table continue until the swap
+			err := tx.Exec("LOCK TABLE accounts IN EXCLUSIVE MODE").Error
+			if err != nil {
+				return erebuild.RebuildError
+			}
+		}
+
+		to, err := bound(tx, opts)
+		if err != nil {
+			return err
+		}
+
+		balances, err := replay(tx, to)
+		if err != nil {
+			return err
+		}
+		result.Entries = balances.Entries
+		result.LastEntryID = balances.LastEntryID
+		result.Supply = balances.Supply
+		result.Accounts = len(balances.Amounts)
+
+		err = write(tx, result.Table, balances)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Raw(fmt.Sprintf(`SELECT COUNT(*) FROM (%s) a FULL OUTER JOIN %s r ON a.address = r.address
+WHERE a.amount IS DISTINCT FROM r.amount`, db.BalancesSQL(tx), result.Table)).Scan(&result.Differences).Error
+		if err != nil {
+			return erebuild.RebuildError
+		}
+
+		if opts.Swap {
+			return swap(ctx, tx, result)
+		}
+		return nil
+	}, txOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// swap replaces the accounts table with the rebuilt one, drops the shards of hot
+// accounts and records the swap in the audit log.
+func swap(ctx context.Context, tx *gorm.DB, result *Result) error {
+	var replaced int64
+	err := tx.Table("accounts").Count(&replaced).Error
+	if err != nil {
+		return erebuild.RebuildError
+	}
+
+	err = tx.Exec(fmt.Sprintf("ALTER TABLE accounts RENAME TO %s", result.Replaced)).Error
+	if err != nil {
+		return erebuild.RebuildError
+	}
+	err = tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO accounts", result.Table)).Error
+	if err != nil {
+		return erebuild.RebuildError
+	}
+	// the rebuilt balances include the shards of hot accounts
+	shards := tx.Exec("DELETE FROM account_shards")
+	if shards.Error != nil {
+		return erebuild.RebuildError
+	}
+	result.Table = "accounts"
+
+	return audit.Record(ctx, tx, audit.Event{
+		Operation: "rebuildBalances",
+		Subject:   result.Replaced,
+		Details: map[string]any{
+			"ledger_entry_id":   result.LastEntryID,
+			"entries":           result.Entries,
+			"accounts":          result.Accounts,
+			"replaced_accounts": replaced,
+			"deleted_shards":    shards.RowsAffected,
+			"differences":       result.Differences,
+			"supply":            result.Supply,
+		},
+	})
+}
+
+// bound returns the id of the last entry to replay, zero for the whole ledger.
+func bound(tx *gorm.DB, opts Options) (uint64, error) {
+	if opts.AsOf == nil {
+		return opts.LedgerID, nil
+	}
+
+	var last sql.NullInt64
+	err := tx.Model(&db.LedgerEntry{}).
+		Where("created_at <= ?", *opts.AsOf).
+		Select("MAX(id)").
+		Scan(&last).Error
+	if err != nil {
+		return 0, erebuild.RebuildError
+	}
+	if !last.Valid {
+		return 0, erebuild.NoEntriesBeforeError{AsOf: opts.AsOf.Format(time.RFC3339)}
+	}
+	return uint64(last.Int64), nil
+}
+
+// replay verifies the chain up to the entry to and replays it.
+func replay(tx *gorm.DB, to uint64) (*ledger.Balances, error) {
+	verification, err := ledger.Verify(tx, 0, to)
+	if err != nil {
+		return nil, err
+	}
+	if !verification.Valid {
+		return nil, erebuild.BrokenLedgerError{BrokenAt: verification.BrokenAt, Reason: verification.Reason}
+	}
+
+	balances := ledger.NewBalances()
+	err = ledger.Replay(tx, balances, to)
+	if err != nil {
+		return nil, err
+	}
+
+	for addr, amount := range balances.Amounts {
+		if amount.LessThan(decimal.Zero) {
+			return nil, erebuild.NegativeBalanceError{Address: addr, Amount: amount}
+		}
+	}
+	return balances, nil
+}
+
+// write creates table with the layout of the accounts table and inserts the balances
+// in address order. It fails if the table already exists.
+func write(tx *gorm.DB, table string, balances *ledger.Balances) error {
+	if tx.Migrator().HasTable(table) {
+		return erebuild.TableExistsError{Table: table}
+	}
+
+	var err error
+	if db.IsSQLite(tx) {
+		// SQLite has no CREATE TABLE ... LIKE, accounts has no indexes besides its primary key
+		err = tx.Table(table).Migrator().CreateTable(&db.Account{})
+	} else {
+		err = tx.Exec(fmt.Sprintf("CREATE TABLE %s (LIKE accounts INCLUDING ALL)", table)).Error
+	}
+	if err != nil {
+		return erebuild.RebuildError
+	}
+
+	addresses := make([]address.Address, 0, len(balances.Amounts))
+	for addr := range balances.Amounts {
+		addresses = append(addresses, addr)
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return addresses[i].Hex() < addresses[j].Hex()
+	})
+
+	accounts := make([]db.Account, 0, len(addresses))
+	for _, addr := range addresses {
+		accounts = append(accounts, db.Account{Address: addr, Amount: balances.Get(addr)})
+	}
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	err = tx.Table(table).CreateInBatches(accounts, insertBatchSize).Error
+	if err != nil {
+		return erebuild.RebuildError
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+// Package reconcile checks the stored account balances against the ledger.
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/ereconcile"
+	"token-transfer-api/internal/ledger"
+	"token-transfer-api/internal/metrics"
+
+	"gorm.io/gorm"
+)
+
+// accountBatchSize is the number of accounts loaded at once.
+const accountBatchSize = 1000
+
+// Discrepancy is an account whose stored balance differs from the replayed ledger.
+type Discrepancy struct {
+	Address  address.Address
+	Stored   decimal.Decimal
+	Expected decimal.Decimal
+}
+
+// Report is the outcome of a reconciliation.
+type Report struct {
+	CheckedAt time.Time
+	// LedgerEntries is the number of replayed ledger entries.
+	LedgerEntries int
+	// LastEntryID is the id of the last replayed ledger entry.
+	LastEntryID uint64
+	// Accounts is the number of checked accounts.
+	Accounts int
+	// Supply is the total amount minted minus the total amount burned according to the ledger.
+	Supply decimal.Decimal
+	// Total is the sum of the stored balances.
+	Total decimal.Decimal
+	// Discrepancies lists the accounts whose balance differs from the ledger, ordered by address.
+	Discrepancies []Discrepancy
+}
+
+// Balanced reports whether all balances match the ledger and sum up to the supply.
+func (r *Report) Balanced() bool {
+	return len(r.Discrepancies) == 0 && r.Total.Equal(r.Supply)
+}
+
+// Err returns the problems found as ereconcile.BalanceMismatchError and
+// ereconcile.SupplyMismatchError values joined with errors.Join, nil if balanced.
+func (r *Report) Err() error {
+	var errs []error
+	for _, d := range r.Discrepancies {
+		errs = append(errs, ereconcile.BalanceMismatchError{Address: d.Address, Stored: d.Stored, Expected: d.Expected})
+	}
+	if !r.Total.Equal(r.Supply) {
+		errs = append(errs, ereconcile.SupplyMismatchError{Supply: r.Supply, Total: r.Total})
+	}
+	return errors.Join(errs...)
+}
+
+// Run recomputes every balance by replaying the ledger and compares it with the stored
+// balances, reading both from a single repeatable read snapshot so that concurrent
+// transfers do not show up as discrepancies. The outcome is recorded in the metrics.
+func Run(ctx context.Context, dbConnection *gorm.DB) (*Report, error) {
+	report, err := run(ctx, dbConnection)
+	if err != nil {
+		metrics.ReconciliationRuns.WithLabelValues("error").Inc()
+		return nil, err
+	}
+
+	result := "balanced"
+	if !report.Balanced() {
+		result = "discrepancy"
+	}
+	metrics.ReconciliationRuns.WithLabelValues(result).Inc()
+	metrics.ReconciliationDiscrepancies.Set(float64(len(report.Discrepancies)))
+	metrics.ReconciliationSupplyDifference.Set(report.Total.Sub(report.Supply).Float64())
+	metrics.ReconciliationLastRun.Set(float64(report.CheckedAt.Unix()))
+
+	return report, nil
+}
+
+func run(ctx context.Context, dbConnection *gorm.DB) (*Report, error) {
+	report := &Report{CheckedAt: time.Now().UTC(), Total: decimal.Zero}
+	balances := ledger.NewBalances()
+
+	err := dbConnection.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := ledger.Replay(tx, balances, 0)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[address.Address]bool, len(balances.Amounts))
+		var accounts []db.Account
+		err = db.Balances(tx).FindInBatches(&accounts, accountBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, account := range accounts {
+				seen[account.Address] = true
+				report.Accounts++
+				report.Total = report.Total.Add(account.Amount)
+
+				expected := balances.Get(account.Address)
+				if !account.Amount.Equal(expected) {
+					report.Discrepancies = append(report.Discrepancies, Discrepancy{
+						Address:  account.Address,
+						Stored:   account.Amount,
+						Expected: expected,
+					})
+				}
+			}
+			return nil
+		}).Error
+		if err != nil {
+			return ereconcile.ReconciliationError
+		}
+
+		// addresses credited by the ledger without an account
+		for addr, expected := range balances.Amounts {
+			if !seen[addr] && !expected.IsZero() {
+				report.Discrepancies = append(report.Discrepancies, Discrepancy{
+					Address:  addr,
+					Stored:   decimal.Zero,
+					Expected: expected,
+				})
+			}
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(report.Discrepancies, func(i, j int) bool {
+		return report.Discrepancies[i].Address.Hex() < report.Discrepancies[j].Address.Hex()
+	})
+	report.LedgerEntries = balances.Entries
+	report.LastEntryID = balances.LastEntryID
+	report.Supply = balances.Supply
+	return report, nil
+}
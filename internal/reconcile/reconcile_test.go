@@ -0,0 +1,52 @@
+package reconcile
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/ereconcile"
+)
+
+func testReport(supply, total int64, discrepancies ...Discrepancy) *Report {
+	return &Report{
+		Supply:        decimal.NewFromInt64(supply),
+		Total:         decimal.NewFromInt64(total),
+		Discrepancies: discrepancies,
+	}
+}
+
+func TestReport_Balanced(t *testing.T) {
+	report := testReport(1000, 1000)
+
+	assert.True(t, report.Balanced())
+	assert.NoError(t, report.Err())
+}
+
+func TestReport_SupplyMismatch(t *testing.T) {
+	report := testReport(1000, 1500)
+
+	var supplyErr ereconcile.SupplyMismatchError
+	assert.False(t, report.Balanced())
+	assert.True(t, errors.As(report.Err(), &supplyErr))
+	assert.True(t, supplyErr.Total.Equal(decimal.NewFromInt64(1500)))
+}
+
+func TestReport_BalanceMismatch(t *testing.T) {
+	// balances can be swapped between accounts without changing the total
+	a := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	b := address.HexToAddress("0xabcdef1234567890abcdef1234567890abcdef12")
+	report := testReport(1000, 1000,
+		Discrepancy{Address: a, Stored: decimal.NewFromInt64(10), Expected: decimal.NewFromInt64(20)},
+		Discrepancy{Address: b, Stored: decimal.NewFromInt64(20), Expected: decimal.NewFromInt64(10)},
+	)
+
+	var balanceErr ereconcile.BalanceMismatchError
+	assert.False(t, report.Balanced())
+	assert.True(t, errors.As(report.Err(), &balanceErr))
+	assert.Equal(t, a, balanceErr.Address)
+
+	var supplyErr ereconcile.SupplyMismatchError
+	assert.False(t, errors.As(report.Err(), &supplyErr))
+}
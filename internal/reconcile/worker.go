@@ -0,0 +1,43 @@
+package reconcile
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Worker periodically reconciles the balances and logs the discrepancies found.
+// The outcome of every run is also exported as metrics, see Run.
+type Worker struct {
+	Db       *gorm.DB
+	Interval time.Duration
+}
+
+// Run reconciles every Interval until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := Run(ctx, w.Db)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("reconcile: %v", err)
+			}
+			continue
+		}
+
+		err = report.Err()
+		if err != nil {
+			log.Printf("reconcile: balances do not match the ledger up to entry %d:\n%v", report.LastEntryID, err)
+		}
+	}
+}
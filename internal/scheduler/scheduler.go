@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/escheduler"
+	"token-transfer-api/internal/ledger"
+
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// parser accepts standard 5-field cron expressions as well as
+// descriptors such as "@daily" or "@every 1h30m".
+var parser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseRecurrence parses a cron-like recurrence expression.
+func ParseRecurrence(recurrence string) (cron.Schedule, error) {
+	schedule, err := parser.Parse(recurrence)
+	if err != nil {
+		return nil, escheduler.InvalidRecurrenceError{Recurrence: recurrence, Err: err}
+	}
+	return schedule, nil
+}
+
+// Schedule persists a new scheduled transfer. The first run happens at executeAt,
+// recurring transfers are then repeated according to recurrence. An executeAt in
+// the past is due immediately, the worker runs it at its next poll.
+// An empty recurrence schedules a one-off transfer. The transfer is recorded in the audit log.
+func Schedule(
+	ctx context.Context,
+	tx *gorm.DB,
+	from, to address.Address,
+	amount decimal.Decimal,
+	executeAt time.Time,
+	recurrence string,
+) (*db.ScheduledTransfer, error) {
+	err := ledger.ValidateAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if recurrence != "" {
+		_, err = ParseRecurrence(recurrence)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scheduled := db.ScheduledTransfer{
+		FromAddress: from,
+		ToAddress:   to,
+		Amount:      amount,
+		ExecuteAt:   executeAt.UTC(),
+		Recurrence:  recurrence,
+		NextRunAt:   executeAt.UTC(),
+		Status:      db.ScheduledTransferActive,
+	}
+	err = tx.Create(&scheduled).Error
+	if err != nil {
+		return nil, escheduler.ScheduleCreationError
+	}
+
+	err = audit.Record(ctx, tx, audit.Event{
+		Operation: "scheduleTransfer",
+		Subject:   strconv.FormatUint(scheduled.ID, 10),
+		Details: map[string]any{
+			"from":       from.Hex(),
+			"to":         to.Hex(),
+			"amount":     amount,
+			"execute_at": scheduled.ExecuteAt,
+			"recurrence": recurrence,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &scheduled, nil
+}
+
+// Cancel stops an active scheduled transfer from running again.
+// The cancellation is recorded in the audit log.
+func Cancel(ctx context.Context, tx *gorm.DB, id uint64) (*db.ScheduledTransfer, error) {
+	scheduled := db.ScheduledTransfer{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		Take(&scheduled).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, escheduler.ScheduleNotFoundError{ID: id}
+		}
+		return nil, escheduler.ScheduleRetrievalError
+	}
+
+	if scheduled.Status != db.ScheduledTransferActive {
+		return nil, escheduler.ScheduleNotActiveError{ID: id}
+	}
+
+	scheduled.Status = db.ScheduledTransferCancelled
+	err = tx.Model(&scheduled).Update("status", scheduled.Status).Error
+	if err != nil {
+		return nil, escheduler.ScheduleUpdateError
+	}
+
+	err = audit.Record(ctx, tx, audit.Event{Operation: "cancelScheduledTransfer", Subject: strconv.FormatUint(id, 10)})
+	if err != nil {
+		return nil, err
+	}
+
+	return &scheduled, nil
+}
+
+// Get returns the scheduled transfer with the given id together with its runs.
+func Get(tx *gorm.DB, id uint64) (*db.ScheduledTransfer, error) {
+	scheduled := db.ScheduledTransfer{}
+	err := tx.Preload("Runs", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("id")
+	}).Where("id = ?", id).Take(&scheduled).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, escheduler.ScheduleNotFoundError{ID: id}
+		}
+		return nil, escheduler.ScheduleRetrievalError
+	}
+	return &scheduled, nil
+}
+
+// ListBySender returns all transfers scheduled from the given address together with their runs.
+func ListBySender(tx *gorm.DB, from address.Address) ([]db.ScheduledTransfer, error) {
+	var scheduled []db.ScheduledTransfer
+	err := tx.Preload("Runs", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("id")
+	}).Where("from_address = ?", from.Hex()).Order("id").Find(&scheduled).Error
+	if err != nil {
+		return nil, escheduler.ScheduleRetrievalError
+	}
+	return scheduled, nil
+}
@@ -0,0 +1,179 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/errors/eledger"
+	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/ledger"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Worker periodically executes due scheduled transfers.
+// The transfers are audit-logged with the Actor "scheduler".
+//
+// Due transfers are claimed with SELECT ... FOR UPDATE SKIP LOCKED, so several
+// replicas can run a Worker against the same database without executing
+// the same transfer twice.
+type Worker struct {
+	Db           *gorm.DB
+	Ledger       *ledger.Ledger
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// Actor is the audit log actor of executed scheduled transfers.
+const Actor = "scheduler"
+
+// Run polls for due transfers until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := w.RunDue(ctx)
+		if err != nil {
+			log.Printf("scheduler: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue executes up to BatchSize due transfers and returns how many were executed.
+// Transfers deferred by a transient failure are not counted and not claimed again
+// until the next poll.
+func (w *Worker) RunDue(ctx context.Context) (int, error) {
+	ctx = audit.WithOperation(audit.WithSystemActor(ctx, Actor), "scheduledTransfer")
+
+	executed := 0
+	var deferred []uint64
+	for executed < w.BatchSize {
+		if ctx.Err() != nil {
+			return executed, nil
+		}
+
+		id, ok, err := w.runNext(ctx, time.Now().UTC(), deferred)
+		if err != nil {
+			return executed, err
+		}
+		if id == 0 {
+			break
+		}
+		if !ok {
+			deferred = append(deferred, id)
+			continue
+		}
+		executed++
+	}
+	return executed, nil
+}
+
+// runNext claims a single due transfer, skipping the deferred ones, executes it and
+// records the outcome. It returns the id of the claimed transfer, zero if there was
+// nothing to run, and false if the transfer failed transiently and was deferred.
+func (w *Worker) runNext(ctx context.Context, now time.Time, deferred []uint64) (uint64, bool, error) {
+	tx, err := db.Begin(ctx, w.Db, w.Ledger.Timeouts)
+	if err != nil {
+		return 0, false, err
+	}
+
+	scheduled := db.ScheduledTransfer{}
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_run_at <= ?", string(db.ScheduledTransferActive), now)
+	if len(deferred) > 0 {
+		query = query.Where("id NOT IN ?", deferred)
+	}
+	err = query.Order("next_run_at").Take(&scheduled).Error
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	run := db.ScheduledTransferRun{
+		ScheduledTransferID: scheduled.ID,
+		ScheduledFor:        scheduled.NextRunAt,
+		ExecutedAt:          now,
+	}
+
+	// the transfer runs in a savepoint so that a failed transfer
+	// does not prevent the run from being recorded
+	err = w.Ledger.RetrySavepoint(ctx, tx, "scheduledTransfer", func(tx *gorm.DB) error {
+		receipt, err := w.Ledger.TransferTx(ctx, tx, scheduled.FromAddress, scheduled.ToAddress, scheduled.Amount)
+		if err != nil {
+			return err
+		}
+		run.Balance = &receipt.Balance
+		return nil
+	})
+	if transient(err) {
+		// the transfer stays due, a later poll runs it again
+		tx.Rollback()
+		log.Printf("scheduler: transfer %d deferred: %v", scheduled.ID, err)
+		return scheduled.ID, false, nil
+	}
+	if err != nil {
+		run.Error = err.Error()
+	} else {
+		run.Succeeded = true
+	}
+
+	err = tx.Create(&run).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+
+	err = tx.Model(&scheduled).Updates(advance(scheduled, now)).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, false, err
+	}
+
+	err = tx.Commit().Error
+	if err != nil {
+		return 0, false, err
+	}
+
+	return scheduled.ID, true, nil
+}
+
+// transient reports whether a transfer failed for a reason that may pass by itself:
+// a conflict still failing after the retries, a timeout or paused transfers.
+// Such transfers are not recorded as failed runs, so a one-off transfer is not lost.
+func transient(err error) bool {
+	var conflict eledger.ConflictError
+	var timeout eledger.TimeoutError
+	return errors.As(err, &conflict) || errors.As(err, &timeout) || errors.Is(err, eresolvers.TransfersPausedError)
+}
+
+// advance returns the column updates that move a scheduled transfer past its current run.
+// Missed occurrences of recurring transfers are skipped rather than executed in a burst.
+func advance(scheduled db.ScheduledTransfer, now time.Time) map[string]interface{} {
+	if scheduled.Recurrence == "" {
+		return map[string]interface{}{"status": db.ScheduledTransferCompleted}
+	}
+
+	schedule, err := ParseRecurrence(scheduled.Recurrence)
+	if err != nil {
+		// recurrences are validated when scheduling, this can only happen
+		// if the row was modified by hand
+		log.Printf("scheduler: transfer %d: %v", scheduled.ID, err)
+		return map[string]interface{}{"status": db.ScheduledTransferCompleted}
+	}
+
+	return map[string]interface{}{"next_run_at": schedule.Next(now)}
+}
@@ -0,0 +1,182 @@
+// Package snapshot freezes the balances of all accounts for airdrops and governance
+// votes, committing to them with a Merkle root verifiable on chain.
+package snapshot
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/esnapshot"
+	"token-transfer-api/internal/merkle"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+// batchSize is the number of balances loaded or inserted at once.
+const batchSize = 1000
+
+// Proof proves that an address held an amount in a snapshot.
+type Proof struct {
+	Snapshot *db.Snapshot
+	Amount   decimal.Decimal
+	Leaf     common.Hash
+	// Hashes are the sibling hashes from the leaf to the root, as expected by MerkleProof.verify.
+	Hashes []common.Hash
+}
+
+// Create snapshots all non-zero balances and stores them with their Merkle root.
+// Balances are read in a repeatable read transaction, so the snapshot is consistent
+// while transfers continue. The snapshot is recorded in the audit log.
+func Create(ctx context.Context, dbConnection *gorm.DB) (*db.Snapshot, error) {
+	snapshot := db.Snapshot{CreatedBy: audit.Actor(ctx), Total: decimal.Zero}
+
+	err := dbConnection.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var position sql.NullInt64
+		err := tx.Model(&db.LedgerEntry{}).Select("MAX(id)").Scan(&position).Error
+		if err != nil {
+			return esnapshot.SnapshotCreationError
+		}
+		snapshot.LedgerEntryID = uint64(position.Int64)
+
+		var balances []db.SnapshotBalance
+		var accounts []db.Account
+		err = db.Balances(tx).Where("amount > 0").FindInBatches(&accounts, batchSize, func(tx *gorm.DB, batch int) error {
+			for _, account := range accounts {
+				balances = append(balances, db.SnapshotBalance{Address: account.Address, Amount: account.Amount})
+				snapshot.Total = snapshot.Total.Add(account.Amount)
+			}
+			return nil
+		}).Error
+		if err != nil {
+			return esnapshot.SnapshotCreationError
+		}
+		if len(balances) == 0 {
+			return esnapshot.EmptySnapshotError
+		}
+
+		tree, err := buildTree(balances)
+		if err != nil {
+			return err
+		}
+		snapshot.Root = tree.Root().Hex()
+		snapshot.Accounts = len(balances)
+
+		err = tx.Create(&snapshot).Error
+		if err != nil {
+			return esnapshot.SnapshotCreationError
+		}
+		for i := range balances {
+			balances[i].SnapshotID = snapshot.ID
+		}
+		err = tx.CreateInBatches(balances, batchSize).Error
+		if err != nil {
+			return esnapshot.SnapshotCreationError
+		}
+
+		return audit.Record(ctx, tx, audit.Event{
+			Operation: "createSnapshot",
+			Subject:   snapshot.Root,
+			Details: map[string]any{
+				"snapshot_id":     snapshot.ID,
+				"ledger_entry_id": snapshot.LedgerEntryID,
+				"accounts":        snapshot.Accounts,
+				"total":           snapshot.Total,
+			},
+		})
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// Get returns the snapshot with the given id.
+func Get(tx *gorm.DB, id uint64) (*db.Snapshot, error) {
+	snapshot := db.Snapshot{}
+	err := tx.Where("id = ?", id).Take(&snapshot).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, esnapshot.SnapshotNotFoundError{ID: id}
+		}
+		return nil, esnapshot.SnapshotRetrievalError
+	}
+	return &snapshot, nil
+}
+
+// MerkleProof returns the proof of the balance of addr in the snapshot,
+// or nil if the address had no balance. The tree is rebuilt from the stored
+// balances, which takes time linear in the size of the snapshot.
+func MerkleProof(tx *gorm.DB, id uint64, addr address.Address) (*Proof, error) {
+	snapshot, err := Get(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := loadBalances(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var amount *decimal.Decimal
+	for i := range balances {
+		if balances[i].Address == addr {
+			amount = &balances[i].Amount
+		}
+	}
+	if amount == nil {
+		return nil, nil
+	}
+
+	tree, err := buildTree(balances)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf, err := merkle.Leaf(addr, *amount)
+	if err != nil {
+		return nil, err
+	}
+	hashes, _ := tree.Proof(leaf)
+
+	return &Proof{Snapshot: snapshot, Amount: *amount, Leaf: leaf, Hashes: hashes}, nil
+}
+
+// loadBalances loads the balances of a snapshot in batches ordered by address.
+func loadBalances(tx *gorm.DB, id uint64) ([]db.SnapshotBalance, error) {
+	var balances []db.SnapshotBalance
+	after := ""
+	for {
+		var batch []db.SnapshotBalance
+		err := tx.Where("snapshot_id = ? AND address > ?", id, after).
+			Order("address").
+			Limit(batchSize).
+			Find(&batch).Error
+		if err != nil {
+			return nil, esnapshot.SnapshotRetrievalError
+		}
+
+		balances = append(balances, batch...)
+		if len(batch) < batchSize {
+			return balances, nil
+		}
+		after = batch[len(batch)-1].Address.Hex()
+	}
+}
+
+func buildTree(balances []db.SnapshotBalance) (*merkle.Tree, error) {
+	leaves := make([]common.Hash, 0, len(balances))
+	for _, balance := range balances {
+		leaf, err := merkle.Leaf(balance.Address, balance.Amount)
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, leaf)
+	}
+	return merkle.New(leaves)
+}
@@ -0,0 +1,280 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/estream"
+	"token-transfer-api/internal/ledger"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EscrowAddress holds the deposits of all streams until they are withdrawn or refunded.
+// It is derived from a fixed seed, so nobody holds a private key for it.
+var EscrowAddress = address.Address(common.BytesToAddress(crypto.Keccak256([]byte("token-transfer-api/stream-escrow"))))
+
+// StreamedAmount returns the amount streamed to the recipient by the given time,
+// including the already withdrawn part. Streams accrue per whole second.
+func StreamedAmount(s *db.Stream, at time.Time) decimal.Decimal {
+	end := at
+	if s.CancelledAt != nil && s.CancelledAt.Before(end) {
+		end = *s.CancelledAt
+	}
+	if end.After(s.Stop) {
+		end = s.Stop
+	}
+
+	elapsed := end.Unix() - s.Start.Unix()
+	if elapsed <= 0 {
+		return decimal.Zero
+	}
+	return s.RatePerSecond.Mul(decimal.NewFromInt64(elapsed))
+}
+
+// WithdrawableAmount returns the amount the recipient can withdraw at the given time.
+func WithdrawableAmount(s *db.Stream, at time.Time) decimal.Decimal {
+	return StreamedAmount(s, at).Sub(s.Withdrawn)
+}
+
+// RemainingAmount returns the part of the deposit that has not been streamed yet
+// at the given time, i.e. what the sender would get back if the stream was cancelled.
+func RemainingAmount(s *db.Stream, at time.Time) decimal.Decimal {
+	if s.Status != db.StreamActive {
+		return decimal.Zero
+	}
+	return s.Deposit.Sub(StreamedAmount(s, at))
+}
+
+// Create starts a stream from sender to recipient and moves the whole deposit,
+// ratePerSecond for every second between start and stop, to the stream escrow.
+// Times are truncated to whole seconds.
+func Create(
+	ctx context.Context,
+	l *ledger.Ledger,
+	sender, recipient address.Address,
+	ratePerSecond decimal.Decimal,
+	start, stop time.Time,
+) (*db.Stream, error) {
+	err := ledger.ValidateAmount(ratePerSecond)
+	if err != nil {
+		return nil, err
+	}
+	if !ratePerSecond.GreaterThan(decimal.Zero) {
+		return nil, estream.NonPositiveRateError
+	}
+	if sender == recipient {
+		return nil, estream.SameSenderAndRecipientError
+	}
+
+	start = start.UTC().Truncate(time.Second)
+	stop = stop.UTC().Truncate(time.Second)
+	if !stop.After(start) {
+		return nil, estream.InvalidTimeRangeError
+	}
+
+	s := db.Stream{
+		Sender:        sender,
+		Recipient:     recipient,
+		RatePerSecond: ratePerSecond,
+		Deposit:       ratePerSecond.Mul(decimal.NewFromInt64(stop.Unix() - start.Unix())),
+		Withdrawn:     decimal.Zero,
+		Start:         start,
+		Stop:          stop,
+		Status:        db.StreamActive,
+	}
+	err = l.RetryTx(ctx, "stream", func(tx *gorm.DB) error {
+		_, err := l.MoveTx(ctx, tx, sender, EscrowAddress, s.Deposit)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&s).Error
+		if err != nil {
+			return estream.StreamCreationError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Withdraw transfers amount of the streamed tokens from the escrow to the recipient.
+// A nil amount withdraws everything that is withdrawable at the given time. Withdrawing
+// the rest of the deposit after stop completes the stream, it can no longer be cancelled.
+func Withdraw(ctx context.Context, l *ledger.Ledger, id uint64, amount *decimal.Decimal, at time.Time) (*db.Stream, error) {
+	s := db.Stream{}
+	err := l.RetryTx(ctx, "stream", func(tx *gorm.DB) error {
+		err := lockActive(tx, id, &s)
+		if err != nil {
+			return err
+		}
+
+		withdrawable := WithdrawableAmount(&s, at)
+		toWithdraw := withdrawable
+		if amount != nil {
+			err = ledger.ValidateAmount(*amount)
+			if err != nil {
+				return err
+			}
+			if amount.GreaterThan(withdrawable) {
+				return estream.AmountExceedsWithdrawableError{Withdrawable: withdrawable}
+			}
+			toWithdraw = *amount
+		}
+		if !toWithdraw.GreaterThan(decimal.Zero) {
+			return estream.NothingToWithdrawError
+		}
+
+		_, err = l.MoveTx(ctx, tx, EscrowAddress, s.Recipient, toWithdraw)
+		if err != nil {
+			return err
+		}
+
+		s.Withdrawn = s.Withdrawn.Add(toWithdraw)
+		// a fully withdrawn stream has nothing left to stream, withdraw or refund
+		if s.Withdrawn.Equal(s.Deposit) {
+			s.Status = db.StreamCompleted
+		}
+		err = tx.Model(&s).Updates(map[string]interface{}{
+			"withdrawn": s.Withdrawn,
+			"status":    s.Status,
+		}).Error
+		if err != nil {
+			return estream.StreamUpdateError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Cancel stops the stream at the given time. The recipient receives everything
+// streamed but not withdrawn yet and the sender is refunded the rest of the deposit.
+func Cancel(ctx context.Context, l *ledger.Ledger, id uint64, at time.Time) (*db.Stream, error) {
+	s := db.Stream{}
+	err := l.RetryTx(ctx, "stream", func(tx *gorm.DB) error {
+		err := lockActive(tx, id, &s)
+		if err != nil {
+			return err
+		}
+
+		at = at.UTC().Truncate(time.Second)
+		withdrawable := WithdrawableAmount(&s, at)
+		remaining := RemainingAmount(&s, at)
+
+		err = l.LockAccounts(tx, EscrowAddress, s.Sender, s.Recipient)
+		if err != nil {
+			return err
+		}
+
+		if withdrawable.GreaterThan(decimal.Zero) {
+			_, err = l.MoveTx(ctx, tx, EscrowAddress, s.Recipient, withdrawable)
+			if err != nil {
+				return err
+			}
+		}
+		if remaining.GreaterThan(decimal.Zero) {
+			_, err = l.MoveTx(ctx, tx, EscrowAddress, s.Sender, remaining)
+			if err != nil {
+				return err
+			}
+		}
+
+		s.Withdrawn = s.Withdrawn.Add(withdrawable)
+		s.Status = db.StreamCancelled
+		s.CancelledAt = &at
+		err = tx.Model(&s).Updates(map[string]interface{}{
+			"withdrawn":    s.Withdrawn,
+			"status":       s.Status,
+			"cancelled_at": s.CancelledAt,
+		}).Error
+		if err != nil {
+			return estream.StreamUpdateError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func lockActive(tx *gorm.DB, id uint64, s *db.Stream) error {
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		Take(s).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return estream.StreamNotFoundError{ID: id}
+		}
+		return estream.StreamRetrievalError
+	}
+
+	if s.Status != db.StreamActive {
+		return estream.StreamNotActiveError{ID: id}
+	}
+	return nil
+}
+
+// Get returns the stream with the given id.
+func Get(tx *gorm.DB, id uint64) (*db.Stream, error) {
+	s := db.Stream{}
+	err := tx.Where("id = ?", id).Take(&s).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, estream.StreamNotFoundError{ID: id}
+		}
+		return nil, estream.StreamRetrievalError
+	}
+	return &s, nil
+}
+
+// ListByAddress returns all streams the address is the sender or the recipient of.
+func ListByAddress(tx *gorm.DB, addr address.Address) ([]db.Stream, error) {
+	var streams []db.Stream
+	err := tx.Where("sender = ? OR recipient = ?", addr.Hex(), addr.Hex()).Order("id").Find(&streams).Error
+	if err != nil {
+		return nil, estream.StreamRetrievalError
+	}
+	return streams, nil
+}
+
+// Balances returns the live stream balances of the address at the given time:
+// incoming is what the address can withdraw from streams it receives and
+// outgoing is what is still locked in streams it sends.
+// They are computed from the active streams on every call, so no background
+// job has to keep them up to date. Cancelled and completed streams are settled
+// and not loaded.
+func Balances(tx *gorm.DB, addr address.Address, at time.Time) (incoming, outgoing decimal.Decimal, err error) {
+	var streams []db.Stream
+	err = tx.Where("status = ? AND (sender = ? OR recipient = ?)", string(db.StreamActive), addr.Hex(), addr.Hex()).
+		Find(&streams).Error
+	if err != nil {
+		return decimal.Zero, decimal.Zero, estream.StreamRetrievalError
+	}
+
+	incoming, outgoing = decimal.Zero, decimal.Zero
+	for _, s := range streams {
+		if s.Recipient == addr {
+			incoming = incoming.Add(WithdrawableAmount(&s, at))
+		}
+		if s.Sender == addr {
+			outgoing = outgoing.Add(RemainingAmount(&s, at))
+		}
+	}
+	return incoming, outgoing, nil
+}
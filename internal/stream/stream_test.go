@@ -0,0 +1,58 @@
+package stream
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+)
+
+var testStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testStream() *db.Stream {
+	return &db.Stream{
+		RatePerSecond: decimal.NewFromInt64(3),
+		Deposit:       decimal.NewFromInt64(300),
+		Withdrawn:     decimal.Zero,
+		Start:         testStart,
+		Stop:          testStart.Add(100 * time.Second),
+		Status:        db.StreamActive,
+	}
+}
+
+func testAmounts(t *testing.T, s *db.Stream, elapsedSeconds int64, streamed, withdrawable, remaining int64) {
+	t.Helper()
+
+	at := testStart.Add(time.Duration(elapsedSeconds) * time.Second)
+	assert.True(t, StreamedAmount(s, at).Equal(decimal.NewFromInt64(streamed)), "streamed: got %s", StreamedAmount(s, at))
+	assert.True(t, WithdrawableAmount(s, at).Equal(decimal.NewFromInt64(withdrawable)), "withdrawable: got %s", WithdrawableAmount(s, at))
+	assert.True(t, RemainingAmount(s, at).Equal(decimal.NewFromInt64(remaining)), "remaining: got %s", RemainingAmount(s, at))
+}
+
+func TestStream_BeforeStart(t *testing.T) {
+	testAmounts(t, testStream(), -5, 0, 0, 300)
+}
+
+func TestStream_Accrues(t *testing.T) {
+	testAmounts(t, testStream(), 10, 30, 30, 270)
+}
+
+func TestStream_AfterWithdrawal(t *testing.T) {
+	s := testStream()
+	s.Withdrawn = decimal.NewFromInt64(20)
+	testAmounts(t, s, 10, 30, 10, 270)
+}
+
+func TestStream_AfterStop(t *testing.T) {
+	testAmounts(t, testStream(), 1000, 300, 300, 0)
+}
+
+func TestStream_Cancelled(t *testing.T) {
+	s := testStream()
+	cancelledAt := testStart.Add(40 * time.Second)
+	s.CancelledAt = &cancelledAt
+	s.Status = db.StreamCancelled
+	s.Withdrawn = decimal.NewFromInt64(120)
+	testAmounts(t, s, 80, 120, 0, 0)
+}
@@ -0,0 +1,185 @@
+package vesting
+
+import (
+	"context"
+	"errors"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/evesting"
+	"token-transfer-api/internal/ledger"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EscrowAddress holds the tokens of all vesting schedules until they are released.
+// It is derived from a fixed seed, so nobody holds a private key for it.
+var EscrowAddress = address.Address(common.BytesToAddress(crypto.Keccak256([]byte("token-transfer-api/vesting-escrow"))))
+
+// VestedAmount returns the amount of tokens of v vested at the given time.
+// Nothing is vested before the cliff, then tokens vest linearly over the duration.
+// The result is rounded down to a whole token.
+func VestedAmount(v *db.Vesting, at time.Time) decimal.Decimal {
+	elapsed := at.Unix() - v.Start.Unix()
+	if elapsed < v.CliffSeconds || elapsed <= 0 {
+		return decimal.Zero
+	}
+	if elapsed >= v.DurationSeconds {
+		return v.Total
+	}
+
+	return v.Total.
+		Mul(decimal.NewFromInt64(elapsed)).
+		QuoInt(decimal.NewFromInt64(v.DurationSeconds))
+}
+
+// ReleasableAmount returns the amount of tokens of v that are vested but not released yet.
+func ReleasableAmount(v *db.Vesting, at time.Time) decimal.Decimal {
+	return VestedAmount(v, at).Sub(v.Released)
+}
+
+// Create creates a vesting schedule for beneficiary and locks total tokens
+// of the default account in the vesting escrow.
+func Create(
+	ctx context.Context,
+	l *ledger.Ledger,
+	beneficiary address.Address,
+	total decimal.Decimal,
+	start time.Time,
+	cliffSeconds int64,
+	durationSeconds int64,
+) (*db.Vesting, error) {
+	err := ledger.ValidateAmount(total)
+	if err != nil {
+		return nil, err
+	}
+	if !total.GreaterThan(decimal.Zero) {
+		return nil, evesting.NonPositiveTotalError
+	}
+	if durationSeconds <= 0 {
+		return nil, evesting.NonPositiveDurationError
+	}
+	if cliffSeconds < 0 || cliffSeconds > durationSeconds {
+		return nil, evesting.InvalidCliffError
+	}
+
+	vesting := db.Vesting{
+		Beneficiary:     beneficiary,
+		Total:           total,
+		Released:        decimal.Zero,
+		Start:           start.UTC(),
+		CliffSeconds:    cliffSeconds,
+		DurationSeconds: durationSeconds,
+	}
+	err = l.RetryTx(ctx, "vesting", func(tx *gorm.DB) error {
+		_, err := l.MoveTx(ctx, tx, address.HexToAddress(db.DefaultAccountHex), EscrowAddress, total)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(&vesting).Error
+		if err != nil {
+			return evesting.VestingCreationError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &vesting, nil
+}
+
+// Release transfers the releasable tokens of the vesting schedule from
+// the escrow to the beneficiary and returns the updated schedule.
+func Release(ctx context.Context, l *ledger.Ledger, id uint64, at time.Time) (*db.Vesting, error) {
+	vesting := db.Vesting{}
+	err := l.RetryTx(ctx, "vesting", func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", id).
+			Take(&vesting).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return evesting.VestingNotFoundError{ID: id}
+			}
+			return evesting.VestingRetrievalError
+		}
+
+		releasable := ReleasableAmount(&vesting, at)
+		if !releasable.GreaterThan(decimal.Zero) {
+			return evesting.NothingToReleaseError
+		}
+
+		_, err = l.MoveTx(ctx, tx, EscrowAddress, vesting.Beneficiary, releasable)
+		if err != nil {
+			return err
+		}
+
+		vesting.Released = vesting.Released.Add(releasable)
+		err = tx.Model(&vesting).Update("released", vesting.Released).Error
+		if err != nil {
+			return evesting.VestingUpdateError
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &vesting, nil
+}
+
+// Get returns the vesting schedule with the given id.
+func Get(tx *gorm.DB, id uint64) (*db.Vesting, error) {
+	vesting := db.Vesting{}
+	err := tx.Where("id = ?", id).Take(&vesting).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, evesting.VestingNotFoundError{ID: id}
+		}
+		return nil, evesting.VestingRetrievalError
+	}
+	return &vesting, nil
+}
+
+// ListByBeneficiary returns all vesting schedules of the given beneficiary.
+func ListByBeneficiary(tx *gorm.DB, beneficiary address.Address) ([]db.Vesting, error) {
+	var vestings []db.Vesting
+	err := tx.Where("beneficiary = ?", beneficiary.Hex()).Order("id").Find(&vestings).Error
+	if err != nil {
+		return nil, evesting.VestingRetrievalError
+	}
+	return vestings, nil
+}
+
+// Vested returns the amount vested at the given time summed over all schedules of beneficiary.
+func Vested(tx *gorm.DB, beneficiary address.Address, at time.Time) (decimal.Decimal, error) {
+	return sum(tx, beneficiary, at, VestedAmount)
+}
+
+// Releasable returns the amount releasable at the given time summed over all schedules of beneficiary.
+func Releasable(tx *gorm.DB, beneficiary address.Address, at time.Time) (decimal.Decimal, error) {
+	return sum(tx, beneficiary, at, ReleasableAmount)
+}
+
+func sum(
+	tx *gorm.DB,
+	beneficiary address.Address,
+	at time.Time,
+	amount func(*db.Vesting, time.Time) decimal.Decimal,
+) (decimal.Decimal, error) {
+	vestings, err := ListByBeneficiary(tx, beneficiary)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	total := decimal.Zero
+	for _, v := range vestings {
+		total = total.Add(amount(&v, at))
+	}
+	return total, nil
+}
@@ -0,0 +1,76 @@
+package vesting
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+)
+
+var testStart = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testVesting(total int64) *db.Vesting {
+	return &db.Vesting{
+		Total:           decimal.NewFromInt64(total),
+		Released:        decimal.Zero,
+		Start:           testStart,
+		CliffSeconds:    100,
+		DurationSeconds: 1000,
+	}
+}
+
+func testVestedAmount(t *testing.T, v *db.Vesting, elapsedSeconds int64, expected int64) {
+	t.Helper()
+
+	at := testStart.Add(time.Duration(elapsedSeconds) * time.Second)
+	vested := VestedAmount(v, at)
+	assert.True(t, vested.Equal(decimal.NewFromInt64(expected)), "expected %d vested, got %s", expected, vested)
+}
+
+func TestVestedAmount_BeforeStart(t *testing.T) {
+	testVestedAmount(t, testVesting(1000), -10, 0)
+}
+
+func TestVestedAmount_BeforeCliff(t *testing.T) {
+	testVestedAmount(t, testVesting(1000), 99, 0)
+}
+
+func TestVestedAmount_AtCliff(t *testing.T) {
+	testVestedAmount(t, testVesting(1000), 100, 100)
+}
+
+func TestVestedAmount_Linear(t *testing.T) {
+	testVestedAmount(t, testVesting(1000), 500, 500)
+}
+
+func TestVestedAmount_RoundsDown(t *testing.T) {
+	// 7 * 333 / 1000 = 2.331
+	testVestedAmount(t, testVesting(7), 333, 2)
+}
+
+func TestVestedAmount_AfterEnd(t *testing.T) {
+	testVestedAmount(t, testVesting(1000), 5000, 1000)
+}
+
+func TestVestedAmount_LargeTotal(t *testing.T) {
+	// 2^255 would lose precision in a float64
+	total, err := decimal.NewFromString("57896044618658097711785492504343953926634992332820282019728792003956564819968")
+	assert.NoError(t, err)
+	expected, err := decimal.NewFromString("28948022309329048855892746252171976963317496166410141009864396001978282409984")
+	assert.NoError(t, err)
+
+	v := testVesting(0)
+	v.Total = total
+
+	vested := VestedAmount(v, testStart.Add(500*time.Second))
+	assert.True(t, vested.Equal(expected), "got %s", vested)
+}
+
+func TestReleasableAmount_SubtractsReleased(t *testing.T) {
+	v := testVesting(1000)
+	v.Released = decimal.NewFromInt64(300)
+
+	releasable := ReleasableAmount(v, testStart.Add(500*time.Second))
+	assert.True(t, releasable.Equal(decimal.NewFromInt64(200)), "got %s", releasable)
+}
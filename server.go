@@ -7,16 +7,28 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/config"
 	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/export"
+	"token-transfer-api/internal/genesis"
 	"token-transfer-api/internal/graph"
+	"token-transfer-api/internal/history"
+	"token-transfer-api/internal/ledger"
+	"token-transfer-api/internal/metrics"
+	"token-transfer-api/internal/reconcile"
+	"token-transfer-api/internal/scheduler"
 
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/vektah/gqlparser/v2/ast"
+	"gorm.io/gorm"
 )
 
 const defaultPort = "8080"
@@ -27,29 +39,75 @@ func main() {
 		port = defaultPort
 	}
 
-	dbConnection, err := db.ConnectDb()
+	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	err = db.CreateDefaultAccount(dbConnection)
-	if err != nil {
-		err2 := db.CloseDb(dbConnection)
-		if err2 != nil {
-			log.Print(err2)
+	seed := genesis.Default()
+	if cfg.GenesisFile != "" {
+		seed, err = genesis.Load(cfg.GenesisFile)
+		if err != nil {
+			log.Fatal(err)
 		}
-		log.Fatal(err)
 	}
-	defer func() {
-		err := db.CloseDb(dbConnection)
+
+	var dbConnection, replicas *gorm.DB
+	var transferLedger *ledger.Ledger
+	if cfg.LedgerStore == config.StoreMemory {
+		transferLedger = &ledger.Ledger{Store: ledger.NewMemoryStore()}
+		err = genesis.Seed(context.Background(), transferLedger, seed)
+		if err != nil {
+			log.Fatal(err)
+		}
+		transferLedger.Configure(cfg)
+		log.Print("ledger is kept in memory, only transfers and balances are available")
+	} else {
+		// a shutdown signal stops waiting for the database
+		connectCtx, stopConnecting := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		dbConnection, err = db.ConnectDb(connectCtx, cfg.Database)
 		if err != nil {
 			log.Fatal(err)
 		}
-	}()
+
+		err = genesis.Apply(connectCtx, dbConnection, seed)
+		if err != nil {
+			err2 := db.CloseDb(dbConnection)
+			if err2 != nil {
+				log.Print(err2)
+			}
+			log.Fatal(err)
+		}
+		defer func() {
+			err := db.CloseDb(dbConnection)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}()
+
+		replicas, err = db.ConnectReplicas(connectCtx, cfg.Database)
+		stopConnecting()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if replicas != nil {
+			defer func() {
+				err := db.CloseDb(replicas)
+				if err != nil {
+					log.Fatal(err)
+				}
+			}()
+		}
+
+		transferLedger = ledger.FromConfig(dbConnection, cfg)
+	}
 
 	srv := handler.New(
 		graph.NewExecutableSchema(
-			graph.Config{Resolvers: &graph.Resolver{Db: dbConnection}},
+			graph.Config{
+				Resolvers:  &graph.Resolver{Db: dbConnection, Replicas: replicas, Ledger: transferLedger},
+				Directives: graph.Directives(),
+			},
 		),
 	)
 
@@ -58,16 +116,62 @@ func main() {
 	srv.AddTransport(transport.POST{})
 
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.AroundOperations(graph.MarkWrites)
+	srv.AroundRootFields(graph.AuditOperation)
+	if dbConnection == nil {
+		srv.AroundRootFields(graph.RequireDb)
+	}
 
 	mux := http.NewServeMux()
 	mux.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	mux.Handle("/query", srv)
+	mux.Handle("/metrics", metrics.Handler())
+	readYourWrites := db.ReadYourWrites(cfg.ReadYourWritesWindow)
+	mux.Handle("/query", audit.Middleware(cfg.TrustProxyHeaders)(auth.Middleware(cfg.AdminTokens)(readYourWrites(srv))))
+	if dbConnection != nil {
+		mux.Handle("/export/", audit.Middleware(cfg.TrustProxyHeaders)(auth.Middleware(cfg.AdminTokens)(readYourWrites(export.Handler(dbConnection, replicas)))))
+	}
 
 	httpServer := &http.Server{
 		Addr:    ":" + port,
 		Handler: mux,
 	}
 
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+
+	// the workers need the database
+	if dbConnection != nil {
+		worker := &scheduler.Worker{
+			Db:           dbConnection,
+			Ledger:       transferLedger,
+			PollInterval: cfg.SchedulerPollInterval,
+			BatchSize:    cfg.SchedulerBatchSize,
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			worker.Run(workerCtx)
+		}()
+
+		if cfg.ReconcileInterval > 0 {
+			reconciler := &reconcile.Worker{Db: dbConnection, Interval: cfg.ReconcileInterval}
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				reconciler.Run(workerCtx)
+			}()
+		}
+
+		if cfg.CheckpointInterval > 0 {
+			checkpointer := &history.Worker{Db: dbConnection, Interval: cfg.CheckpointInterval}
+			workers.Add(1)
+			go func() {
+				defer workers.Done()
+				checkpointer.Run(workerCtx)
+			}()
+		}
+	}
+
 	serverErrors := make(chan error, 1)
 
 	go func() {
@@ -95,5 +199,8 @@ func main() {
 		log.Fatalf("server shutdown failed: %v", err)
 	}
 
+	stopWorkers()
+	workers.Wait()
+
 	log.Println("Server exiting.")
 }
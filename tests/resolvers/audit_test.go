@@ -0,0 +1,146 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/audit"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/graph/model"
+)
+
+func transferForAudit(suite *testSuite, to address.Address, amount int64) {
+	suite.T().Helper()
+	_, err := suite.mutationResolver.Transfer(suite.ctx, model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   to,
+		Amount:      decimal.NewFromInt64(amount),
+	})
+	require.NoError(suite.T(), err, transferShouldSucceed)
+}
+
+// TestAudit_TransferRecordsBalances tests that transfers are recorded with request metadata and balances.
+func (suite *testSuite) TestAudit_TransferRecordsBalances() {
+	// assemble
+	ctx := audit.WithMetadata(suite.ctx, audit.Metadata{RequestID: "request-1", ClientIP: "192.0.2.1"})
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	operation := "transfer"
+
+	// act
+	_, err := suite.mutationResolver.Transfer(ctx, model.Transfer{
+		FromAddress: defaultAddress,
+		ToAddress:   receiverAddress,
+		Amount:      decimal.NewFromInt64(10),
+	})
+	require.NoError(suite.T(), err, transferShouldSucceed)
+	events, err := suite.queryResolver.AuditEvents(suite.ctx, &model.AuditEventFilter{Operation: &operation}, nil, nil)
+
+	// assert
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), events.Edges, 1)
+	event := events.Edges[0].Node
+	assert.Equal(suite.T(), audit.Anonymous, event.Actor)
+	assert.Equal(suite.T(), "request-1", *event.RequestID)
+	assert.Equal(suite.T(), "192.0.2.1", *event.ClientIP)
+	require.Len(suite.T(), event.Balances, 2)
+	assert.Equal(suite.T(), defaultAddress, event.Balances[0].Address)
+	assert.True(suite.T(), event.Balances[0].Before.Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount)))
+	assert.True(suite.T(), event.Balances[0].After.Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount-10)))
+	assert.Equal(suite.T(), receiverAddress, event.Balances[1].Address)
+	assert.True(suite.T(), event.Balances[1].Before.Equal(decimal.Zero))
+	assert.True(suite.T(), event.Balances[1].After.Equal(decimal.NewFromInt64(10)))
+}
+
+// TestAudit_FailedTransferIsNotRecorded tests that events are rolled back with the audited change.
+func (suite *testSuite) TestAudit_FailedTransferIsNotRecorded() {
+	// act
+	_, err := suite.mutationResolver.Transfer(suite.ctx, model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   address.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Amount:      decimal.NewFromInt64(db.DefaultCurrencyAmount + 1),
+	})
+
+	// assert
+	assert.Error(suite.T(), err)
+	var count int64
+	require.NoError(suite.T(), testDB.Model(&db.AuditEvent{}).Where("operation = ?", "transfer").Count(&count).Error)
+	assert.Equal(suite.T(), int64(0), count)
+}
+
+// TestAudit_AppendOnly tests that recorded events cannot be modified or deleted.
+func (suite *testSuite) TestAudit_AppendOnly() {
+	// act
+	updateErr := testDB.Exec("UPDATE audit_events SET actor = ?", "someone else").Error
+	deleteErr := testDB.Exec("DELETE FROM audit_events").Error
+
+	// assert
+	assert.Error(suite.T(), updateErr)
+	assert.Error(suite.T(), deleteErr)
+}
+
+// TestAudit_Pagination tests paging through events with cursors.
+func (suite *testSuite) TestAudit_Pagination() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	for i := int64(1); i <= 3; i++ {
+		transferForAudit(suite, receiverAddress, i)
+	}
+	filter := &model.AuditEventFilter{Address: &receiverAddress}
+	first := int32(2)
+
+	// act
+	page1, err1 := suite.queryResolver.AuditEvents(suite.ctx, filter, &first, nil)
+	require.NoError(suite.T(), err1)
+	page2, err2 := suite.queryResolver.AuditEvents(suite.ctx, filter, &first, page1.PageInfo.EndCursor)
+
+	// assert
+	require.NoError(suite.T(), err2)
+	assert.Len(suite.T(), page1.Edges, 2)
+	assert.True(suite.T(), page1.PageInfo.HasNextPage)
+	require.Len(suite.T(), page2.Edges, 1)
+	assert.False(suite.T(), page2.PageInfo.HasNextPage)
+	assert.True(suite.T(), page2.Edges[0].Node.Balances[1].After.Equal(decimal.NewFromInt64(6)))
+}
+
+// TestAudit_PaginationIgnoresCreatedAt tests that events are paged in the order they were recorded,
+// even if a server with a lagging clock recorded a later event with an earlier created_at.
+func (suite *testSuite) TestAudit_PaginationIgnoresCreatedAt() {
+	// assemble
+	now := time.Now().UTC()
+	events := []db.AuditEvent{
+		{CreatedAt: now, Actor: testOperator, Operation: "clockSkew", Details: "{}"},
+		{CreatedAt: now.Add(-time.Hour), Actor: testOperator, Operation: "clockSkew", Details: "{}"},
+		{CreatedAt: now.Add(time.Hour), Actor: testOperator, Operation: "clockSkew", Details: "{}"},
+	}
+	for i := range events {
+		require.NoError(suite.T(), testDB.Create(&events[i]).Error, setupFailed)
+	}
+	operation := "clockSkew"
+	filter := &model.AuditEventFilter{Operation: &operation}
+	first := int32(1)
+
+	// act
+	var ids []string
+	var after *string
+	for {
+		page, err := suite.queryResolver.AuditEvents(suite.ctx, filter, &first, after)
+		require.NoError(suite.T(), err)
+		for _, edge := range page.Edges {
+			ids = append(ids, edge.Node.ID)
+		}
+		if !page.PageInfo.HasNextPage {
+			break
+		}
+		after = page.PageInfo.EndCursor
+	}
+
+	// assert
+	require.Len(suite.T(), ids, len(events))
+	for i, event := range events {
+		assert.Equal(suite.T(), strconv.FormatUint(event.ID, 10), ids[i])
+	}
+}
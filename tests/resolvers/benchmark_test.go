@@ -0,0 +1,93 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/ledger"
+)
+
+// BenchmarkTransfer compares transfers locking and reading the accounts before updating
+// them with transfers using conditional updates. Parallel transfers share the sender,
+// so the time its account is locked limits the throughput. Run it with
+//
+//	go test -run '^$' -bench Transfer ./tests/resolvers
+func BenchmarkTransfer(b *testing.B) {
+	if testDB == nil {
+		b.Skip("needs a database, DATABASE_URL not set")
+	}
+	for _, conditional := range []bool{false, true} {
+		name := "locking"
+		if conditional {
+			name = "conditional"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			clearDBState(b)
+			l := ledger.New(testDB)
+			l.ConditionalUpdates = conditional
+			sender := address.HexToAddress(db.DefaultAccountHex)
+			amount := decimal.NewFromInt64(1)
+			var receivers atomic.Int64
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				receiver := address.HexToAddress(fmt.Sprintf("0x%040d", receivers.Add(1)))
+				for pb.Next() {
+					_, err := l.Transfer(context.Background(), sender, receiver, amount)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkHotAccountPayout compares parallel payouts from a single account with the
+// account stored in one row and split over shards, see ledger.Ledger.HotAccounts.
+// Appending to the ledger still serializes the payouts while they commit, the shards
+// only remove the wait for the sender's row. SQLite serializes all writers, so the
+// difference only shows with PostgreSQL. Run it with
+//
+//	go test -run '^$' -bench HotAccountPayout ./tests/resolvers
+func BenchmarkHotAccountPayout(b *testing.B) {
+	if testDB == nil {
+		b.Skip("needs a database, DATABASE_URL not set")
+	}
+	for _, shards := range []int{0, 8} {
+		name := "unsharded"
+		if shards > 0 {
+			name = fmt.Sprintf("shards=%d", shards)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			clearDBState(b)
+			sender := address.HexToAddress(db.DefaultAccountHex)
+			l := ledger.New(testDB)
+			if shards > 0 {
+				l.HotAccounts = []address.Address{sender}
+				l.HotAccountShards = shards
+			}
+			amount := decimal.NewFromInt64(1)
+			var receivers atomic.Int64
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				receiver := address.HexToAddress(fmt.Sprintf("0x%040d", receivers.Add(1)))
+				for pb.Next() {
+					_, err := l.Transfer(context.Background(), sender, receiver, amount)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package resolvers
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eauth"
+	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/ledger"
+)
+
+const testOperator = "test-operator"
+
+func countAuditEvents(suite *testSuite, operation string) int64 {
+	suite.T().Helper()
+	var count int64
+	err := testDB.Model(&db.AuditEvent{}).Where("operation = ? AND actor = ?", operation, testOperator).Count(&count).Error
+	require.NoError(suite.T(), err)
+	return count
+}
+
+// TestControls_FreezeRejectsTransfers tests that frozen senders and receivers cannot transfer until unfrozen.
+func (suite *testSuite) TestControls_FreezeRejectsTransfers() {
+	// assemble
+	ctx := auth.WithOperator(suite.ctx, testOperator)
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	frozenAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	_, err := suite.mutationResolver.FreezeAccount(ctx, frozenAddress, "incident 42")
+	require.NoError(suite.T(), err)
+
+	input := model.Transfer{
+		FromAddress: defaultAddress,
+		ToAddress:   frozenAddress,
+		Amount:      decimal.NewFromInt64(10),
+	}
+
+	// act
+	_, frozenErr := suite.mutationResolver.Transfer(suite.ctx, input)
+	_, err = suite.mutationResolver.UnfreezeAccount(ctx, frozenAddress)
+	require.NoError(suite.T(), err)
+	_, unfrozenErr := suite.mutationResolver.Transfer(suite.ctx, input)
+
+	// assert
+	assert.Equal(suite.T(), eresolvers.AccountFrozenError{Address: frozenAddress}, frozenErr)
+	assert.NoError(suite.T(), unfrozenErr, transferShouldSucceed)
+	assert.Equal(suite.T(), int64(1), countAuditEvents(suite, "freezeAccount"))
+	assert.Equal(suite.T(), int64(1), countAuditEvents(suite, "unfreezeAccount"))
+}
+
+// TestControls_PauseRejectsTransfers tests that pausing rejects transfers but keeps serving reads.
+func (suite *testSuite) TestControls_PauseRejectsTransfers() {
+	// assemble
+	ctx := auth.WithOperator(suite.ctx, testOperator)
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+
+	_, err := suite.mutationResolver.Pause(ctx, "incident 42")
+	require.NoError(suite.T(), err)
+
+	input := model.Transfer{
+		FromAddress: defaultAddress,
+		ToAddress:   address.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Amount:      decimal.NewFromInt64(10),
+	}
+
+	// act
+	sender, pausedErr := suite.mutationResolver.Transfer(suite.ctx, input)
+	balance, balanceErr := suite.queryResolver.Balance(suite.ctx, defaultAddress)
+	state, err := suite.mutationResolver.Unpause(ctx)
+	require.NoError(suite.T(), err)
+	_, unpausedErr := suite.mutationResolver.Transfer(suite.ctx, input)
+
+	// assert
+	assert.ErrorIs(suite.T(), pausedErr, eresolvers.TransfersPausedError)
+	assert.Nil(suite.T(), sender)
+	assert.NoError(suite.T(), balanceErr)
+	assert.True(suite.T(), balance.Balance.Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount)))
+	assert.False(suite.T(), state.Paused)
+	assert.NoError(suite.T(), unpausedErr, transferShouldSucceed)
+	assert.Equal(suite.T(), int64(1), countAuditEvents(suite, "pause"))
+	assert.Equal(suite.T(), int64(1), countAuditEvents(suite, "unpause"))
+}
+
+// TestControls_RequireOperator tests that anonymous requests cannot change controls.
+func (suite *testSuite) TestControls_RequireOperator() {
+	// act
+	frozen, err := suite.mutationResolver.FreezeAccount(suite.ctx, address.HexToAddress(db.DefaultAccountHex), "no operator")
+
+	// assert
+	assert.ErrorIs(suite.T(), err, eauth.UnauthorizedError)
+	assert.Nil(suite.T(), frozen)
+}
+
+// lockHookStore calls beforeLock each time a transfer is about to lock its accounts.
+type lockHookStore struct {
+	ledger.Store
+	beforeLock func()
+}
+
+func (s lockHookStore) InTx(ctx context.Context, fn func(tx ledger.Store) error) error {
+	return s.Store.InTx(ctx, func(tx ledger.Store) error {
+		return fn(lockHookStore{Store: tx, beforeLock: s.beforeLock})
+	})
+}
+
+func (s lockHookStore) LockAccounts(ctx context.Context, addresses []address.Address, upsert ...address.Address) (map[address.Address]*db.Account, error) {
+	s.beforeLock()
+	return s.Store.LockAccounts(ctx, addresses, upsert...)
+}
+
+// TestControls_FreezeDuringTransfer tests that a transfer in flight while its sender is frozen
+// either commits before the freeze returns or is rejected.
+func (suite *testSuite) TestControls_FreezeDuringTransfer() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	freezeDone := make(chan error, 1)
+	var freezeFirst bool
+	var once sync.Once
+	hookLedger := ledger.New(testDB)
+	hookLedger.Store = lockHookStore{Store: hookLedger.Store, beforeLock: func() {
+		once.Do(func() {
+			go func() {
+				_, err := suite.mutationResolver.FreezeAccount(auth.WithOperator(suite.ctx, testOperator), defaultAddress, "incident 42")
+				freezeDone <- err
+			}()
+			// SQLite transactions hold the write lock of the database, the freeze waits for the transfer
+			select {
+			case err := <-freezeDone:
+				freezeDone <- err
+				freezeFirst = true
+			case <-time.After(200 * time.Millisecond):
+			}
+		})
+	}}
+	resolver := (&graph.Resolver{Db: testDB, Ledger: hookLedger}).Mutation()
+
+	// act
+	_, transferErr := resolver.Transfer(suite.ctx, model.Transfer{
+		FromAddress: defaultAddress,
+		ToAddress:   receiverAddress,
+		Amount:      decimal.NewFromInt64(10),
+	})
+	freezeErr := <-freezeDone
+
+	// assert
+	require.NoError(suite.T(), freezeErr)
+	if freezeFirst {
+		assert.Equal(suite.T(), eresolvers.AccountFrozenError{Address: defaultAddress}, transferErr)
+		assert.True(suite.T(), getAccountBalance(suite, receiverAddress).IsZero())
+	} else {
+		assert.NoError(suite.T(), transferErr, transferShouldSucceed)
+	}
+}
@@ -0,0 +1,75 @@
+package resolvers
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/export"
+)
+
+// TestExport_AccountsCSV tests exporting all accounts as CSV.
+func (suite *testSuite) TestExport_AccountsCSV() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	transferForHistory(suite, receiverAddress, 10)
+	var buf bytes.Buffer
+
+	// act
+	n, err := export.Accounts(testDB, &buf, export.CSV, export.Filter{})
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, n)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(suite.T(), lines, 3)
+	assert.Equal(suite.T(), "address,amount", lines[0])
+	assert.Contains(suite.T(), lines, receiverAddress.Hex()+",10")
+}
+
+// TestExport_TransfersJSONLines tests exporting the transfers of an address as JSON Lines.
+func (suite *testSuite) TestExport_TransfersJSONLines() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	otherAddress := address.HexToAddress("0xabcdef1234567890abcdef1234567890abcdef12")
+	transferForHistory(suite, receiverAddress, 10)
+	transferForHistory(suite, otherAddress, 20)
+	var buf bytes.Buffer
+
+	// act
+	n, err := export.Transfers(testDB, &buf, export.JSONLines, export.Filter{Address: &receiverAddress})
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, n)
+	var entry export.Entry
+	require.NoError(suite.T(), json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(suite.T(), uint64(2), entry.ID)
+	assert.Equal(suite.T(), string(db.LedgerEntryTransfer), entry.Kind)
+	assert.Equal(suite.T(), db.DefaultAccountHex, entry.FromAddress)
+	assert.Equal(suite.T(), receiverAddress.Hex(), entry.ToAddress)
+	assert.Equal(suite.T(), "10", entry.Amount)
+}
+
+// TestExport_TransfersTimeRange tests that only the transfers created in the range are exported.
+func (suite *testSuite) TestExport_TransfersTimeRange() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	transferForHistory(suite, receiverAddress, 10)
+	from := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	transferForHistory(suite, receiverAddress, 20)
+	var buf bytes.Buffer
+
+	// act
+	n, err := export.Transfers(testDB, &buf, export.CSV, export.Filter{From: &from})
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, n)
+	assert.Contains(suite.T(), buf.String(), ","+receiverAddress.Hex()+",20,")
+}
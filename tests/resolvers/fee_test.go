@@ -0,0 +1,101 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/efees"
+	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/fees"
+	"token-transfer-api/internal/graph"
+	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/ledger"
+)
+
+var feeCollectorAddress = address.HexToAddress("0xFEEFEEFEEFEEFEEFEEFEEFEEFEEFEEFEEFEEFEE0")
+
+func feeMutationResolver(schedule fees.Schedule) graph.MutationResolver {
+	feeLedger := ledger.New(testDB)
+	feeLedger.Fees = schedule
+	feeLedger.FeeCollector = feeCollectorAddress
+	return (&graph.Resolver{Db: testDB, Ledger: feeLedger}).Mutation()
+}
+
+// TestTransfer_Fee tests that the fee is deducted from the amount and credited to the collector.
+func (suite *testSuite) TestTransfer_Fee() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	recipientAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	initialBalance := getAccountBalance(suite, defaultAddress)
+	resolver := feeMutationResolver(fees.Percentage{BasisPoints: 100})
+
+	input := model.Transfer{
+		FromAddress: defaultAddress,
+		ToAddress:   recipientAddress,
+		Amount:      decimal.NewFromInt64(1000),
+	}
+
+	// act
+	sender, err := resolver.Transfer(suite.ctx, input)
+
+	// assert
+	require.NoError(suite.T(), err, transferShouldSucceed)
+	assert.True(suite.T(), sender.Sent.Equal(decimal.NewFromInt64(1000)))
+	assert.True(suite.T(), sender.Fee.Equal(decimal.NewFromInt64(10)))
+	assert.True(suite.T(), sender.Received.Equal(decimal.NewFromInt64(990)))
+	assert.True(suite.T(), sender.Balance.Equal(initialBalance.Sub(decimal.NewFromInt64(1000))))
+
+	assert.True(suite.T(), getAccountBalance(suite, recipientAddress).Equal(decimal.NewFromInt64(990)))
+	assert.True(suite.T(), getAccountBalance(suite, feeCollectorAddress).Equal(decimal.NewFromInt64(10)))
+}
+
+// TestTransfer_FeeExceedsAmount tests that transfers smaller than their fee are rejected.
+func (suite *testSuite) TestTransfer_FeeExceedsAmount() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	initialBalance := getAccountBalance(suite, defaultAddress)
+	resolver := feeMutationResolver(fees.Flat{Amount: decimal.NewFromInt64(10)})
+
+	input := model.Transfer{
+		FromAddress: defaultAddress,
+		ToAddress:   address.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Amount:      decimal.NewFromInt64(5),
+	}
+
+	// act
+	sender, err := resolver.Transfer(suite.ctx, input)
+
+	// assert
+	assert.Error(suite.T(), err, transferShouldFail)
+	assert.IsType(suite.T(), efees.FeeExceedsAmountError{}, err)
+	assert.Nil(suite.T(), sender)
+	assert.True(suite.T(), getAccountBalance(suite, defaultAddress).Equal(initialBalance))
+}
+
+// TestTransfer_FeeCollectorFrozen tests that transfers charging a fee are rejected while the collector is frozen.
+func (suite *testSuite) TestTransfer_FeeCollectorFrozen() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	initialBalance := getAccountBalance(suite, defaultAddress)
+	resolver := feeMutationResolver(fees.Flat{Amount: decimal.NewFromInt64(1)})
+	_, err := resolver.FreezeAccount(auth.WithOperator(suite.ctx, testOperator), feeCollectorAddress, "incident 42")
+	require.NoError(suite.T(), err)
+
+	input := model.Transfer{
+		FromAddress: defaultAddress,
+		ToAddress:   address.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Amount:      decimal.NewFromInt64(10),
+	}
+
+	// act
+	sender, err := resolver.Transfer(suite.ctx, input)
+
+	// assert
+	assert.Equal(suite.T(), eresolvers.AccountFrozenError{Address: feeCollectorAddress}, err)
+	assert.Nil(suite.T(), sender)
+	assert.True(suite.T(), getAccountBalance(suite, defaultAddress).Equal(initialBalance))
+	assert.True(suite.T(), getAccountBalance(suite, feeCollectorAddress).IsZero())
+}
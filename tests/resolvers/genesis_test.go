@@ -0,0 +1,99 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/egenesis"
+	"token-transfer-api/internal/genesis"
+)
+
+const testGenesis = `{
+	"token": {"name": "Example Token", "symbol": "EXT", "decimals": 18},
+	"allocations": [
+		{"address": "0x1234567890123456789012345678901234567890", "amount": "1000"},
+		{"address": "0xabCDEF1234567890ABcDEF1234567890aBCDeF12", "amount": "2000"}
+	]
+}`
+
+func parseTestGenesis(suite *testSuite) *genesis.Genesis {
+	suite.T().Helper()
+	g, err := genesis.Parse([]byte(testGenesis), false)
+	require.NoError(suite.T(), err, setupFailed)
+	return g
+}
+
+// TestGenesis_Apply tests seeding an empty database from a genesis file.
+func (suite *testSuite) TestGenesis_Apply() {
+	// assemble
+	truncateTables(suite.T())
+	g := parseTestGenesis(suite)
+
+	// act
+	err := genesis.Apply(suite.ctx, testDB, g)
+
+	// assert
+	require.NoError(suite.T(), err)
+	firstAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	secondAddress := address.HexToAddress("0xabCDEF1234567890ABcDEF1234567890aBCDeF12")
+	assert.True(suite.T(), getAccountBalance(suite, firstAddress).Equal(decimal.NewFromInt64(1000)))
+	assert.True(suite.T(), getAccountBalance(suite, secondAddress).Equal(decimal.NewFromInt64(2000)))
+
+	var mints int64
+	require.NoError(suite.T(), testDB.Model(&db.LedgerEntry{}).Where("kind = ?", db.LedgerEntryMint).Count(&mints).Error)
+	assert.Equal(suite.T(), int64(2), mints)
+
+	var events int64
+	require.NoError(suite.T(), testDB.Model(&db.AuditEvent{}).Where("operation = ? AND actor = ?", "genesis", "genesis").Count(&events).Error)
+	assert.Equal(suite.T(), int64(1), events)
+
+	token, err := suite.queryResolver.Token(suite.ctx)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "EXT", token.Symbol)
+	assert.Equal(suite.T(), int32(18), token.Decimals)
+	assert.Equal(suite.T(), g.Hash.Hex(), token.GenesisHash)
+}
+
+// TestGenesis_ApplyIsIdempotent tests that applying the same genesis again changes nothing.
+func (suite *testSuite) TestGenesis_ApplyIsIdempotent() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	transferForHistory(suite, address.HexToAddress("0x1234567890123456789012345678901234567890"), 10)
+
+	// act
+	err := genesis.Apply(suite.ctx, testDB, genesis.Default())
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), getAccountBalance(suite, defaultAddress).Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount-10)))
+	var entries int64
+	require.NoError(suite.T(), testDB.Model(&db.LedgerEntry{}).Count(&entries).Error)
+	assert.Equal(suite.T(), int64(2), entries)
+}
+
+// TestGenesis_Mismatch tests that a database seeded from another genesis is refused.
+func (suite *testSuite) TestGenesis_Mismatch() {
+	// assemble
+	g := parseTestGenesis(suite)
+
+	// act
+	err := genesis.Apply(suite.ctx, testDB, g)
+
+	// assert
+	assert.ErrorIs(suite.T(), err, egenesis.GenesisMismatchError{Stored: genesis.Default().Hash.Hex(), Given: g.Hash.Hex()})
+}
+
+// TestGenesis_LegacyDatabase tests that a database seeded before genesis files is assumed to use the default.
+func (suite *testSuite) TestGenesis_LegacyDatabase() {
+	// assemble
+	require.NoError(suite.T(), testDB.Exec("DELETE FROM genesis").Error, setupFailed)
+
+	// act
+	err := genesis.Apply(suite.ctx, testDB, genesis.Default())
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), getAccountBalance(suite, address.HexToAddress(db.DefaultAccountHex)).Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount)))
+}
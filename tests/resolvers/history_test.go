@@ -0,0 +1,99 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/history"
+)
+
+func transferForHistory(suite *testSuite, to address.Address, amount int64) {
+	suite.T().Helper()
+	_, err := suite.mutationResolver.Transfer(suite.ctx, model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   to,
+		Amount:      decimal.NewFromInt64(amount),
+	})
+	require.NoError(suite.T(), err, transferShouldSucceed)
+}
+
+func checkpointForHistory(suite *testSuite) {
+	suite.T().Helper()
+	_, err := history.Checkpoint(suite.ctx, testDB)
+	require.NoError(suite.T(), err, setupFailed)
+}
+
+func assertBalanceAt(suite *testSuite, addr address.Address, ledgerID string, expected int64) {
+	suite.T().Helper()
+	balance, err := suite.queryResolver.BalanceAt(suite.ctx, addr, nil, &ledgerID)
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), balance.Balance.Equal(decimal.NewFromInt64(expected)),
+		"expected %d at entry %s, got %s", expected, ledgerID, balance.Balance)
+}
+
+// TestHistory_BalanceAtLedgerID tests balances before, at and after checkpoints.
+func (suite *testSuite) TestHistory_BalanceAtLedgerID() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	transferForHistory(suite, receiverAddress, 10) // entry 2
+	checkpointForHistory(suite)
+	transferForHistory(suite, receiverAddress, 20) // entry 3
+	transferForHistory(suite, receiverAddress, 30) // entry 4
+	checkpointForHistory(suite)
+	transferForHistory(suite, receiverAddress, 40) // entry 5
+
+	// act & assert
+	assertBalanceAt(suite, receiverAddress, "1", 0)
+	assertBalanceAt(suite, receiverAddress, "2", 10)
+	assertBalanceAt(suite, receiverAddress, "3", 30)
+	assertBalanceAt(suite, receiverAddress, "4", 60)
+	assertBalanceAt(suite, receiverAddress, "5", 100)
+	assertBalanceAt(suite, address.HexToAddress(db.DefaultAccountHex), "3", db.DefaultCurrencyAmount-30)
+}
+
+// TestHistory_BalanceAtTime tests that a time refers to the last entry created before it.
+func (suite *testSuite) TestHistory_BalanceAtTime() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	transferForHistory(suite, receiverAddress, 10)
+	between := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	transferForHistory(suite, receiverAddress, 20)
+
+	// act
+	balance, err := suite.queryResolver.BalanceAt(suite.ctx, receiverAddress, &between, nil)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), balance.Balance.Equal(decimal.NewFromInt64(10)))
+	assert.Equal(suite.T(), "2", *balance.LedgerID)
+}
+
+// TestHistory_BalancesAt tests the bulk snapshot of all addresses.
+func (suite *testSuite) TestHistory_BalancesAt() {
+	// assemble
+	firstAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	secondAddress := address.HexToAddress("0xabcdef1234567890abcdef1234567890abcdef12")
+	transferForHistory(suite, firstAddress, 10)
+	checkpointForHistory(suite)
+	transferForHistory(suite, secondAddress, 20)
+	transferForHistory(suite, firstAddress, 30)
+	ledgerID := "3"
+
+	// act
+	balances, err := suite.queryResolver.BalancesAt(suite.ctx, nil, &ledgerID)
+
+	// assert
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), balances, 3)
+	assert.Equal(suite.T(), address.HexToAddress(db.DefaultAccountHex), balances[0].Address)
+	assert.True(suite.T(), balances[0].Balance.Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount-30)))
+	assert.Equal(suite.T(), firstAddress, balances[1].Address)
+	assert.True(suite.T(), balances[1].Balance.Equal(decimal.NewFromInt64(10)))
+	assert.Equal(suite.T(), secondAddress, balances[2].Address)
+	assert.True(suite.T(), balances[2].Balance.Equal(decimal.NewFromInt64(20)))
+}
@@ -0,0 +1,80 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/importer"
+	"token-transfer-api/internal/reconcile"
+)
+
+const testImport = `address,amount
+0x0000000000000000000000000000000000000000,400000
+0x1234567890123456789012345678901234567890,1000
+0xabcdef1234567890abcdef1234567890abcdef12,0
+`
+
+func parseTestImport(suite *testSuite) []importer.Row {
+	suite.T().Helper()
+	rows, err := importer.Parse(strings.NewReader(testImport))
+	require.NoError(suite.T(), err, setupFailed)
+	return rows
+}
+
+// TestImport_DryRun tests that a dry run reports the changes without applying them.
+func (suite *testSuite) TestImport_DryRun() {
+	// assemble
+	rows := parseTestImport(suite)
+
+	// act
+	result, err := importer.Run(suite.ctx, testDB, rows, importer.Options{DryRun: true})
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 3, result.Rows)
+	require.Len(suite.T(), result.Changes, 2)
+	assert.Equal(suite.T(), address.HexToAddress(db.DefaultAccountHex), result.Changes[0].Address)
+	assert.True(suite.T(), result.Changes[0].Delta().Equal(decimal.NewFromInt64(-600000)))
+	assert.True(suite.T(), result.Changes[1].Delta().Equal(decimal.NewFromInt64(1000)))
+	assert.True(suite.T(), result.Minted.Equal(decimal.NewFromInt64(1000)))
+	assert.True(suite.T(), result.Burned.Equal(decimal.NewFromInt64(600000)))
+	assert.True(suite.T(), getAccountBalance(suite, address.HexToAddress(db.DefaultAccountHex)).Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount)))
+}
+
+// TestImport_Apply tests that imported balances are applied in batches and recorded in the ledger.
+func (suite *testSuite) TestImport_Apply() {
+	// assemble
+	rows := parseTestImport(suite)
+
+	// act
+	result, err := importer.Run(suite.ctx, testDB, rows, importer.Options{BatchSize: 2})
+
+	// assert
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), result.Changes, 2)
+	assert.True(suite.T(), getAccountBalance(suite, address.HexToAddress(db.DefaultAccountHex)).Equal(decimal.NewFromInt64(400000)))
+	assert.True(suite.T(), getAccountBalance(suite, address.HexToAddress("0x1234567890123456789012345678901234567890")).Equal(decimal.NewFromInt64(1000)))
+
+	report, err := reconcile.Run(suite.ctx, testDB)
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), report.Balanced())
+	assert.True(suite.T(), report.Supply.Equal(decimal.NewFromInt64(401000)))
+}
+
+// TestImport_Idempotent tests that importing the same balances again changes nothing.
+func (suite *testSuite) TestImport_Idempotent() {
+	// assemble
+	rows := parseTestImport(suite)
+	_, err := importer.Run(suite.ctx, testDB, rows, importer.Options{})
+	require.NoError(suite.T(), err, setupFailed)
+
+	// act
+	result, err := importer.Run(suite.ctx, testDB, rows, importer.Options{})
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), result.Changes)
+}
@@ -0,0 +1,265 @@
+package resolvers
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eledger"
+	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/ledger"
+	"token-transfer-api/internal/reconcile"
+)
+
+func transferForLedger(suite *testSuite, amount int64) {
+	suite.T().Helper()
+	_, err := suite.mutationResolver.Transfer(suite.ctx, model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   address.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Amount:      decimal.NewFromInt64(amount),
+	})
+	require.NoError(suite.T(), err, transferShouldSucceed)
+}
+
+// TestLedger_TransfersAreChained tests that the mint and transfers form an intact hash chain.
+func (suite *testSuite) TestLedger_TransfersAreChained() {
+	// assemble
+	transferForLedger(suite, 10)
+	transferForLedger(suite, 20)
+
+	// act
+	verification, err := suite.queryResolver.VerifyLedger(suite.ctx, nil, nil)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), verification.Valid)
+	assert.Equal(suite.T(), 3, verification.Checked)
+	assert.Nil(suite.T(), verification.BrokenAt)
+
+	var entries []db.LedgerEntry
+	require.NoError(suite.T(), testDB.Order("id").Find(&entries).Error)
+	require.Len(suite.T(), entries, 3)
+	assert.Equal(suite.T(), db.LedgerEntryMint, entries[0].Kind)
+	assert.Equal(suite.T(), db.GenesisHash, entries[0].PrevHash)
+	assert.Equal(suite.T(), entries[1].Hash, entries[2].PrevHash)
+	assert.Equal(suite.T(), entries[2].Hash, verification.LastHash)
+}
+
+// TestLedger_DetectsEditedEntry tests that editing an entry in the database breaks the chain.
+func (suite *testSuite) TestLedger_DetectsEditedEntry() {
+	// assemble
+	transferForLedger(suite, 10)
+	transferForLedger(suite, 20)
+	transferForLedger(suite, 30)
+	err := testDB.Exec("UPDATE ledger_entries SET amount = 2000 WHERE id = 3").Error
+	require.NoError(suite.T(), err, setupFailed)
+
+	// act
+	verification, err := suite.queryResolver.VerifyLedger(suite.ctx, nil, nil)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.False(suite.T(), verification.Valid)
+	assert.Equal(suite.T(), "3", *verification.BrokenAt)
+	assert.NotNil(suite.T(), verification.Reason)
+}
+
+// TestLedger_DetectsRemovedEntry tests that removing an entry breaks the chain.
+func (suite *testSuite) TestLedger_DetectsRemovedEntry() {
+	// assemble
+	transferForLedger(suite, 10)
+	transferForLedger(suite, 20)
+	err := testDB.Exec("DELETE FROM ledger_entries WHERE id = 2").Error
+	require.NoError(suite.T(), err, setupFailed)
+	from, to := "2", "3"
+
+	// act
+	verification, err := suite.queryResolver.VerifyLedger(suite.ctx, &from, &to)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.False(suite.T(), verification.Valid)
+	assert.Equal(suite.T(), "3", *verification.BrokenAt)
+}
+
+// TestLedger_Mint tests that minting credits a new account and is recorded in the ledger and the audit log.
+func (suite *testSuite) TestLedger_Mint() {
+	// assemble
+	receiver := address.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	// act
+	balance, err := ledger.New(testDB).Mint(suite.ctx, receiver, decimal.NewFromInt64(500))
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), balance.Equal(decimal.NewFromInt64(500)))
+	assert.True(suite.T(), getAccountBalance(suite, receiver).Equal(decimal.NewFromInt64(500)))
+
+	var entries []db.LedgerEntry
+	require.NoError(suite.T(), testDB.Order("id").Find(&entries).Error)
+	require.Len(suite.T(), entries, 2)
+	assert.Equal(suite.T(), db.LedgerEntryMint, entries[1].Kind)
+	assert.Equal(suite.T(), receiver, entries[1].ToAddress)
+
+	var count int64
+	require.NoError(suite.T(), testDB.Model(&db.AuditEvent{}).Where("operation = ?", "mint").Count(&count).Error)
+	assert.Equal(suite.T(), int64(1), count)
+}
+
+// TestLedger_MintNegative tests that negative amounts cannot be minted.
+func (suite *testSuite) TestLedger_MintNegative() {
+	// act
+	_, err := ledger.New(testDB).Mint(suite.ctx, address.HexToAddress(db.DefaultAccountHex), decimal.NewFromInt64(-1))
+
+	// assert
+	assert.Error(suite.T(), err)
+	assert.True(suite.T(), getAccountBalance(suite, address.HexToAddress(db.DefaultAccountHex)).Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount)))
+}
+
+// TestLedger_ConditionalUpdates tests that concurrent transfers using conditional updates
+// move exact amounts and leave an intact ledger.
+func (suite *testSuite) TestLedger_ConditionalUpdates() {
+	// assemble
+	l := ledger.New(testDB)
+	l.ConditionalUpdates = true
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	receivers := make([]address.Address, 5)
+	for i := range receivers {
+		receivers[i] = address.HexToAddress(fmt.Sprintf("0x%040d", i+1))
+		_, err := l.Transfer(suite.ctx, defaultAddress, receivers[i], decimal.NewFromInt64(100))
+		require.NoError(suite.T(), err, setupFailed)
+	}
+
+	// act
+	var wg sync.WaitGroup
+	errs := make(chan error, len(receivers)*10)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// receivers send back, so accounts are both debited and credited concurrently
+			from, to := defaultAddress, receivers[i%len(receivers)]
+			if i%2 == 1 {
+				from, to = to, receivers[(i+1)%len(receivers)]
+			}
+			_, err := l.Transfer(suite.ctx, from, to, decimal.NewFromInt64(10))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	// assert
+	for err := range errs {
+		assert.NoError(suite.T(), err, transferShouldSucceed)
+	}
+	total := getAccountBalance(suite, defaultAddress)
+	for _, receiver := range receivers {
+		balance := getAccountBalance(suite, receiver)
+		assert.False(suite.T(), balance.LessThan(decimal.Zero), "negative balance of %s", receiver)
+		total = total.Add(balance)
+	}
+	assert.True(suite.T(), total.Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount)))
+
+	verification, err := suite.queryResolver.VerifyLedger(suite.ctx, nil, nil)
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), verification.Valid)
+}
+
+// TestLedger_HotAccount tests that concurrent payouts from a sharded hot account keep
+// its balance exact and non-negative.
+func (suite *testSuite) TestLedger_HotAccount() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	l := ledger.New(testDB)
+	l.HotAccounts = []address.Address{defaultAddress}
+	l.HotAccountShards = 4
+	_, err := l.Transfer(suite.ctx, defaultAddress, address.HexToAddress("0x0000000000000000000000000000000000000001"), decimal.NewFromInt64(db.DefaultCurrencyAmount-100))
+	require.NoError(suite.T(), err, setupFailed)
+
+	// act
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := l.Transfer(suite.ctx, defaultAddress, address.HexToAddress(fmt.Sprintf("0x%040d", i+2)), decimal.NewFromInt64(5))
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	// assert
+	failed := 0
+	for err := range errs {
+		if err != nil {
+			assert.ErrorIs(suite.T(), err, eresolvers.InsufficientBalanceError)
+			failed++
+		}
+	}
+	assert.Equal(suite.T(), 10, failed)
+
+	balance, err := l.Balance(suite.ctx, defaultAddress)
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), balance.IsZero(), "unexpected balance %s", balance)
+
+	var shards []db.AccountShard
+	require.NoError(suite.T(), testDB.Find(&shards).Error)
+	for _, shard := range shards {
+		assert.False(suite.T(), shard.Amount.LessThan(decimal.Zero), "negative shard %d", shard.Shard)
+	}
+
+	report, err := reconcile.Run(suite.ctx, testDB)
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), report.Balanced())
+}
+
+// TestLedger_LockTimeout tests that a transfer waiting too long for a lock fails with a timeout error.
+func (suite *testSuite) TestLedger_LockTimeout() {
+	if db.IsSQLite(testDB) {
+		suite.T().Skip("SQLite transactions wait for the write lock up to the busy timeout of the connection")
+	}
+
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	timeouts := db.Timeouts{Lock: 100 * time.Millisecond}
+	store := ledger.NewSQLStore(testDB)
+	store.Timeouts = timeouts
+	l := &ledger.Ledger{Db: testDB, Store: store, Timeouts: timeouts}
+
+	holder := testDB.Begin()
+	require.NoError(suite.T(), holder.Error, setupFailed)
+	defer holder.Rollback()
+	require.NoError(suite.T(), l.LockAccounts(holder, defaultAddress), setupFailed)
+
+	// act
+	_, err := l.Transfer(suite.ctx, defaultAddress, address.HexToAddress("0x1234567890123456789012345678901234567890"), decimal.NewFromInt64(10))
+
+	// assert
+	var timeout eledger.TimeoutError
+	require.ErrorAs(suite.T(), err, &timeout)
+	assert.Equal(suite.T(), db.ConflictLockTimeout, timeout.Timeout)
+}
+
+// TestLedger_CancelledContext tests that a transfer of a cancelled request is not executed.
+func (suite *testSuite) TestLedger_CancelledContext() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	ctx, cancel := context.WithCancel(suite.ctx)
+	cancel()
+
+	// act
+	_, err := ledger.New(testDB).Transfer(ctx, defaultAddress, address.HexToAddress("0x1234567890123456789012345678901234567890"), decimal.NewFromInt64(10))
+
+	// assert
+	assert.ErrorIs(suite.T(), err, context.Canceled)
+	assert.True(suite.T(), getAccountBalance(suite, defaultAddress).Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount)))
+}
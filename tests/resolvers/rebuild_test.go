@@ -0,0 +1,120 @@
+package resolvers
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/erebuild"
+	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/rebuild"
+)
+
+func transferForRebuild(suite *testSuite, to address.Address, amount int64) {
+	suite.T().Helper()
+	_, err := suite.mutationResolver.Transfer(suite.ctx, model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   to,
+		Amount:      decimal.NewFromInt64(amount),
+	})
+	require.NoError(suite.T(), err, transferShouldSucceed)
+}
+
+func dropTableAfterTest(suite *testSuite, table string) {
+	suite.T().Cleanup(func() {
+		err := testDB.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", table)).Error
+		assert.NoError(suite.T(), err)
+	})
+}
+
+func rebuiltBalance(suite *testSuite, table string, addr address.Address) decimal.Decimal {
+	suite.T().Helper()
+	account := db.Account{}
+	err := testDB.Table(table).Where("address = ?", addr.Hex()).Take(&account).Error
+	require.NoError(suite.T(), err)
+	return account.Amount
+}
+
+// TestRebuild_SideTable tests that replaying the whole ledger reproduces the accounts table.
+func (suite *testSuite) TestRebuild_SideTable() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	transferForRebuild(suite, receiverAddress, 10)
+	transferForRebuild(suite, receiverAddress, 20)
+	dropTableAfterTest(suite, "accounts_rebuild_test")
+
+	// act
+	result, err := rebuild.Run(suite.ctx, testDB, rebuild.Options{SideTable: "accounts_rebuild_test"})
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 3, result.Entries)
+	assert.Equal(suite.T(), 2, result.Accounts)
+	assert.Equal(suite.T(), int64(0), result.Differences)
+	assert.True(suite.T(), rebuiltBalance(suite, "accounts_rebuild_test", receiverAddress).Equal(decimal.NewFromInt64(30)))
+}
+
+// TestRebuild_LedgerID tests replaying the ledger up to a given entry.
+func (suite *testSuite) TestRebuild_LedgerID() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	transferForRebuild(suite, receiverAddress, 10)
+	transferForRebuild(suite, receiverAddress, 20)
+	dropTableAfterTest(suite, "accounts_rebuild_test")
+
+	// act
+	result, err := rebuild.Run(suite.ctx, testDB, rebuild.Options{LedgerID: 2, SideTable: "accounts_rebuild_test"})
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), uint64(2), result.LastEntryID)
+	assert.Equal(suite.T(), int64(2), result.Differences)
+	assert.True(suite.T(), rebuiltBalance(suite, "accounts_rebuild_test", receiverAddress).Equal(decimal.NewFromInt64(10)))
+}
+
+// TestRebuild_Swap tests that swapping in the rebuilt table repairs an edited balance.
+func (suite *testSuite) TestRebuild_Swap() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	transferForRebuild(suite, receiverAddress, 10)
+	err := testDB.Exec("UPDATE accounts SET amount = 1000 WHERE address = ?", receiverAddress.Hex()).Error
+	require.NoError(suite.T(), err, setupFailed)
+
+	// act
+	result, err := rebuild.Run(auth.WithOperator(suite.ctx, testOperator), testDB, rebuild.Options{Swap: true})
+	require.NoError(suite.T(), err)
+	dropTableAfterTest(suite, result.Replaced)
+	balance, balanceErr := suite.queryResolver.Balance(suite.ctx, receiverAddress)
+
+	// assert
+	assert.Equal(suite.T(), int64(1), result.Differences)
+	assert.NoError(suite.T(), balanceErr)
+	assert.True(suite.T(), balance.Balance.Equal(decimal.NewFromInt64(10)))
+	assert.Equal(suite.T(), int64(1), countAuditEvents(suite, "rebuildBalances"))
+}
+
+// TestRebuild_RefusesBrokenLedger tests that a tampered ledger is not replayed.
+func (suite *testSuite) TestRebuild_RefusesBrokenLedger() {
+	// assemble
+	transferForRebuild(suite, address.HexToAddress("0x1234567890123456789012345678901234567890"), 10)
+	err := testDB.Exec("UPDATE ledger_entries SET amount = 1000 WHERE id = 2").Error
+	require.NoError(suite.T(), err, setupFailed)
+
+	// act
+	_, err = rebuild.Run(suite.ctx, testDB, rebuild.Options{Swap: true})
+
+	// assert
+	assert.ErrorAs(suite.T(), err, &erebuild.BrokenLedgerError{})
+}
+
+// TestRebuild_RefusesBoundedSwap tests that a past state cannot replace the accounts table.
+func (suite *testSuite) TestRebuild_RefusesBoundedSwap() {
+	// act
+	_, err := rebuild.Run(suite.ctx, testDB, rebuild.Options{LedgerID: 1, Swap: true})
+
+	// assert
+	assert.ErrorIs(suite.T(), err, erebuild.BoundedSwapError)
+}
@@ -0,0 +1,56 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/graph/model"
+)
+
+// TestReconcile_Balanced tests that balances changed only through transfers match the ledger.
+func (suite *testSuite) TestReconcile_Balanced() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	for _, amount := range []int64{10, 20, 30} {
+		_, err := suite.mutationResolver.Transfer(suite.ctx, model.Transfer{
+			FromAddress: address.HexToAddress(db.DefaultAccountHex),
+			ToAddress:   receiverAddress,
+			Amount:      decimal.NewFromInt64(amount),
+		})
+		require.NoError(suite.T(), err, transferShouldSucceed)
+	}
+
+	// act
+	report, err := suite.queryResolver.Reconciliation(suite.ctx)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), report.Balanced)
+	assert.Empty(suite.T(), report.Discrepancies)
+	assert.Equal(suite.T(), 4, report.LedgerEntries)
+	assert.Equal(suite.T(), 2, report.Accounts)
+	assert.True(suite.T(), report.Supply.Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount)))
+	assert.True(suite.T(), report.Total.Equal(report.Supply))
+}
+
+// TestReconcile_DetectsEditedBalance tests that a balance changed outside the ledger is reported.
+func (suite *testSuite) TestReconcile_DetectsEditedBalance() {
+	// assemble
+	leakAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	err := testDB.Create(&db.Account{Address: leakAddress, Amount: decimal.NewFromInt64(500)}).Error
+	require.NoError(suite.T(), err, setupFailed)
+
+	// act
+	report, err := suite.queryResolver.Reconciliation(suite.ctx)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.False(suite.T(), report.Balanced)
+	require.Len(suite.T(), report.Discrepancies, 1)
+	assert.Equal(suite.T(), leakAddress, report.Discrepancies[0].Address)
+	assert.True(suite.T(), report.Discrepancies[0].Stored.Equal(decimal.NewFromInt64(500)))
+	assert.True(suite.T(), report.Discrepancies[0].Expected.Equal(decimal.Zero))
+	assert.True(suite.T(), report.Total.Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount+500)))
+}
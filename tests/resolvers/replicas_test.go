@@ -0,0 +1,95 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/export"
+	"token-transfer-api/internal/graph"
+)
+
+// connectStaleReplica connects to an empty SQLite database standing in for a replica
+// which has not caught up with the primary yet.
+func connectStaleReplica(suite *testSuite) *gorm.DB {
+	suite.T().Helper()
+	suite.T().Setenv("DATABASE_REPLICA_URLS", "sqlite:"+filepath.Join(suite.T().TempDir(), "replica.db"))
+	replicas, err := db.ConnectReplicas(suite.ctx, db.Options{})
+	require.NoError(suite.T(), err, setupFailed)
+	suite.T().Cleanup(func() {
+		assert.NoError(suite.T(), db.CloseDb(replicas))
+	})
+	require.NoError(suite.T(), db.Migrate(suite.ctx, replicas), setupFailed)
+	return replicas
+}
+
+// TestReplicas_QueriesReadReplicas tests that queries are served by the replicas,
+// unless the request reads its own writes.
+func (suite *testSuite) TestReplicas_QueriesReadReplicas() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	resolver := &graph.Resolver{Db: testDB, Replicas: connectStaleReplica(suite), Ledger: testResolver.Ledger}
+	transferForHistory(suite, receiverAddress, 10)
+
+	// act
+	stale, err := resolver.Query().Balance(suite.ctx, receiverAddress)
+	require.NoError(suite.T(), err)
+	fresh, err := resolver.Query().Balance(db.WithPrimary(suite.ctx), receiverAddress)
+	require.NoError(suite.T(), err)
+
+	// assert
+	assert.True(suite.T(), stale.Balance.IsZero())
+	assert.Equal(suite.T(), decimal.NewFromInt64(10), fresh.Balance)
+}
+
+// TestReplicas_VerifyLedgerReadsPrimary tests that the ledger is verified against the primary,
+// a stale replica would report a shorter chain as valid.
+func (suite *testSuite) TestReplicas_VerifyLedgerReadsPrimary() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	resolver := &graph.Resolver{Db: testDB, Replicas: connectStaleReplica(suite), Ledger: testResolver.Ledger}
+	transferForHistory(suite, receiverAddress, 10)
+
+	// act
+	verification, err := resolver.Query().VerifyLedger(suite.ctx, nil, nil)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), verification.Valid)
+	assert.Equal(suite.T(), 2, verification.Checked)
+}
+
+// TestReplicas_ExportReadYourWrites tests that the read your writes header pins an export to the primary.
+func (suite *testSuite) TestReplicas_ExportReadYourWrites() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	handler := db.ReadYourWrites(time.Second)(export.Handler(testDB, connectStaleReplica(suite)))
+	transferForHistory(suite, receiverAddress, 10)
+	exportAccounts := func(readYourWrites bool) string {
+		req := httptest.NewRequest(http.MethodGet, "/export/accounts?format=csv", nil)
+		req = req.WithContext(auth.WithOperator(suite.ctx, "operator"))
+		if readYourWrites {
+			req.Header.Set(db.ReadYourWritesHeader, "true")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(suite.T(), http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+
+	// act
+	stale := exportAccounts(false)
+	fresh := exportAccounts(true)
+
+	// assert
+	assert.NotContains(suite.T(), stale, receiverAddress.Hex())
+	assert.Contains(suite.T(), strings.Split(strings.TrimSpace(fresh), "\n"), receiverAddress.Hex()+",10")
+}
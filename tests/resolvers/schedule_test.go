@@ -0,0 +1,246 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/escheduler"
+	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/ledger"
+	"token-transfer-api/internal/scheduler"
+)
+
+const scheduleShouldSucceed = "scheduling should succeed"
+
+func newTestWorker() *scheduler.Worker {
+	return &scheduler.Worker{
+		Db:           testDB,
+		Ledger:       ledger.New(testDB),
+		PollInterval: time.Second,
+		BatchSize:    10,
+	}
+}
+
+// TestScheduleTransfer_OneOff tests that a due one-off transfer is executed once and completed.
+func (suite *testSuite) TestScheduleTransfer_OneOff() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	recipientAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	transferAmount := decimal.NewFromInt64(100)
+
+	input := model.Transfer{
+		FromAddress: defaultAddress,
+		ToAddress:   recipientAddress,
+		Amount:      transferAmount,
+	}
+	scheduled, err := suite.mutationResolver.ScheduleTransfer(suite.ctx, input, time.Now().Add(-time.Minute), nil)
+	require.NoError(suite.T(), err, scheduleShouldSucceed)
+	assert.Equal(suite.T(), model.ScheduledTransferStatusActive, scheduled.Status)
+
+	// act
+	executed, err := newTestWorker().RunDue(suite.ctx)
+	require.NoError(suite.T(), err)
+
+	// assert
+	assert.Equal(suite.T(), 1, executed)
+	assert.Equal(suite.T(), transferAmount, getAccountBalance(suite, recipientAddress))
+
+	scheduled, err = suite.queryResolver.ScheduledTransfer(suite.ctx, scheduled.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.ScheduledTransferStatusCompleted, scheduled.Status)
+	require.Len(suite.T(), scheduled.Runs, 1)
+	assert.True(suite.T(), scheduled.Runs[0].Succeeded)
+
+	executed, err = newTestWorker().RunDue(suite.ctx)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, executed)
+}
+
+// TestScheduleTransfer_Recurring tests that a recurring transfer is rescheduled after its run.
+func (suite *testSuite) TestScheduleTransfer_Recurring() {
+	// assemble
+	input := model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   address.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Amount:      decimal.NewFromInt64(10),
+	}
+	recurrence := "@every 1h"
+	scheduled, err := suite.mutationResolver.ScheduleTransfer(suite.ctx, input, time.Now().Add(-time.Minute), &recurrence)
+	require.NoError(suite.T(), err, scheduleShouldSucceed)
+
+	// act
+	executed, err := newTestWorker().RunDue(suite.ctx)
+	require.NoError(suite.T(), err)
+
+	// assert
+	assert.Equal(suite.T(), 1, executed)
+
+	scheduled, err = suite.queryResolver.ScheduledTransfer(suite.ctx, scheduled.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.ScheduledTransferStatusActive, scheduled.Status)
+	require.NotNil(suite.T(), scheduled.NextRunAt)
+	assert.True(suite.T(), scheduled.NextRunAt.After(time.Now()))
+	assert.Len(suite.T(), scheduled.Runs, 1)
+}
+
+// TestScheduleTransfer_PastExecuteAt tests that a recurring transfer scheduled several periods
+// in the past runs once immediately and does not catch up the missed runs.
+func (suite *testSuite) TestScheduleTransfer_PastExecuteAt() {
+	// assemble
+	recipientAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	input := model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   recipientAddress,
+		Amount:      decimal.NewFromInt64(10),
+	}
+	recurrence := "@every 1h"
+	scheduled, err := suite.mutationResolver.ScheduleTransfer(suite.ctx, input, time.Now().Add(-3*time.Hour), &recurrence)
+	require.NoError(suite.T(), err, scheduleShouldSucceed)
+
+	// act
+	first, err := newTestWorker().RunDue(suite.ctx)
+	require.NoError(suite.T(), err)
+	second, err := newTestWorker().RunDue(suite.ctx)
+	require.NoError(suite.T(), err)
+
+	// assert
+	assert.Equal(suite.T(), 1, first)
+	assert.Equal(suite.T(), 0, second)
+	assert.Equal(suite.T(), decimal.NewFromInt64(10), getAccountBalance(suite, recipientAddress))
+
+	scheduled, err = suite.queryResolver.ScheduledTransfer(suite.ctx, scheduled.ID)
+	require.NoError(suite.T(), err)
+	require.NotNil(suite.T(), scheduled.NextRunAt)
+	assert.True(suite.T(), scheduled.NextRunAt.After(time.Now()))
+}
+
+// TestScheduleTransfer_FailedRun tests that a failing transfer is recorded without moving funds.
+func (suite *testSuite) TestScheduleTransfer_FailedRun() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	initialBalance := getAccountBalance(suite, defaultAddress)
+
+	input := model.Transfer{
+		FromAddress: defaultAddress,
+		ToAddress:   address.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Amount:      initialBalance.Add(decimal.NewFromInt64(1)),
+	}
+	scheduled, err := suite.mutationResolver.ScheduleTransfer(suite.ctx, input, time.Now().Add(-time.Minute), nil)
+	require.NoError(suite.T(), err, scheduleShouldSucceed)
+
+	// act
+	_, err = newTestWorker().RunDue(suite.ctx)
+	require.NoError(suite.T(), err)
+
+	// assert
+	assert.True(suite.T(), getAccountBalance(suite, defaultAddress).Equal(initialBalance))
+
+	scheduled, err = suite.queryResolver.ScheduledTransfer(suite.ctx, scheduled.ID)
+	require.NoError(suite.T(), err)
+	require.Len(suite.T(), scheduled.Runs, 1)
+	assert.False(suite.T(), scheduled.Runs[0].Succeeded)
+	require.NotNil(suite.T(), scheduled.Runs[0].Error)
+}
+
+// TestScheduleTransfer_Paused tests that a one-off transfer due while transfers are paused
+// stays due and runs once they are resumed.
+func (suite *testSuite) TestScheduleTransfer_Paused() {
+	// assemble
+	ctx := auth.WithOperator(suite.ctx, testOperator)
+	recipientAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	input := model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   recipientAddress,
+		Amount:      decimal.NewFromInt64(10),
+	}
+	scheduled, err := suite.mutationResolver.ScheduleTransfer(suite.ctx, input, time.Now().Add(-time.Minute), nil)
+	require.NoError(suite.T(), err, scheduleShouldSucceed)
+
+	_, err = suite.mutationResolver.Pause(ctx, "incident 42")
+	require.NoError(suite.T(), err)
+
+	// act
+	pausedExecuted, pausedErr := newTestWorker().RunDue(suite.ctx)
+	paused, err := suite.queryResolver.ScheduledTransfer(suite.ctx, scheduled.ID)
+	require.NoError(suite.T(), err)
+
+	_, err = suite.mutationResolver.Unpause(ctx)
+	require.NoError(suite.T(), err)
+	executed, err := newTestWorker().RunDue(suite.ctx)
+	require.NoError(suite.T(), err)
+
+	// assert
+	require.NoError(suite.T(), pausedErr)
+	assert.Equal(suite.T(), 0, pausedExecuted)
+	assert.Equal(suite.T(), model.ScheduledTransferStatusActive, paused.Status)
+	assert.Empty(suite.T(), paused.Runs)
+
+	assert.Equal(suite.T(), 1, executed)
+	assert.Equal(suite.T(), decimal.NewFromInt64(10), getAccountBalance(suite, recipientAddress))
+}
+
+// TestScheduleTransfer_NotDue tests that transfers scheduled in the future are not executed.
+func (suite *testSuite) TestScheduleTransfer_NotDue() {
+	// assemble
+	input := model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   address.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Amount:      decimal.NewFromInt64(10),
+	}
+	_, err := suite.mutationResolver.ScheduleTransfer(suite.ctx, input, time.Now().Add(time.Hour), nil)
+	require.NoError(suite.T(), err, scheduleShouldSucceed)
+
+	// act
+	executed, err := newTestWorker().RunDue(suite.ctx)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, executed)
+}
+
+// TestScheduleTransfer_InvalidRecurrence tests that malformed recurrences are rejected.
+func (suite *testSuite) TestScheduleTransfer_InvalidRecurrence() {
+	// assemble
+	input := model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   address.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Amount:      decimal.NewFromInt64(10),
+	}
+	recurrence := "every tuesday"
+
+	// act
+	scheduled, err := suite.mutationResolver.ScheduleTransfer(suite.ctx, input, time.Now(), &recurrence)
+
+	// assert
+	assert.Error(suite.T(), err)
+	assert.IsType(suite.T(), escheduler.InvalidRecurrenceError{}, err)
+	assert.Nil(suite.T(), scheduled)
+}
+
+// TestScheduleTransfer_Cancel tests that cancelled transfers are not executed.
+func (suite *testSuite) TestScheduleTransfer_Cancel() {
+	// assemble
+	recipientAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	input := model.Transfer{
+		FromAddress: address.HexToAddress(db.DefaultAccountHex),
+		ToAddress:   recipientAddress,
+		Amount:      decimal.NewFromInt64(10),
+	}
+	scheduled, err := suite.mutationResolver.ScheduleTransfer(suite.ctx, input, time.Now().Add(-time.Minute), nil)
+	require.NoError(suite.T(), err, scheduleShouldSucceed)
+
+	// act
+	scheduled, err = suite.mutationResolver.CancelScheduledTransfer(suite.ctx, scheduled.ID)
+	require.NoError(suite.T(), err)
+	executed, err := newTestWorker().RunDue(suite.ctx)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), model.ScheduledTransferStatusCancelled, scheduled.Status)
+	assert.Equal(suite.T(), 0, executed)
+	assert.True(suite.T(), getAccountBalance(suite, recipientAddress).IsZero())
+}
@@ -0,0 +1,69 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/graph/model"
+)
+
+// TestSimulate_Transfer tests that a simulated transfer projects the balances without moving any tokens.
+func (suite *testSuite) TestSimulate_Transfer() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	transferForHistory(suite, receiverAddress, 10)
+	input := model.Transfer{FromAddress: defaultAddress, ToAddress: receiverAddress, Amount: decimal.NewFromInt64(100)}
+
+	// act
+	simulation, err := suite.queryResolver.SimulateTransfer(suite.ctx, input)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Nil(suite.T(), simulation.Error)
+	assert.Nil(suite.T(), simulation.Message)
+	assert.Equal(suite.T(), decimal.NewFromInt64(999_990), simulation.SenderBalance)
+	assert.Equal(suite.T(), decimal.NewFromInt64(999_890), simulation.ProjectedSenderBalance)
+	assert.Equal(suite.T(), decimal.NewFromInt64(10), simulation.ReceiverBalance)
+	assert.Equal(suite.T(), decimal.NewFromInt64(110), simulation.ProjectedReceiverBalance)
+	balance, err := suite.queryResolver.Balance(suite.ctx, receiverAddress)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), decimal.NewFromInt64(10), balance.Balance)
+}
+
+// TestSimulate_TransferFailures tests the error codes of transfers rejected by the controls and the balance.
+func (suite *testSuite) TestSimulate_TransferFailures() {
+	// assemble
+	ctx := auth.WithOperator(suite.ctx, testOperator)
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	frozenAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	_, err := suite.mutationResolver.FreezeAccount(ctx, frozenAddress, "incident 42")
+	require.NoError(suite.T(), err)
+	simulate := func(to address.Address, amount int64) *model.TransferSimulation {
+		simulation, err := suite.queryResolver.SimulateTransfer(suite.ctx, model.Transfer{
+			FromAddress: defaultAddress,
+			ToAddress:   to,
+			Amount:      decimal.NewFromInt64(amount),
+		})
+		require.NoError(suite.T(), err)
+		require.NotNil(suite.T(), simulation.Error)
+		return simulation
+	}
+
+	// act
+	frozen := simulate(frozenAddress, 10)
+	insufficient := simulate(address.HexToAddress("0xabcdef1234567890abcdef1234567890abcdef12"), 2_000_000)
+	_, err = suite.mutationResolver.Pause(ctx, "incident 42")
+	require.NoError(suite.T(), err)
+	paused := simulate(frozenAddress, 10)
+
+	// assert
+	assert.Equal(suite.T(), model.TransferErrorAccountFrozen, *frozen.Error)
+	assert.Contains(suite.T(), *frozen.Message, frozenAddress.Hex())
+	assert.Equal(suite.T(), model.TransferErrorInsufficientBalance, *insufficient.Error)
+	assert.Equal(suite.T(), insufficient.SenderBalance, insufficient.ProjectedSenderBalance)
+	assert.Equal(suite.T(), model.TransferErrorTransfersPaused, *paused.Error)
+}
@@ -0,0 +1,94 @@
+package resolvers
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/esnapshot"
+	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/merkle"
+)
+
+// TestSnapshot_CreateSnapshot tests that a snapshot records all non-zero balances.
+func (suite *testSuite) TestSnapshot_CreateSnapshot() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	transferForHistory(suite, receiverAddress, 10)
+
+	// act
+	snapshot, err := suite.mutationResolver.CreateSnapshot(suite.ctx)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int32(2), snapshot.Accounts)
+	assert.True(suite.T(), snapshot.Total.Equal(decimal.NewFromInt64(db.DefaultCurrencyAmount)))
+	assert.Equal(suite.T(), "2", *snapshot.LedgerEntryID)
+
+	found, err := suite.queryResolver.Snapshot(suite.ctx, snapshot.ID)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), snapshot.Root, found.Root)
+}
+
+// TestSnapshot_MerkleProof tests that the proofs of all holders verify against the root.
+func (suite *testSuite) TestSnapshot_MerkleProof() {
+	// assemble
+	holders := []address.Address{
+		address.HexToAddress(db.DefaultAccountHex),
+		address.HexToAddress("0x1234567890123456789012345678901234567890"),
+		address.HexToAddress("0xabcdef1234567890abcdef1234567890abcdef12"),
+	}
+	transferForHistory(suite, holders[1], 10)
+	transferForHistory(suite, holders[2], 20)
+	snapshot, err := suite.mutationResolver.CreateSnapshot(suite.ctx)
+	require.NoError(suite.T(), err, setupFailed)
+
+	for _, holder := range holders {
+		// act
+		proof, err := suite.queryResolver.MerkleProof(suite.ctx, snapshot.ID, holder)
+
+		// assert
+		require.NoError(suite.T(), err)
+		require.NotNil(suite.T(), proof)
+		assert.Equal(suite.T(), snapshot.Root, proof.Root)
+		leaf, err := merkle.Leaf(holder, proof.Amount)
+		require.NoError(suite.T(), err)
+		assert.Equal(suite.T(), leaf.Hex(), proof.Leaf)
+		assert.True(suite.T(), merkle.Verify(common.HexToHash(proof.Root), leaf, toHashes(proof)))
+	}
+}
+
+// TestSnapshot_IgnoresLaterTransfers tests that transfers after a snapshot do not change it.
+func (suite *testSuite) TestSnapshot_IgnoresLaterTransfers() {
+	// assemble
+	receiverAddress := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	snapshot, err := suite.mutationResolver.CreateSnapshot(suite.ctx)
+	require.NoError(suite.T(), err, setupFailed)
+	transferForHistory(suite, receiverAddress, 10)
+
+	// act
+	proof, err := suite.queryResolver.MerkleProof(suite.ctx, snapshot.ID, receiverAddress)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.Nil(suite.T(), proof)
+}
+
+// TestSnapshot_NotFound tests querying a snapshot that does not exist.
+func (suite *testSuite) TestSnapshot_NotFound() {
+	// act
+	_, err := suite.queryResolver.MerkleProof(suite.ctx, "42", address.HexToAddress(db.DefaultAccountHex))
+
+	// assert
+	assert.ErrorIs(suite.T(), err, esnapshot.SnapshotNotFoundError{ID: 42})
+}
+
+func toHashes(proof *model.MerkleProof) []common.Hash {
+	hashes := make([]common.Hash, 0, len(proof.Proof))
+	for _, hash := range proof.Proof {
+		hashes = append(hashes, common.HexToHash(hash))
+	}
+	return hashes
+}
@@ -0,0 +1,117 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/estream"
+	"token-transfer-api/internal/stream"
+)
+
+// TestStream_CreateDepositsTotal tests that the whole deposit is moved to the escrow on creation.
+func (suite *testSuite) TestStream_CreateDepositsTotal() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	recipient := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	initialBalance := getAccountBalance(suite, defaultAddress)
+	start := time.Now().Add(time.Hour)
+
+	// act
+	created, err := suite.mutationResolver.CreateStream(suite.ctx, defaultAddress, recipient, decimal.NewFromInt64(2), start, start.Add(100*time.Second))
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), created.Deposit.Equal(decimal.NewFromInt64(200)))
+	assert.True(suite.T(), getAccountBalance(suite, defaultAddress).Equal(initialBalance.Sub(created.Deposit)))
+	assert.True(suite.T(), getAccountBalance(suite, stream.EscrowAddress).Equal(created.Deposit))
+
+	balance, err := suite.queryResolver.Balance(suite.ctx, defaultAddress)
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), balance.StreamingOut.Equal(created.Deposit))
+	assert.True(suite.T(), balance.Total.Equal(initialBalance))
+}
+
+// TestStream_WithdrawAndCancel tests that withdrawing and cancelling settle the whole deposit.
+func (suite *testSuite) TestStream_WithdrawAndCancel() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	recipient := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	initialBalance := getAccountBalance(suite, defaultAddress)
+	start := time.Now().Add(-100 * time.Second)
+	created, err := suite.mutationResolver.CreateStream(suite.ctx, defaultAddress, recipient, decimal.NewFromInt64(1), start, start.Add(1000*time.Second))
+	require.NoError(suite.T(), err)
+
+	// act
+	withdrawn, err := suite.mutationResolver.WithdrawFromStream(suite.ctx, created.ID, nil)
+	require.NoError(suite.T(), err)
+	cancelled, err := suite.mutationResolver.CancelStream(suite.ctx, created.ID)
+	require.NoError(suite.T(), err)
+
+	// assert
+	assert.True(suite.T(), withdrawn.Withdrawn.GreaterThanOrEqual(decimal.NewFromInt64(100)))
+	assert.Equal(suite.T(), "CANCELLED", cancelled.Status.String())
+
+	recipientBalance := getAccountBalance(suite, recipient)
+	senderBalance := getAccountBalance(suite, defaultAddress)
+	assert.True(suite.T(), recipientBalance.Equal(cancelled.Withdrawn))
+	assert.True(suite.T(), senderBalance.Add(recipientBalance).Equal(initialBalance))
+	assert.True(suite.T(), getAccountBalance(suite, stream.EscrowAddress).IsZero())
+
+	_, err = suite.mutationResolver.CancelStream(suite.ctx, created.ID)
+	assert.IsType(suite.T(), estream.StreamNotActiveError{}, err)
+}
+
+// TestStream_WithdrawTooMuch tests that the recipient cannot withdraw more than was streamed.
+func (suite *testSuite) TestStream_WithdrawTooMuch() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	recipient := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	start := time.Now().Add(time.Hour)
+	created, err := suite.mutationResolver.CreateStream(suite.ctx, defaultAddress, recipient, decimal.NewFromInt64(1), start, start.Add(100*time.Second))
+	require.NoError(suite.T(), err)
+	amount := decimal.NewFromInt64(1)
+
+	// act
+	withdrawn, err := suite.mutationResolver.WithdrawFromStream(suite.ctx, created.ID, &amount)
+
+	// assert
+	assert.IsType(suite.T(), estream.AmountExceedsWithdrawableError{}, err)
+	assert.Nil(suite.T(), withdrawn)
+	assert.True(suite.T(), getAccountBalance(suite, recipient).IsZero())
+}
+
+// TestStream_WithdrawAfterStopCompletes tests that withdrawing the whole deposit after stop
+// completes the stream, which is no longer counted by the balances or cancellable.
+func (suite *testSuite) TestStream_WithdrawAfterStopCompletes() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	recipient := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	start := time.Now().Add(-200 * time.Second)
+	created, err := suite.mutationResolver.CreateStream(suite.ctx, defaultAddress, recipient, decimal.NewFromInt64(1), start, start.Add(100*time.Second))
+	require.NoError(suite.T(), err)
+
+	// act
+	withdrawn, err := suite.mutationResolver.WithdrawFromStream(suite.ctx, created.ID, nil)
+	require.NoError(suite.T(), err)
+
+	// assert
+	assert.Equal(suite.T(), "COMPLETED", withdrawn.Status.String())
+	assert.True(suite.T(), withdrawn.Withdrawn.Equal(created.Deposit))
+	assert.True(suite.T(), getAccountBalance(suite, recipient).Equal(created.Deposit))
+
+	var active int64
+	require.NoError(suite.T(), testDB.Model(&db.Stream{}).Where("status = ?", string(db.StreamActive)).Count(&active).Error)
+	assert.Zero(suite.T(), active)
+	incoming, _, err := stream.Balances(testDB, recipient, time.Now())
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), incoming.IsZero())
+	_, outgoing, err := stream.Balances(testDB, defaultAddress, time.Now())
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), outgoing.IsZero())
+
+	_, err = suite.mutationResolver.CancelStream(suite.ctx, created.ID)
+	assert.IsType(suite.T(), estream.StreamNotActiveError{}, err)
+}
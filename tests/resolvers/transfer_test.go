@@ -10,14 +10,18 @@ import (
 	"gorm.io/gorm"
 	"log"
 	"os"
+	"path"
+	"strings"
 	"sync"
 	"testing"
 	"token-transfer-api/internal/address"
 	"token-transfer-api/internal/db"
 	"token-transfer-api/internal/decimal"
 	"token-transfer-api/internal/errors/eresolvers"
+	"token-transfer-api/internal/genesis"
 	"token-transfer-api/internal/graph"
 	"token-transfer-api/internal/graph/model"
+	"token-transfer-api/internal/ledger"
 )
 
 var (
@@ -28,32 +32,52 @@ var (
 type testSuite struct {
 	suite.Suite
 	mutationResolver graph.MutationResolver
+	queryResolver    graph.QueryResolver
 	ctx              context.Context
 }
 
 func (suite *testSuite) SetupTest() {
+	if testDB == nil && !memoryTests[path.Base(suite.T().Name())] {
+		suite.T().Skip("needs a database, DATABASE_URL not set")
+	}
 	clearDBState(suite.T())
 
 	suite.mutationResolver = testResolver.Mutation()
+	suite.queryResolver = testResolver.Query()
 	suite.ctx = context.Background()
 }
 
+// memoryTests are the tests run against the in-memory ledger if DATABASE_URL is not set,
+// the other tests need a database and are skipped.
+var memoryTests = map[string]bool{
+	"TestTransfer_SuccessfulTransfer":         true,
+	"TestTransfer_InsufficientBalance":        true,
+	"TestTransfer_NegativeAmount":             true,
+	"TestTransfer_NonInteger":                 true,
+	"TestTransfer_SelfTransfer":               true,
+	"TestTransfer_RaceCondition":              true,
+	"TestTransfer_FirstOrCreateRaceCondition": true,
+	"TestTransfer_SenderNotFound":             true,
+}
+
 func TestMain(m *testing.M) {
 	if os.Getenv("DATABASE_URL") == "" {
-		log.Fatalf("DATABASE_URL not set")
+		log.Print("DATABASE_URL not set, running the transfer tests against the in-memory ledger")
+		testResolver = &graph.Resolver{}
+		os.Exit(m.Run())
 	}
 
 	var err error
-	testDB, err = db.ConnectDb()
+	testDB, err = db.ConnectDb(context.Background(), db.Options{})
 	if err != nil {
 		log.Fatalf("Failed to connect to test database: %v", err)
 	}
 
-	testResolver = &graph.Resolver{Db: testDB}
+	testResolver = &graph.Resolver{Db: testDB, Ledger: ledger.New(testDB)}
 
-	err = db.CreateDefaultAccount(testDB)
+	err = genesis.Apply(context.Background(), testDB, genesis.Default())
 	if err != nil {
-		log.Fatalf("Failed to create default account: %v", err)
+		log.Fatalf("Failed to apply genesis: %v", err)
 	}
 
 	exitCode := m.Run()
@@ -66,24 +90,102 @@ func TestMain(m *testing.M) {
 }
 
 // clearDBState truncates all tables and recreates default data for a clean test run.
-func clearDBState(t *testing.T) {
+// Without a database it replaces the in-memory ledger with a newly seeded one.
+func clearDBState(t testing.TB) {
 	t.Helper()
-	err := testDB.Exec("TRUNCATE TABLE accounts RESTART IDENTITY CASCADE").Error
+	if testDB == nil {
+		testResolver.Ledger = &ledger.Ledger{Store: ledger.NewMemoryStore()}
+		err := genesis.Seed(context.Background(), testResolver.Ledger, genesis.Default())
+		require.NoError(t, err, setupFailed)
+		return
+	}
+
+	truncateTables(t)
+
+	err := genesis.Apply(context.Background(), testDB, genesis.Default())
 	require.NoError(t, err, setupFailed)
+}
+
+// testTables lists the tables emptied before every test.
+var testTables = []string{"accounts", "account_shards", "scheduled_transfers", "scheduled_transfer_runs", "vestings", "streams", "frozen_accounts", "pause", "audit_events", "audit_balance_changes", "ledger_entries", "balance_checkpoints", "snapshots", "snapshot_balances", "genesis"}
 
-	err = db.CreateDefaultAccount(testDB)
+// truncateTables removes all rows, leaving an unseeded database.
+func truncateTables(t testing.TB) {
+	t.Helper()
+	if !db.IsSQLite(testDB) {
+		err := testDB.Exec("TRUNCATE TABLE " + strings.Join(testTables, ", ") + " RESTART IDENTITY CASCADE").Error
+		require.NoError(t, err, setupFailed)
+		return
+	}
+
+	// SQLite has no TRUNCATE, the append-only triggers are dropped to delete the audit log
+	err := testDB.Transaction(func(tx *gorm.DB) error {
+		// foreign keys are checked on commit, once all referencing rows are gone too
+		err := tx.Exec("PRAGMA defer_foreign_keys = ON").Error
+		if err != nil {
+			return err
+		}
+		for _, table := range []string{"audit_events", "audit_balance_changes"} {
+			for _, trigger := range []string{"update", "delete"} {
+				err := tx.Exec("DROP TRIGGER IF EXISTS " + table + "_append_only_" + trigger).Error
+				if err != nil {
+					return err
+				}
+			}
+		}
+		for _, table := range testTables {
+			err := tx.Exec("DELETE FROM " + table).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Exec("DELETE FROM sqlite_sequence").Error
+	})
 	require.NoError(t, err, setupFailed)
+	require.NoError(t, db.Migrate(context.Background(), testDB), setupFailed)
 }
 
 // getAccountBalance fetches the balance of a given address.
 func getAccountBalance(suite *testSuite, addr address.Address) decimal.Decimal {
 	suite.T().Helper()
+	if testDB == nil {
+		account, err := testResolver.Ledger.Store.GetAccount(suite.ctx, addr)
+		require.NoError(suite.T(), err, fmt.Sprintf("Failed to get balance for %s", addr.Hex()))
+		if account == nil {
+			return decimal.Zero
+		}
+		return account.Amount
+	}
+
 	var account = db.Account{Address: addr}
 	err := testDB.Where("address = ?", addr).FirstOrCreate(&account).Error
 	require.NoError(suite.T(), err, fmt.Sprintf("Failed to get balance for %s", addr.Hex()))
 	return account.Amount
 }
 
+// setAccountBalance sets the balance of a given address, creating the account if missing.
+func setAccountBalance(suite *testSuite, addr address.Address, amount decimal.Decimal) {
+	suite.T().Helper()
+	store := testResolver.Ledger.Store
+	err := store.InTx(suite.ctx, func(tx ledger.Store) error {
+		accounts, err := tx.LockAccounts(suite.ctx, nil, addr)
+		if err != nil {
+			return err
+		}
+		accounts[addr].Amount = amount
+		return tx.ApplyTransfer(suite.ctx, accounts[addr])
+	})
+	require.NoError(suite.T(), err, setupFailed)
+}
+
+// accountExists reports whether the account of a given address exists.
+func accountExists(suite *testSuite, addr address.Address) bool {
+	suite.T().Helper()
+	account, err := testResolver.Ledger.Store.GetAccount(suite.ctx, addr)
+	require.NoError(suite.T(), err)
+	return account != nil
+}
+
 // TestTransfer_SuccessfulTransfer tests a basic successful transfer.
 func (suite *testSuite) TestTransfer_SuccessfulTransfer() {
 	// assemble
@@ -250,10 +352,7 @@ func (suite *testSuite) TestTransfer_RaceCondition() {
 		clearDBState(suite.T())
 
 		walletAddress := address.HexToAddress(db.DefaultAccountHex)
-		err := testDB.Model(&db.Account{}).
-			Where("address = ?", walletAddress).
-			Update("amount", decimal.NewFromInt64(10)).Error
-		require.NoError(suite.T(), err, setupFailed)
+		setAccountBalance(suite, walletAddress, decimal.NewFromInt64(10))
 
 		currentBalance := getAccountBalance(suite, walletAddress)
 		assert.Equal(suite.T(), decimal.NewFromInt64(10), currentBalance)
@@ -269,11 +368,7 @@ func (suite *testSuite) TestTransfer_RaceCondition() {
 		}
 
 		// create the 0x1111111111111111111111111111111111111111 account
-		err = testDB.FirstOrCreate(&db.Account{
-			Address: address.FromHex("0x1111111111111111111111111111111111111111"),
-			Amount:  decimal.NewFromInt64(1),
-		}).Error
-		assert.NoError(suite.T(), err, setupFailed)
+		setAccountBalance(suite, address.FromHex("0x1111111111111111111111111111111111111111"), decimal.NewFromInt64(1))
 
 		// act
 		var wg sync.WaitGroup
@@ -288,7 +383,7 @@ func (suite *testSuite) TestTransfer_RaceCondition() {
 					Amount:      decimal.NewFromInt64(amount),
 				}
 
-				_, err = suite.mutationResolver.Transfer(suite.ctx, input)
+				_, err := suite.mutationResolver.Transfer(suite.ctx, input)
 				results <- err
 			}(i, txData.amount, txData.fromAddr, txData.toAddr)
 		}
@@ -360,14 +455,9 @@ func (suite *testSuite) TestTransfer_FirstOrCreateRaceCondition() {
 		clearDBState(suite.T())
 
 		// create the 0x333 account
-		err := testDB.FirstOrCreate(&db.Account{
-			Address: address.FromHex("0x3333333333333333333333333333333333333333"),
-			Amount:  decimal.NewFromInt64(100),
-		}).Error
-		assert.NoError(suite.T(), err, setupFailed)
-
-		res := testDB.Where("address = ?", address.FromHex("0x2222222222222222222222222222222222222222")).First(&db.Account{})
-		assert.NotNil(suite.T(), res.Error)
+		setAccountBalance(suite, address.FromHex("0x3333333333333333333333333333333333333333"), decimal.NewFromInt64(100))
+
+		assert.False(suite.T(), accountExists(suite, address.FromHex("0x2222222222222222222222222222222222222222")))
 
 		var wg sync.WaitGroup
 		results := make(chan error, len(transfers))
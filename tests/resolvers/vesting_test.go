@@ -0,0 +1,103 @@
+package resolvers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"time"
+	"token-transfer-api/internal/address"
+	"token-transfer-api/internal/auth"
+	"token-transfer-api/internal/db"
+	"token-transfer-api/internal/decimal"
+	"token-transfer-api/internal/errors/eauth"
+	"token-transfer-api/internal/errors/evesting"
+	"token-transfer-api/internal/vesting"
+)
+
+// TestVesting_CreateLocksTokens tests that creating a vesting moves the total into the escrow.
+func (suite *testSuite) TestVesting_CreateLocksTokens() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	beneficiary := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	initialBalance := getAccountBalance(suite, defaultAddress)
+	total := decimal.NewFromInt64(1000)
+
+	// act
+	created, err := suite.mutationResolver.CreateVesting(auth.WithOperator(suite.ctx, testOperator), beneficiary, total, time.Now(), 3600, 7200)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), created.Vested.IsZero())
+	assert.True(suite.T(), getAccountBalance(suite, defaultAddress).Equal(initialBalance.Sub(total)))
+	assert.True(suite.T(), getAccountBalance(suite, vesting.EscrowAddress).Equal(total))
+
+	releasable, err := suite.queryResolver.Releasable(suite.ctx, beneficiary)
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), releasable.IsZero())
+}
+
+// TestVesting_ReleaseBeforeCliff tests that nothing can be released before the cliff.
+func (suite *testSuite) TestVesting_ReleaseBeforeCliff() {
+	// assemble
+	beneficiary := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	created, err := suite.mutationResolver.CreateVesting(auth.WithOperator(suite.ctx, testOperator), beneficiary, decimal.NewFromInt64(1000), time.Now(), 3600, 7200)
+	require.NoError(suite.T(), err)
+
+	// act
+	released, err := suite.mutationResolver.Release(suite.ctx, created.ID)
+
+	// assert
+	assert.ErrorIs(suite.T(), err, evesting.NothingToReleaseError)
+	assert.Nil(suite.T(), released)
+	assert.True(suite.T(), getAccountBalance(suite, beneficiary).IsZero())
+}
+
+// TestVesting_ReleaseAfterEnd tests that the whole total is released once fully vested.
+func (suite *testSuite) TestVesting_ReleaseAfterEnd() {
+	// assemble
+	beneficiary := address.HexToAddress("0x1234567890123456789012345678901234567890")
+	total := decimal.NewFromInt64(1000)
+	start := time.Now().Add(-3 * time.Hour)
+	created, err := suite.mutationResolver.CreateVesting(auth.WithOperator(suite.ctx, testOperator), beneficiary, total, start, 3600, 7200)
+	require.NoError(suite.T(), err)
+
+	// act
+	released, err := suite.mutationResolver.Release(suite.ctx, created.ID)
+
+	// assert
+	require.NoError(suite.T(), err)
+	assert.True(suite.T(), released.Released.Equal(total))
+	assert.True(suite.T(), released.Releasable.IsZero())
+	assert.True(suite.T(), getAccountBalance(suite, beneficiary).Equal(total))
+	assert.True(suite.T(), getAccountBalance(suite, vesting.EscrowAddress).IsZero())
+
+	_, err = suite.mutationResolver.Release(suite.ctx, created.ID)
+	assert.ErrorIs(suite.T(), err, evesting.NothingToReleaseError)
+}
+
+// TestVesting_InvalidCliff tests that a cliff longer than the duration is rejected.
+func (suite *testSuite) TestVesting_InvalidCliff() {
+	// assemble
+	beneficiary := address.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	// act
+	created, err := suite.mutationResolver.CreateVesting(auth.WithOperator(suite.ctx, testOperator), beneficiary, decimal.NewFromInt64(1000), time.Now(), 7201, 7200)
+
+	// assert
+	assert.ErrorIs(suite.T(), err, evesting.InvalidCliffError)
+	assert.Nil(suite.T(), created)
+}
+
+// TestVesting_RequireOperator tests that anonymous requests cannot lock treasury tokens in a vesting.
+func (suite *testSuite) TestVesting_RequireOperator() {
+	// assemble
+	defaultAddress := address.HexToAddress(db.DefaultAccountHex)
+	initialBalance := getAccountBalance(suite, defaultAddress)
+
+	// act
+	created, err := suite.mutationResolver.CreateVesting(suite.ctx, address.HexToAddress("0x1234567890123456789012345678901234567890"), decimal.NewFromInt64(1000), time.Now(), 3600, 7200)
+
+	// assert
+	assert.ErrorIs(suite.T(), err, eauth.UnauthorizedError)
+	assert.Nil(suite.T(), created)
+	assert.True(suite.T(), getAccountBalance(suite, defaultAddress).Equal(initialBalance))
+}